@@ -28,7 +28,55 @@ type Accumulator interface {
 		tags map[string]string,
 		t ...time.Time)
 
+	// AddSummary is the same as AddFields, but will add the metric as a "Summary" type
+	AddSummary(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
+	// AddHistogram is the same as AddFields, but will add the metric as a "Histogram" type
+	AddHistogram(measurement string,
+		fields map[string]interface{},
+		tags map[string]string,
+		t ...time.Time)
+
 	SetPrecision(precision, interval time.Duration)
 
 	AddError(err error)
+
+	// WithTracking upgrades the accumulator to a TrackingAccumulator, which
+	// reports when the metrics added to it have been handled by the outputs.
+	// maxTracked is the maximum number of undelivered metric groups the
+	// caller will have in flight; the caller must read Delivered() so that
+	// no more than maxTracked notifications are pending.
+	WithTracking(maxTracked int) TrackingAccumulator
+}
+
+// TrackingID uniquely identifies a tracked metric group.
+type TrackingID uint64
+
+// DeliveryInfo provides the results of a delivered metric group.
+type DeliveryInfo interface {
+	// ID is the TrackingID of the group.
+	ID() TrackingID
+
+	// Delivered returns true if the metrics of the group were written by all
+	// outputs they were routed to. It is false if any of the metrics was
+	// dropped by an output, for instance because its buffer overflowed.
+	Delivered() bool
+}
+
+// TrackingAccumulator is an Accumulator that provides a signal when the
+// metrics of a group have been fully handled: written or dropped by every
+// output, or consumed by processors and aggregators.
+type TrackingAccumulator interface {
+	Accumulator
+
+	// AddTrackingMetricGroup adds a group of metrics, as one unit of
+	// delivery, and returns its TrackingID.
+	AddTrackingMetricGroup(group []Metric) TrackingID
+
+	// Delivered returns a channel that receives the DeliveryInfo of every
+	// group, once all its metrics have been handled.
+	Delivered() <-chan DeliveryInfo
 }
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -23,6 +24,25 @@ type MetricMaker interface {
 	) telegraf.Metric
 }
 
+// errorRecorder is implemented by the makers that keep track of the latest
+// error of their plugin.
+type errorRecorder interface {
+	SetLastError(err error)
+}
+
+// router is implemented by the makers whose metrics are only sent to the
+// outputs subscribing to their routes.
+type router interface {
+	Routes() []string
+}
+
+// routedMetric carries the routes of a metric from the accumulator to the
+// flusher, which unwraps it.
+type routedMetric struct {
+	telegraf.Metric
+	routes []string
+}
+
 func NewAccumulator(
 	maker MetricMaker,
 	metrics chan telegraf.Metric,
@@ -32,6 +52,9 @@ func NewAccumulator(
 		metrics:   metrics,
 		precision: time.Nanosecond,
 	}
+	if r, ok := maker.(router); ok {
+		acc.routes = r.Routes()
+	}
 	return &acc
 }
 
@@ -41,6 +64,23 @@ type accumulator struct {
 	maker MetricMaker
 
 	precision time.Duration
+
+	routes []string
+
+	// queue, if set, is used instead of the metrics channel.
+	queue *inputQueue
+}
+
+// send sends a metric to the flusher, along with the routes of the maker.
+func (ac *accumulator) send(m telegraf.Metric) {
+	if len(ac.routes) > 0 {
+		m = &routedMetric{Metric: m, routes: ac.routes}
+	}
+	if ac.queue != nil {
+		ac.queue.put(m)
+		return
+	}
+	ac.metrics <- m
 }
 
 func (ac *accumulator) AddFields(
@@ -50,7 +90,7 @@ func (ac *accumulator) AddFields(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Untyped, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
@@ -61,7 +101,7 @@ func (ac *accumulator) AddGauge(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Gauge, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
 	}
 }
 
@@ -72,7 +112,29 @@ func (ac *accumulator) AddCounter(
 	t ...time.Time,
 ) {
 	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Counter, ac.getTime(t)); m != nil {
-		ac.metrics <- m
+		ac.send(m)
+	}
+}
+
+func (ac *accumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Summary, ac.getTime(t)); m != nil {
+		ac.send(m)
+	}
+}
+
+func (ac *accumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	t ...time.Time,
+) {
+	if m := ac.maker.MakeMetric(measurement, fields, tags, telegraf.Histogram, ac.getTime(t)); m != nil {
+		ac.send(m)
 	}
 }
 
@@ -83,6 +145,9 @@ func (ac *accumulator) AddError(err error) {
 		return
 	}
 	NErrors.Incr(1)
+	if r, ok := ac.maker.(errorRecorder); ok {
+		r.SetLastError(err)
+	}
 	//TODO suppress/throttle consecutive duplicate errors?
 	log.Printf("E! Error in plugin [%s]: %s", ac.maker.Name(), err)
 }
@@ -117,3 +182,42 @@ func (ac accumulator) getTime(t []time.Time) time.Time {
 	}
 	return timestamp.Round(ac.precision)
 }
+
+func (ac *accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &trackingAccumulator{
+		accumulator: ac,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// trackingAccumulator wraps the metrics of each group so that the input is
+// notified once the outputs have handled all of them.
+type trackingAccumulator struct {
+	*accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (ta *trackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	made := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		if m = ta.maker.MakeMetric(m.Name(), m.Fields(), m.Tags(), m.Type(), ta.getTime([]time.Time{m.Time()})); m != nil {
+			made = append(made, m)
+		}
+	}
+
+	tracked, id := metric.WithGroupTracking(made, ta.onDelivery)
+	for _, m := range tracked {
+		ta.send(m)
+	}
+	return id
+}
+
+func (ta *trackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return ta.delivered
+}
+
+// onDelivery blocks if the input has more groups in flight than the
+// maxTracked it asked for and does not read the delivered channel.
+func (ta *trackingAccumulator) onDelivery(info telegraf.DeliveryInfo) {
+	ta.delivered <- info
+}
@@ -309,6 +309,60 @@ func TestAddCounter(t *testing.T) {
 	assert.Equal(t, testm.Type(), telegraf.Counter)
 }
 
+func TestAddSummaryHistogram(t *testing.T) {
+	now := time.Now()
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&TestMetricMaker{}, metrics)
+
+	a.AddSummary("rpc_duration",
+		map[string]interface{}{"0.5": float64(0.2), "count": float64(10), "sum": float64(3)},
+		map[string]string{}, now)
+	a.AddHistogram("rpc_duration",
+		map[string]interface{}{"+Inf": float64(10), "count": float64(10), "sum": float64(3)},
+		map[string]string{}, now)
+
+	testm := <-metrics
+	assert.Equal(t, telegraf.Summary, testm.Type())
+	testm = <-metrics
+	assert.Equal(t, telegraf.Histogram, testm.Type())
+}
+
+func TestAddRoutes(t *testing.T) {
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	a := NewAccumulator(&routedMetricMaker{routes: []string{"kafka"}}, metrics)
+
+	a.AddFields("acctest",
+		map[string]interface{}{"value": float64(101)},
+		map[string]string{})
+
+	testm := <-metrics
+	rm, ok := testm.(*routedMetric)
+	require.True(t, ok)
+	assert.Equal(t, []string{"kafka"}, rm.routes)
+	assert.Contains(t, rm.String(), "acctest value=101")
+
+	// metrics of makers without routes are not wrapped
+	a = NewAccumulator(&routedMetricMaker{}, metrics)
+	a.AddFields("acctest",
+		map[string]interface{}{"value": float64(101)},
+		map[string]string{})
+
+	testm = <-metrics
+	_, ok = testm.(*routedMetric)
+	assert.False(t, ok)
+}
+
+type routedMetricMaker struct {
+	TestMetricMaker
+	routes []string
+}
+
+func (tm *routedMetricMaker) Routes() []string {
+	return tm.routes
+}
+
 type TestMetricMaker struct {
 }
 
@@ -335,6 +389,10 @@ func (tm *TestMetricMaker) MakeMetric(
 		if m, err := metric.New(measurement, tags, fields, t, telegraf.Gauge); err == nil {
 			return m
 		}
+	case telegraf.Summary, telegraf.Histogram:
+		if m, err := metric.New(measurement, tags, fields, t, mType); err == nil {
+			return m
+		}
 	}
 	return nil
 }
@@ -1,8 +1,10 @@
 package agent
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"runtime"
 	"sync"
@@ -12,73 +14,163 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/config"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/schedule"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 // Agent runs telegraf and collects data based on the given config
 type Agent struct {
 	Config *config.Config
+
+	// mu guards Config while the agent runs, since Reload replaces it.
+	mu sync.RWMutex
+
+	// runMu serializes Reload with the start and the shutdown of Run.
+	runMu   sync.Mutex
+	running bool
+
+	metricC     chan telegraf.Metric
+	shutdown    chan struct{}
+	inputs      map[*models.RunningInput]*worker
+	queues      map[*models.RunningInput]*inputQueue
+	aggregators map[*models.RunningAggregator]*worker
+	// flushSem is held while the outputs are flushed.
+	flushSem chan struct{}
+	// reloaded notifies the flusher that the flush interval may have changed.
+	reloaded chan struct{}
+	// flushNow asks the flusher for an immediate flush.
+	flushNow chan struct{}
+	// limiter limits the series of the metrics of all the inputs.
+	limiter *models.CardinalityLimiter
+}
+
+// errNotRunning is returned by the operations that need a running agent.
+var errNotRunning = errors.New("agent is not running")
+
+// worker is a goroutine running a single plugin, which can be stopped on its
+// own when the plugin is removed by a reload.
+type worker struct {
+	stop chan struct{}
+	done chan struct{}
+	// trigger asks the worker to run immediately.
+	trigger chan struct{}
+}
+
+func newWorker() *worker {
+	return &worker{
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+		trigger: make(chan struct{}, 1),
+	}
+}
+
+// Stop stops the worker and waits for it to return.
+func (w *worker) Stop() {
+	close(w.stop)
+	<-w.done
 }
 
 // NewAgent returns an Agent struct based off the given Config
 func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
-		Config: config,
+		Config:  config,
+		limiter: models.NewCardinalityLimiter(),
 	}
 
-	if !a.Config.Agent.OmitHostname {
-		if a.Config.Agent.Hostname == "" {
-			hostname, err := os.Hostname()
-			if err != nil {
-				return nil, err
-			}
+	if err := setHostname(config); err != nil {
+		return nil, err
+	}
+	configureLimiter(a.limiter, config)
+
+	return a, nil
+}
 
-			a.Config.Agent.Hostname = hostname
+// configureLimiter applies the series limits of the given config.
+func configureLimiter(l *models.CardinalityLimiter, c *config.Config) {
+	l.Configure(c.Agent.SeriesLimit, c.Agent.SeriesLimits,
+		c.Agent.SeriesLimitWindow.Duration, c.Agent.SeriesLimitAction)
+}
+
+// setHostname sets the host tag of the given config, unless it is omitted.
+func setHostname(c *config.Config) error {
+	if c.Agent.OmitHostname {
+		return nil
+	}
+	if c.Agent.Hostname == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return err
 		}
 
-		config.Tags["host"] = a.Config.Agent.Hostname
+		c.Agent.Hostname = hostname
 	}
 
-	return a, nil
+	c.Tags["host"] = c.Agent.Hostname
+	return nil
+}
+
+// config returns the current configuration of the agent.
+func (a *Agent) config() *config.Config {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config
 }
 
 // Connect connects to all configured outputs
 func (a *Agent) Connect() error {
 	for _, o := range a.Config.Outputs {
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			if err := ot.Start(); err != nil {
-				log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
-					o.Name, err.Error())
-				return err
-			}
+		if err := connectOutput(o); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		log.Printf("D! Attempting connection to output: %s\n", o.Name)
-		err := o.Output.Connect()
-		if err != nil {
-			log.Printf("E! Failed to connect to output %s, retrying in 15s, "+
-				"error was '%s' \n", o.Name, err)
-			time.Sleep(15 * time.Second)
-			err = o.Output.Connect()
-			if err != nil {
-				return err
-			}
+// connectOutput starts the given output if it is a service output, and
+// connects it.
+func connectOutput(o *models.RunningOutput) error {
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		if err := ot.Start(); err != nil {
+			log.Printf("E! Service for output %s failed to start, exiting\n%s\n",
+				o.Name, err.Error())
+			return err
 		}
-		log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	}
+
+	log.Printf("D! Attempting connection to output: %s\n", o.Name)
+	err := o.Connect()
+	if err != nil {
+		// The output reconnects on its own when it is next written to,
+		// once its retry backoff has elapsed. Metrics are buffered until
+		// then.
+		log.Printf("E! Failed to connect to output %s, retrying in the "+
+			"background, error was '%s' \n", o.Name, err)
+		return nil
+	}
+	log.Printf("D! Successfully connected to output: %s\n", o.Name)
 	return nil
 }
 
 // Close closes the connection to all configured outputs
 func (a *Agent) Close() error {
 	var err error
-	for _, o := range a.Config.Outputs {
-		err = o.Output.Close()
-		switch ot := o.Output.(type) {
-		case telegraf.ServiceOutput:
-			ot.Stop()
-		}
+	for _, o := range a.config().Outputs {
+		err = closeOutput(o)
+	}
+	return err
+}
+
+// closeOutput closes the connection of the given output and its buffer.
+func closeOutput(o *models.RunningOutput) error {
+	err := o.Output.Close()
+	switch ot := o.Output.(type) {
+	case telegraf.ServiceOutput:
+		ot.Stop()
+	}
+	if cerr := o.Close(); cerr != nil {
+		log.Printf("E! Error closing buffer of output [%s]: %s\n",
+			o.Name, cerr)
 	}
 	return err
 }
@@ -96,12 +188,14 @@ func panicRecover(input *models.RunningInput) {
 }
 
 // gatherer runs the inputs that have been configured with their own
-// reporting interval.
+// reporting interval or schedule. A gather requested on trigger runs
+// immediately, without jitter.
 func (a *Agent) gatherer(
 	shutdown chan struct{},
+	trigger chan struct{},
 	input *models.RunningInput,
 	interval time.Duration,
-	metricC chan telegraf.Metric,
+	acc *accumulator,
 ) {
 	defer panicRecover(input)
 
@@ -110,31 +204,89 @@ func (a *Agent) gatherer(
 		map[string]string{"input": input.Config.Name},
 	)
 
-	acc := NewAccumulator(input, metricC)
-	acc.SetPrecision(a.Config.Agent.Precision.Duration,
-		a.Config.Agent.Interval.Duration)
+	// inputs are restarted when the agent settings change on reload
+	agentConfig := a.config().Agent
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	acc.SetPrecision(agentConfig.Precision.Duration,
+		agentConfig.Interval.Duration)
+
+	jitter := agentConfig.CollectionJitter.Duration
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
+
+	sched := inputSchedule(input.Config, interval)
+	// inputs gather right away, unless their gathers are aligned on the
+	// wall clock.
+	next := time.Now()
+	if !input.Config.RunOnce &&
+		(input.Config.Schedule != nil || input.Config.RoundInterval) {
+		next = sched.Next(next)
+		if next.IsZero() {
+			log.Printf("W! Schedule of input %s never matches, it only "+
+				"gathers when triggered\n", input.Name())
+		}
+	}
 
 	for {
-		internal.RandomSleep(a.Config.Agent.CollectionJitter.Duration, shutdown)
+		triggered, ok := waitUntil(next, shutdown, trigger)
+		if !ok {
+			return
+		}
+		if !triggered {
+			internal.RandomSleep(jitter, shutdown)
+		}
 
 		start := time.Now()
 		gatherWithTimeout(shutdown, input, acc, interval)
 		elapsed := time.Since(start)
 
 		GatherTime.Incr(elapsed.Nanoseconds())
-
-		select {
-		case <-shutdown:
-			return
-		case <-ticker.C:
-			continue
+		input.SetLastGather(start, elapsed)
+
+		switch {
+		case triggered:
+			// the scheduled gather still happens
+		case input.Config.RunOnce:
+			next = time.Time{}
+		default:
+			next = sched.Next(next)
+			if now := time.Now(); !next.IsZero() && next.Before(now) {
+				// skip the gathers missed while this one ran
+				next = sched.Next(now)
+			}
 		}
 	}
 }
 
+// inputSchedule returns the schedule of the gathers of an input.
+func inputSchedule(c *models.InputConfig, interval time.Duration) schedule.Schedule {
+	if c.Schedule != nil {
+		return c.Schedule
+	}
+	return schedule.Every(interval, c.RoundInterval)
+}
+
+// waitUntil waits until the time t, or forever if t is zero. It returns
+// whether the wait ended on trigger, and false if shutdown was closed.
+func waitUntil(t time.Time, shutdown, trigger chan struct{}) (bool, bool) {
+	var timeout <-chan time.Time
+	if !t.IsZero() {
+		timer := time.NewTimer(time.Until(t))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-shutdown:
+		return false, false
+	case <-timeout:
+		return false, true
+	case <-trigger:
+		return true, true
+	}
+}
+
 // gatherWithTimeout gathers from the given input, with the given timeout.
 //   when the given timeout is reached, gatherWithTimeout logs an error message
 //   but continues waiting for it to return. This is to avoid leaving behind
@@ -202,6 +354,7 @@ func (a *Agent) Test() error {
 			a.Config.Agent.Interval.Duration)
 		input.SetTrace(true)
 		input.SetDefaultTags(a.Config.Tags)
+		input.SetCardinalityLimiter(a.limiter)
 
 		fmt.Printf("* Plugin: %s, Collection 1\n", input.Name())
 		if input.Config.Interval != 0 {
@@ -231,8 +384,9 @@ func (a *Agent) Test() error {
 func (a *Agent) flush() {
 	var wg sync.WaitGroup
 
-	wg.Add(len(a.Config.Outputs))
-	for _, o := range a.Config.Outputs {
+	outputs := a.config().Outputs
+	wg.Add(len(outputs))
+	for _, o := range outputs {
 		go func(output *models.RunningOutput) {
 			defer wg.Done()
 			err := output.Write()
@@ -254,7 +408,7 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 
 	// create an output metric channel and a gorouting that continously passes
 	// each metric onto the output plugins & aggregators.
-	outMetricC := make(chan telegraf.Metric, 100)
+	outMetricC := make(chan routedMetric, 100)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -267,7 +421,11 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 					continue
 				}
 				return
-			case m := <-outMetricC:
+			case rm := <-outMetricC:
+				m := rm.Metric
+				// the lock keeps Reload from removing the aggregators and
+				// outputs while the metric is handed to them.
+				a.mu.RLock()
 				// if dropOriginal is set to true, then we will only send this
 				// metric to the aggregators, not the outputs.
 				var dropOriginal bool
@@ -278,21 +436,34 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 						}
 					}
 				}
+				var outputs []*models.RunningOutput
 				if !dropOriginal {
-					for i, o := range a.Config.Outputs {
-						if i == len(a.Config.Outputs)-1 {
+					for _, o := range a.Config.Outputs {
+						if o.Subscribes(rm.routes) {
+							outputs = append(outputs, o)
+						}
+					}
+				}
+				if len(outputs) == 0 {
+					m.Drop()
+				} else {
+					for i, o := range outputs {
+						if i == len(outputs)-1 {
 							o.AddMetric(m)
 						} else {
 							o.AddMetric(m.Copy())
 						}
 					}
 				}
+				a.mu.RUnlock()
 			}
 		}
 	}()
 
-	ticker := time.NewTicker(a.Config.Agent.FlushInterval.Duration)
-	semaphore := make(chan struct{}, 1)
+	ticker := time.NewTicker(a.config().Agent.FlushInterval.Duration)
+	defer func() {
+		ticker.Stop()
+	}()
 	for {
 		select {
 		case <-shutdown:
@@ -301,28 +472,48 @@ func (a *Agent) flusher(shutdown chan struct{}, metricC chan telegraf.Metric) er
 			wg.Wait()
 			a.flush()
 			return nil
+		case <-a.reloaded:
+			ticker.Stop()
+			ticker = time.NewTicker(a.config().Agent.FlushInterval.Duration)
 		case <-ticker.C:
 			go func() {
 				select {
-				case semaphore <- struct{}{}:
-					internal.RandomSleep(a.Config.Agent.FlushJitter.Duration, shutdown)
+				case a.flushSem <- struct{}{}:
+					internal.RandomSleep(a.config().Agent.FlushJitter.Duration, shutdown)
 					a.flush()
-					<-semaphore
+					<-a.flushSem
 				default:
 					// skipping this flush because one is already happening
 					log.Println("W! Skipping a scheduled flush because there is" +
 						" already a flush ongoing.")
 				}
 			}()
+		case <-a.flushNow:
+			go func() {
+				select {
+				case a.flushSem <- struct{}{}:
+					a.flush()
+					<-a.flushSem
+				default:
+					log.Println("D! Skipping a requested flush because there is" +
+						" already a flush ongoing.")
+				}
+			}()
 		case metric := <-metricC:
+			// the metrics made by the processors take the routes of the
+			// metric they are made from.
+			var routes []string
+			if rm, ok := metric.(*routedMetric); ok {
+				metric, routes = rm.Metric, rm.routes
+			}
 			// NOTE potential bottleneck here as we put each metric through the
 			// processors serially.
 			mS := []telegraf.Metric{metric}
-			for _, processor := range a.Config.Processors {
+			for _, processor := range a.config().Processors {
 				mS = processor.Apply(mS...)
 			}
 			for _, m := range mS {
-				outMetricC <- m
+				outMetricC <- routedMetric{Metric: m, routes: routes}
 			}
 		}
 	}
@@ -337,24 +528,27 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 		a.Config.Agent.Interval.Duration, a.Config.Agent.Quiet,
 		a.Config.Agent.Hostname, a.Config.Agent.FlushInterval.Duration)
 
+	warnUnsubscribedRoutes(a.Config)
+
+	a.runMu.Lock()
 	// channel shared between all input threads for accumulating metrics
-	metricC := make(chan telegraf.Metric, 100)
+	a.metricC = make(chan telegraf.Metric, 100)
+	a.shutdown = shutdown
+	a.inputs = make(map[*models.RunningInput]*worker)
+	a.queues = make(map[*models.RunningInput]*inputQueue)
+	a.aggregators = make(map[*models.RunningAggregator]*worker)
+	a.flushSem = make(chan struct{}, 1)
+	a.reloaded = make(chan struct{}, 1)
+	a.flushNow = make(chan struct{}, 1)
 
 	// Start all ServicePlugins
-	for _, input := range a.Config.Inputs {
-		input.SetDefaultTags(a.Config.Tags)
-		switch p := input.Input.(type) {
-		case telegraf.ServiceInput:
-			acc := NewAccumulator(input, metricC)
-			// Service input plugins should set their own precision of their
-			// metrics.
-			acc.SetPrecision(time.Nanosecond, 0)
-			if err := p.Start(acc); err != nil {
-				log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
-					input.Name(), err.Error())
-				return err
-			}
-			defer p.Stop()
+	for i, input := range a.Config.Inputs {
+		if err := a.startServiceInput(input); err != nil {
+			log.Printf("E! Service for input %s failed to start, exiting\n%s\n",
+				input.Name(), err.Error())
+			a.stopInputs(a.Config.Inputs[:i])
+			a.runMu.Unlock()
+			return err
 		}
 	}
 
@@ -367,37 +561,204 @@ func (a *Agent) Run(shutdown chan struct{}) error {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err := a.flusher(shutdown, metricC); err != nil {
+		if err := a.flusher(shutdown, a.metricC); err != nil {
 			log.Printf("E! Flusher routine failed, exiting: %s\n", err.Error())
 			close(shutdown)
 		}
 	}()
 
-	wg.Add(len(a.Config.Aggregators))
 	for _, aggregator := range a.Config.Aggregators {
-		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
-			acc := NewAccumulator(agg, metricC)
-			acc.SetPrecision(a.Config.Agent.Precision.Duration,
-				a.Config.Agent.Interval.Duration)
-			agg.Run(acc, shutdown)
-		}(aggregator)
+		a.startAggregator(aggregator)
 	}
-
-	wg.Add(len(a.Config.Inputs))
 	for _, input := range a.Config.Inputs {
-		interval := a.Config.Agent.Interval.Duration
-		// overwrite global interval if this plugin has it's own.
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
+		a.startGatherer(input)
+	}
+	a.running = true
+	a.runMu.Unlock()
+
+	var api *http.Server
+	if addr := a.Config.Agent.APIAddress; addr != "" {
+		var err error
+		if api, err = a.startAPI(addr); err != nil {
+			log.Printf("E! Failed to start the management API on %s: %s\n",
+				addr, err)
 		}
-		go func(in *models.RunningInput, interv time.Duration) {
-			defer wg.Done()
-			a.gatherer(shutdown, in, interv, metricC)
-		}(input, interval)
 	}
 
+	<-shutdown
+
+	if api != nil {
+		api.Close()
+	}
+
+	a.runMu.Lock()
+	a.running = false
+	a.stopInputs(a.Config.Inputs)
+	a.stopAggregators(a.Config.Aggregators)
+	a.runMu.Unlock()
+
 	wg.Wait()
 	a.Close()
 	return nil
 }
+
+// warnUnsubscribedRoutes logs the routes of the inputs and aggregators that
+// no output subscribes to, since their metrics are dropped.
+func warnUnsubscribedRoutes(c *config.Config) {
+	check := func(name string, routes []string) {
+		if len(routes) == 0 {
+			routes = []string{models.DefaultRoute}
+		}
+		for _, route := range routes {
+			var subscribed bool
+			for _, o := range c.Outputs {
+				if o.Subscribes([]string{route}) {
+					subscribed = true
+					break
+				}
+			}
+			if !subscribed && len(c.Outputs) > 0 {
+				log.Printf("W! No output subscribes to route %q of %s, its "+
+					"metrics are dropped\n", route, name)
+			}
+		}
+	}
+	for _, input := range c.Inputs {
+		check(input.Name(), input.Config.Routes)
+	}
+	for _, agg := range c.Aggregators {
+		check(agg.Name(), agg.Config.Routes)
+	}
+}
+
+// startServiceInput starts the service of the given input, if it is a
+// service input.
+func (a *Agent) startServiceInput(input *models.RunningInput) error {
+	input.SetDefaultTags(a.Config.Tags)
+	input.SetCardinalityLimiter(a.limiter)
+	p, ok := input.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
+	}
+	acc := a.inputAccumulator(input)
+	// Service input plugins should set their own precision of their
+	// metrics.
+	acc.SetPrecision(time.Nanosecond, 0)
+	return p.Start(acc)
+}
+
+// startGatherer starts gathering from the given input.
+func (a *Agent) startGatherer(input *models.RunningInput) {
+	interval := a.Config.Agent.Interval.Duration
+	// overwrite global interval if this plugin has it's own.
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
+	}
+	w := newWorker()
+	a.inputs[input] = w
+	acc := a.inputAccumulator(input)
+	go func() {
+		defer close(w.done)
+		a.gatherer(w.stop, w.trigger, input, interval, acc)
+	}()
+}
+
+// inputAccumulator returns an accumulator sending the metrics of the given
+// input to the flusher, through the queue of the input if it has one.
+func (a *Agent) inputAccumulator(input *models.RunningInput) *accumulator {
+	acc := NewAccumulator(input, a.metricC)
+	if input.Config.QueueSize <= 0 {
+		return acc
+	}
+
+	q, ok := a.queues[input]
+	if !ok {
+		q = newInputQueue(input, a.shutdown)
+		a.queues[input] = q
+		go q.forward(a.metricC)
+	}
+	acc.queue = q
+	return acc
+}
+
+// TriggerGather makes the inputs of the given name gather immediately, or
+// all inputs if name is empty. The name may be given with or without the
+// "inputs." prefix. It returns the number of inputs triggered.
+func (a *Agent) TriggerGather(name string) (int, error) {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if !a.running {
+		return 0, errNotRunning
+	}
+
+	var n int
+	for input, w := range a.inputs {
+		if name != "" && name != input.Name() && name != input.Config.Name {
+			continue
+		}
+		select {
+		case w.trigger <- struct{}{}:
+		default:
+			// a gather is already pending
+		}
+		n++
+	}
+	return n, nil
+}
+
+// TriggerFlush makes the agent flush its outputs immediately.
+func (a *Agent) TriggerFlush() error {
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if !a.running {
+		return errNotRunning
+	}
+
+	select {
+	case a.flushNow <- struct{}{}:
+	default:
+		// a flush is already pending
+	}
+	return nil
+}
+
+// stopInputs stops gathering from the given inputs, and stops the service of
+// service inputs.
+func (a *Agent) stopInputs(inputs []*models.RunningInput) {
+	for _, input := range inputs {
+		if w, ok := a.inputs[input]; ok {
+			w.Stop()
+			delete(a.inputs, input)
+		}
+		if p, ok := input.Input.(telegraf.ServiceInput); ok {
+			p.Stop()
+		}
+		if q, ok := a.queues[input]; ok {
+			q.Stop()
+			delete(a.queues, input)
+		}
+	}
+}
+
+// startAggregator starts the period loop of the given aggregator.
+func (a *Agent) startAggregator(agg *models.RunningAggregator) {
+	w := newWorker()
+	a.aggregators[agg] = w
+	go func() {
+		defer close(w.done)
+		acc := NewAccumulator(agg, a.metricC)
+		acc.SetPrecision(a.config().Agent.Precision.Duration,
+			a.config().Agent.Interval.Duration)
+		agg.Run(acc, w.stop)
+	}()
+}
+
+// stopAggregators stops the given aggregators.
+func (a *Agent) stopAggregators(aggs []*models.RunningAggregator) {
+	for _, agg := range aggs {
+		if w, ok := a.aggregators[agg]; ok {
+			w.Stop()
+			delete(a.aggregators, agg)
+		}
+	}
+}
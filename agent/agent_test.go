@@ -2,8 +2,11 @@ package agent
 
 import (
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/schedule"
 
 	// needing to load the plugins
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
@@ -109,3 +112,36 @@ func TestAgent_LoadOutput(t *testing.T) {
 	a, _ = NewAgent(c)
 	assert.Equal(t, 3, len(a.Config.Outputs))
 }
+
+func TestInputSchedule(t *testing.T) {
+	now := time.Date(2018, 3, 1, 10, 3, 30, 0, time.UTC)
+
+	s := inputSchedule(&models.InputConfig{}, time.Minute)
+	assert.Equal(t, now.Add(time.Minute), s.Next(now))
+
+	s = inputSchedule(&models.InputConfig{RoundInterval: true}, time.Minute)
+	assert.Equal(t, time.Date(2018, 3, 1, 10, 4, 0, 0, time.UTC), s.Next(now))
+
+	cron, err := schedule.ParseCron("0 * * * *")
+	assert.NoError(t, err)
+	s = inputSchedule(&models.InputConfig{Schedule: cron}, time.Minute)
+	assert.Equal(t, time.Date(2018, 3, 1, 11, 0, 0, 0, time.UTC), s.Next(now))
+}
+
+func TestWaitUntil(t *testing.T) {
+	shutdown := make(chan struct{})
+	trigger := make(chan struct{}, 1)
+
+	triggered, ok := waitUntil(time.Now(), shutdown, trigger)
+	assert.False(t, triggered)
+	assert.True(t, ok)
+
+	trigger <- struct{}{}
+	triggered, ok = waitUntil(time.Time{}, shutdown, trigger)
+	assert.True(t, triggered)
+	assert.True(t, ok)
+
+	close(shutdown)
+	_, ok = waitUntil(time.Time{}, shutdown, trigger)
+	assert.False(t, ok)
+}
@@ -0,0 +1,201 @@
+package agent
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+type inputStatus struct {
+	Name               string                 `json:"name"`
+	Interval           string                 `json:"interval"`
+	Config             map[string]interface{} `json:"config"`
+	LastGather         *time.Time             `json:"last_gather,omitempty"`
+	LastGatherDuration string                 `json:"last_gather_duration,omitempty"`
+	LastError          string                 `json:"last_error,omitempty"`
+	LastErrorTime      *time.Time             `json:"last_error_time,omitempty"`
+}
+
+type outputStatus struct {
+	Name        string                 `json:"name"`
+	Config      map[string]interface{} `json:"config"`
+	BufferSize  int                    `json:"buffer_size"`
+	BufferLimit int                    `json:"buffer_limit"`
+	Circuit     string                 `json:"circuit"`
+}
+
+type pluginStatus struct {
+	Name   string                 `json:"name"`
+	Config map[string]interface{} `json:"config"`
+}
+
+type pluginsStatus struct {
+	Inputs      []inputStatus  `json:"inputs"`
+	Outputs     []outputStatus `json:"outputs"`
+	Processors  []pluginStatus `json:"processors"`
+	Aggregators []pluginStatus `json:"aggregators"`
+}
+
+type statStatus struct {
+	Name   string                 `json:"name"`
+	Tags   map[string]string      `json:"tags"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+// startAPI serves the HTTP management API on the given address.
+func (a *Agent) startAPI(address string) (*http.Server, error) {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	srv := &http.Server{Handler: a.apiHandler()}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! Management API stopped: %s\n", err)
+		}
+	}()
+	log.Printf("I! Management API listening on %s\n", ln.Addr())
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok && !addr.IP.IsLoopback() {
+		log.Printf("W! Management API is not bound to localhost, it has no " +
+			"authentication and is reachable from other hosts\n")
+	}
+	return srv, nil
+}
+
+// apiHandler returns the handler of the HTTP management API:
+//
+//	GET  /plugins            the loaded plugins, their settings and status
+//	GET  /stats              the internal stats of the agent
+//	POST /gather?input=name  gather from the inputs of the given name, or all
+//	POST /flush              flush the outputs
+func (a *Agent) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plugins", a.servePlugins)
+	mux.HandleFunc("/stats", a.serveStats)
+	mux.HandleFunc("/gather", a.serveGather)
+	mux.HandleFunc("/flush", a.serveFlush)
+	return mux
+}
+
+func (a *Agent) servePlugins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	a.mu.RLock()
+	c := a.Config
+	a.mu.RUnlock()
+
+	status := pluginsStatus{
+		Inputs:      []inputStatus{},
+		Outputs:     []outputStatus{},
+		Processors:  []pluginStatus{},
+		Aggregators: []pluginStatus{},
+	}
+	for _, input := range c.Inputs {
+		interval := c.Agent.Interval.Duration
+		if input.Config.Interval != 0 {
+			interval = input.Config.Interval
+		}
+		s := input.Status()
+		is := inputStatus{
+			Name:      input.Name(),
+			Interval:  interval.String(),
+			Config:    c.PluginSettings(input.Input),
+			LastError: s.LastError,
+		}
+		if !s.LastGather.IsZero() {
+			is.LastGather = &s.LastGather
+			is.LastGatherDuration = s.LastGatherDuration.String()
+		}
+		if !s.LastErrorTime.IsZero() {
+			is.LastErrorTime = &s.LastErrorTime
+		}
+		status.Inputs = append(status.Inputs, is)
+	}
+	for _, o := range c.Outputs {
+		status.Outputs = append(status.Outputs, outputStatus{
+			Name:        "outputs." + o.Name,
+			Config:      c.PluginSettings(o.Output),
+			BufferSize:  o.BufferLen(),
+			BufferLimit: o.MetricBufferLimit,
+			Circuit:     o.BreakerState().String(),
+		})
+	}
+	for _, p := range c.Processors {
+		status.Processors = append(status.Processors, pluginStatus{
+			Name:   p.Name,
+			Config: c.PluginSettings(p.Processor),
+		})
+	}
+	for _, agg := range c.Aggregators {
+		status.Aggregators = append(status.Aggregators, pluginStatus{
+			Name:   agg.Name(),
+			Config: c.PluginSettings(agg.Aggregator()),
+		})
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (a *Agent) serveStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats := []statStatus{}
+	for _, m := range selfstat.Snapshot() {
+		stats = append(stats, statStatus{
+			Name:   m.Name(),
+			Tags:   m.Tags(),
+			Fields: m.Fields(),
+		})
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (a *Agent) serveGather(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := r.URL.Query().Get("input")
+	n, err := a.TriggerGather(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "no input named "+name, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]int{"triggered": n})
+}
+
+func (a *Agent) serveFlush(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := a.TriggerFlush(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("E! Error writing management API response: %s\n", err)
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type apiInput struct {
+	Servers  []string
+	Password string
+}
+
+func (i *apiInput) Description() string                   { return "" }
+func (i *apiInput) SampleConfig() string                  { return "" }
+func (i *apiInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func newAPIAgent() (*Agent, *models.RunningInput) {
+	c := config.NewConfig()
+	input := models.NewRunningInput(&apiInput{
+		Servers:  []string{"localhost"},
+		Password: "hunter2",
+	}, &models.InputConfig{Name: "api"})
+	c.Inputs = append(c.Inputs, input)
+
+	a := &Agent{
+		Config:   c,
+		running:  true,
+		inputs:   map[*models.RunningInput]*worker{input: newWorker()},
+		flushNow: make(chan struct{}, 1),
+	}
+	return a, input
+}
+
+func TestAPIPlugins(t *testing.T) {
+	a, input := newAPIAgent()
+	start := time.Now()
+	input.SetLastGather(start, time.Second)
+	input.SetLastError(errors.New("gather failed"))
+
+	rec := httptest.NewRecorder()
+	a.apiHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/plugins", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status pluginsStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.Len(t, status.Inputs, 1)
+	in := status.Inputs[0]
+	assert.Equal(t, "inputs.api", in.Name)
+	assert.Equal(t, "10s", in.Interval)
+	assert.Equal(t, []interface{}{"localhost"}, in.Config["servers"])
+	assert.Equal(t, config.Redacted, in.Config["password"])
+	assert.Equal(t, "1s", in.LastGatherDuration)
+	assert.Equal(t, "gather failed", in.LastError)
+	assert.NotNil(t, in.LastErrorTime)
+	assert.Len(t, status.Outputs, 0)
+}
+
+func TestAPIStats(t *testing.T) {
+	a, _ := newAPIAgent()
+
+	rec := httptest.NewRecorder()
+	a.apiHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats []statStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+}
+
+func TestAPIGather(t *testing.T) {
+	a, input := newAPIAgent()
+	handler := a.apiHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/gather", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/gather?input=nope", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/gather?input=api", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, a.inputs[input].trigger, 1)
+
+	a.running = false
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/gather", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestAPIFlush(t *testing.T) {
+	a, _ := newAPIAgent()
+
+	rec := httptest.NewRecorder()
+	a.apiHandler().ServeHTTP(rec, httptest.NewRequest("POST", "/flush", nil))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Len(t, a.flushNow, 1)
+}
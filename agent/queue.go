@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/buffer"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// inputQueue is a bounded queue between an input and the flusher. Unless its
+// overflow policy is to block, it keeps a stalled flusher or output from
+// blocking the input, at the cost of dropping metrics.
+type inputQueue struct {
+	input   *models.RunningInput
+	metrics chan telegraf.Metric
+
+	shutdown <-chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func newInputQueue(input *models.RunningInput, shutdown <-chan struct{}) *inputQueue {
+	return &inputQueue{
+		input:    input,
+		metrics:  make(chan telegraf.Metric, input.Config.QueueSize),
+		shutdown: shutdown,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// put adds a metric to the queue. When the queue is full, it blocks, drops
+// the oldest queued metric or drops the given metric, depending on the
+// overflow policy of the input. A blocked put drops the metric once the queue
+// is stopped or the agent shuts down, since nothing reads the queue anymore.
+func (q *inputQueue) put(m telegraf.Metric) {
+	switch q.input.Config.QueueOverflow {
+	case models.OverflowDropNewest:
+		select {
+		case q.metrics <- m:
+		default:
+			q.drop(m)
+		}
+	case models.OverflowDropOldest:
+		for {
+			select {
+			case q.metrics <- m:
+				return
+			default:
+			}
+			select {
+			case old := <-q.metrics:
+				q.drop(old)
+			default:
+				// the forwarder emptied the queue meanwhile
+			}
+		}
+	default:
+		select {
+		case q.metrics <- m:
+		case <-q.stop:
+			q.drop(m)
+		case <-q.shutdown:
+			q.drop(m)
+		}
+	}
+}
+
+func (q *inputQueue) drop(m telegraf.Metric) {
+	m.Reject()
+	q.input.MetricsDropped.Incr(1)
+	buffer.MetricsDropped.Incr(1)
+}
+
+// forward sends the queued metrics to out. Once stopped, it sends the
+// metrics left in the queue, unless the agent shuts down.
+func (q *inputQueue) forward(out chan<- telegraf.Metric) {
+	defer close(q.done)
+	for {
+		select {
+		case m := <-q.metrics:
+			select {
+			case out <- m:
+			case <-q.shutdown:
+				return
+			}
+		case <-q.stop:
+			for {
+				select {
+				case m := <-q.metrics:
+					select {
+					case out <- m:
+					case <-q.shutdown:
+						return
+					}
+				default:
+					return
+				}
+			}
+		case <-q.shutdown:
+			return
+		}
+	}
+}
+
+// Stop stops the forwarder once the queue is empty and waits for it to
+// return.
+func (q *inputQueue) Stop() {
+	close(q.stop)
+	<-q.done
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestQueue returns a queue of size 2. The name keeps the stats of the
+// tests apart, stats being registered once per input name.
+func newTestQueue(name string, overflow string, shutdown <-chan struct{}) *inputQueue {
+	input := models.NewRunningInput(&apiInput{}, &models.InputConfig{
+		Name:          name,
+		QueueSize:     2,
+		QueueOverflow: overflow,
+	})
+	return newInputQueue(input, shutdown)
+}
+
+func queued(q *inputQueue) []string {
+	var names []string
+	for len(q.metrics) > 0 {
+		names = append(names, (<-q.metrics).Name())
+	}
+	return names
+}
+
+func TestInputQueueDropOldest(t *testing.T) {
+	q := newTestQueue("drop_oldest", models.OverflowDropOldest, nil)
+	dropped := q.input.MetricsDropped.Get()
+	for _, name := range []string{"a", "b", "c"} {
+		q.put(testutil.TestMetric(1, name))
+	}
+	assert.Equal(t, []string{"b", "c"}, queued(q))
+	assert.Equal(t, dropped+1, q.input.MetricsDropped.Get())
+}
+
+func TestInputQueueDropNewest(t *testing.T) {
+	q := newTestQueue("drop_newest", models.OverflowDropNewest, nil)
+	dropped := q.input.MetricsDropped.Get()
+	for _, name := range []string{"a", "b", "c"} {
+		q.put(testutil.TestMetric(1, name))
+	}
+	assert.Equal(t, []string{"a", "b"}, queued(q))
+	assert.Equal(t, dropped+1, q.input.MetricsDropped.Get())
+}
+
+func TestInputQueueForward(t *testing.T) {
+	shutdown := make(chan struct{})
+	defer close(shutdown)
+	q := newTestQueue("block", models.OverflowBlock, shutdown)
+	dropped := q.input.MetricsDropped.Get()
+	out := make(chan telegraf.Metric, 3)
+
+	q.put(testutil.TestMetric(1, "a"))
+	q.put(testutil.TestMetric(1, "b"))
+	go q.forward(out)
+	q.put(testutil.TestMetric(1, "c"))
+
+	// stopping the queue forwards the queued metrics first
+	q.Stop()
+	require.Len(t, out, 3)
+	assert.Equal(t, "a", (<-out).Name())
+	assert.Equal(t, "b", (<-out).Name())
+	assert.Equal(t, "c", (<-out).Name())
+	assert.Equal(t, dropped, q.input.MetricsDropped.Get())
+}
+
+func TestInputQueueBlockStopped(t *testing.T) {
+	q := newTestQueue("block_stopped", models.OverflowBlock, nil)
+	dropped := q.input.MetricsDropped.Get()
+
+	q.put(testutil.TestMetric(1, "a"))
+	q.put(testutil.TestMetric(1, "b"))
+	close(q.stop)
+	q.put(testutil.TestMetric(1, "c"))
+	assert.Equal(t, []string{"a", "b"}, queued(q))
+	assert.Equal(t, dropped+1, q.input.MetricsDropped.Get())
+}
+
+func TestInputQueueBlockShutdown(t *testing.T) {
+	shutdown := make(chan struct{})
+	q := newTestQueue("block_shutdown", models.OverflowBlock, shutdown)
+	dropped := q.input.MetricsDropped.Get()
+
+	q.put(testutil.TestMetric(1, "a"))
+	q.put(testutil.TestMetric(1, "b"))
+	close(shutdown)
+	q.put(testutil.TestMetric(1, "c"))
+	assert.Equal(t, []string{"a", "b"}, queued(q))
+	assert.Equal(t, dropped+1, q.input.MetricsDropped.Get())
+}
@@ -0,0 +1,238 @@
+package agent
+
+import (
+	"log"
+	"reflect"
+
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+)
+
+// Reload applies the configuration c to the running agent. Only the plugins
+// whose configuration changed are restarted: the other plugins keep running
+// with their state, and unchanged outputs keep their buffered metrics. The
+// metrics buffered by a changed output are handed over to the new output of
+// the same name, if any.
+//
+// Inputs and aggregators are all restarted if the agent settings or the
+// global tags changed.
+func (a *Agent) Reload(c *config.Config) error {
+	if err := setHostname(c); err != nil {
+		return err
+	}
+
+	a.runMu.Lock()
+	defer a.runMu.Unlock()
+	if !a.running {
+		return errNotRunning
+	}
+
+	old := a.Config
+	restartAll := !reflect.DeepEqual(old.Agent, c.Agent) ||
+		!reflect.DeepEqual(old.Tags, c.Tags)
+	if restartAll {
+		log.Printf("I! Agent settings or global tags changed, restarting " +
+			"all inputs and aggregators")
+	}
+
+	// inputs
+	var inputSums, newInputSums []uint64
+	for _, in := range old.Inputs {
+		inputSums = append(inputSums, in.Config.Checksum)
+	}
+	for _, in := range c.Inputs {
+		newInputSums = append(newInputSums, in.Config.Checksum)
+	}
+	if restartAll {
+		inputSums = nil
+	}
+	matched, kept := matchChecksums(inputSums, newInputSums)
+	var addedInputs, removedInputs []*models.RunningInput
+	for i, j := range matched {
+		if j < 0 {
+			addedInputs = append(addedInputs, c.Inputs[i])
+		} else {
+			c.Inputs[i] = old.Inputs[j]
+		}
+	}
+	for j, in := range old.Inputs {
+		if j >= len(kept) || !kept[j] {
+			removedInputs = append(removedInputs, in)
+		}
+	}
+
+	// aggregators
+	var aggSums, newAggSums []uint64
+	for _, agg := range old.Aggregators {
+		aggSums = append(aggSums, agg.Config.Checksum)
+	}
+	for _, agg := range c.Aggregators {
+		newAggSums = append(newAggSums, agg.Config.Checksum)
+	}
+	if restartAll {
+		aggSums = nil
+	}
+	matched, kept = matchChecksums(aggSums, newAggSums)
+	var addedAggs, removedAggs []*models.RunningAggregator
+	for i, j := range matched {
+		if j < 0 {
+			addedAggs = append(addedAggs, c.Aggregators[i])
+		} else {
+			c.Aggregators[i] = old.Aggregators[j]
+		}
+	}
+	for j, agg := range old.Aggregators {
+		if j >= len(kept) || !kept[j] {
+			removedAggs = append(removedAggs, agg)
+		}
+	}
+
+	// processors have no state outside of the plugin, they are kept so that
+	// the plugin keeps its state.
+	var procSums, newProcSums []uint64
+	for _, p := range old.Processors {
+		procSums = append(procSums, p.Config.Checksum)
+	}
+	for _, p := range c.Processors {
+		newProcSums = append(newProcSums, p.Config.Checksum)
+	}
+	matched, _ = matchChecksums(procSums, newProcSums)
+	for i, j := range matched {
+		if j >= 0 {
+			c.Processors[i] = old.Processors[j]
+		}
+	}
+
+	// outputs
+	var outputSums, newOutputSums []uint64
+	for _, o := range old.Outputs {
+		outputSums = append(outputSums, o.Config.Checksum)
+	}
+	for _, o := range c.Outputs {
+		newOutputSums = append(newOutputSums, o.Config.Checksum)
+	}
+	matched, kept = matchChecksums(outputSums, newOutputSums)
+	var addedOutputs, removedOutputs []*models.RunningOutput
+	for i, j := range matched {
+		if j < 0 {
+			addedOutputs = append(addedOutputs, c.Outputs[i])
+		} else {
+			c.Outputs[i] = old.Outputs[j]
+		}
+	}
+	for j, o := range old.Outputs {
+		if !kept[j] {
+			removedOutputs = append(removedOutputs, o)
+		}
+	}
+
+	warnUnsubscribedRoutes(c)
+
+	var err error
+	for _, o := range addedOutputs {
+		if cerr := connectOutput(o); cerr != nil {
+			err = cerr
+		}
+	}
+
+	a.stopInputs(removedInputs)
+
+	// no flush may run while the removed outputs hand over their metrics, and
+	// no metric is routed to the new outputs until the removed ones are
+	// closed, so that an output never opens the buffer directory of an
+	// output it replaces while that one still uses it.
+	a.flushSem <- struct{}{}
+	a.mu.Lock()
+	for _, o := range removedOutputs {
+		a.retireOutput(o, addedOutputs)
+	}
+	a.Config = c
+	a.mu.Unlock()
+	configureLimiter(a.limiter, c)
+	a.stopAggregators(removedAggs)
+	<-a.flushSem
+
+	select {
+	case a.reloaded <- struct{}{}:
+	default:
+	}
+
+	for _, agg := range addedAggs {
+		a.startAggregator(agg)
+	}
+	for _, input := range addedInputs {
+		if serr := a.startServiceInput(input); serr != nil {
+			log.Printf("E! Service for input %s failed to start: %s\n",
+				input.Name(), serr)
+			err = serr
+			continue
+		}
+		a.startGatherer(input)
+	}
+
+	log.Printf("I! Reloaded config: inputs %d started, %d stopped; "+
+		"aggregators %d started, %d stopped; outputs %d started, %d stopped",
+		len(addedInputs), len(removedInputs),
+		len(addedAggs), len(removedAggs),
+		len(addedOutputs), len(removedOutputs))
+	return err
+}
+
+// retireOutput flushes and closes an output removed by a reload. The metrics
+// it could not write are handed over to the first added output of the same
+// name.
+func (a *Agent) retireOutput(o *models.RunningOutput, added []*models.RunningOutput) {
+	if err := o.Write(); err != nil {
+		log.Printf("E! Error writing to output [%s]: %s\n", o.Name, err)
+	}
+
+	metrics := o.Drain()
+	var next *models.RunningOutput
+	for _, n := range added {
+		if n.Name == o.Name {
+			next = n
+			break
+		}
+	}
+	if len(metrics) > 0 {
+		if next != nil {
+			log.Printf("I! Output [%s] handing over %d buffered metrics to "+
+				"its new configuration", o.Name, len(metrics))
+			for _, m := range metrics {
+				next.AddMetric(m)
+			}
+		} else {
+			log.Printf("W! Output [%s] was removed, dropping %d buffered metrics",
+				o.Name, len(metrics))
+			for _, m := range metrics {
+				m.Reject()
+			}
+		}
+	}
+
+	if err := closeOutput(o); err != nil {
+		log.Printf("E! Error closing output [%s]: %s\n", o.Name, err)
+	}
+}
+
+// matchChecksums pairs every new plugin with an unpaired old plugin of the
+// same checksum. It returns, for each new plugin, the index of the old plugin
+// it is paired with or -1, and for each old plugin whether it is paired.
+func matchChecksums(old, new []uint64) ([]int, []bool) {
+	byChecksum := make(map[uint64][]int)
+	for j, sum := range old {
+		byChecksum[sum] = append(byChecksum[sum], j)
+	}
+
+	matched := make([]int, len(new))
+	kept := make([]bool, len(old))
+	for i, sum := range new {
+		matched[i] = -1
+		if js := byChecksum[sum]; len(js) > 0 {
+			matched[i] = js[0]
+			kept[js[0]] = true
+			byChecksum[sum] = js[1:]
+		}
+	}
+	return matched, kept
+}
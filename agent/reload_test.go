@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/config"
+	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchChecksums(t *testing.T) {
+	matched, kept := matchChecksums(
+		[]uint64{1, 2, 2, 3},
+		[]uint64{2, 4, 1, 2, 2},
+	)
+	assert.Equal(t, []int{1, -1, 0, 2, -1}, matched)
+	assert.Equal(t, []bool{true, true, true, false}, kept)
+}
+
+func TestMatchChecksumsEmpty(t *testing.T) {
+	matched, kept := matchChecksums(nil, []uint64{1, 2})
+	assert.Equal(t, []int{-1, -1}, matched)
+	assert.Len(t, kept, 0)
+}
+
+type reloadInput struct {
+	started bool
+	stopped bool
+}
+
+func (i *reloadInput) Description() string                   { return "" }
+func (i *reloadInput) SampleConfig() string                  { return "" }
+func (i *reloadInput) Gather(acc telegraf.Accumulator) error { return nil }
+func (i *reloadInput) Start(acc telegraf.Accumulator) error  { i.started = true; return nil }
+func (i *reloadInput) Stop()                                 { i.stopped = true }
+
+type reloadAggregator struct{}
+
+func (a *reloadAggregator) Description() string           { return "" }
+func (a *reloadAggregator) SampleConfig() string          { return "" }
+func (a *reloadAggregator) Add(in telegraf.Metric)        {}
+func (a *reloadAggregator) Push(acc telegraf.Accumulator) {}
+func (a *reloadAggregator) Reset()                        {}
+
+// reloadOutput fails to write while fail is set.
+type reloadOutput struct {
+	fail    bool
+	closed  bool
+	written int
+}
+
+func (o *reloadOutput) Description() string  { return "" }
+func (o *reloadOutput) SampleConfig() string { return "" }
+func (o *reloadOutput) Connect() error       { return nil }
+func (o *reloadOutput) Close() error         { o.closed = true; return nil }
+func (o *reloadOutput) Write(metrics []telegraf.Metric) error {
+	if o.fail {
+		return errors.New("write failed")
+	}
+	o.written += len(metrics)
+	return nil
+}
+
+func newReloadInput(name string, checksum uint64) *models.RunningInput {
+	return models.NewRunningInput(&reloadInput{},
+		&models.InputConfig{Name: name, Checksum: checksum})
+}
+
+func newReloadAggregator(name string, checksum uint64) *models.RunningAggregator {
+	return models.NewRunningAggregator(&reloadAggregator{},
+		&models.AggregatorConfig{Name: name, Period: time.Hour, Checksum: checksum})
+}
+
+func newReloadOutput(name string, checksum uint64, fail bool) *models.RunningOutput {
+	return models.NewRunningOutput(name, &reloadOutput{fail: fail},
+		&models.OutputConfig{Name: name, Checksum: checksum}, 100, 100)
+}
+
+func reloadConfig() *config.Config {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	return c
+}
+
+// newReloadAgent returns an agent running the plugins of c, without running
+// its flusher.
+func newReloadAgent(t *testing.T, c *config.Config) *Agent {
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+	a.running = true
+	a.metricC = make(chan telegraf.Metric, 100)
+	a.shutdown = make(chan struct{})
+	a.inputs = make(map[*models.RunningInput]*worker)
+	a.queues = make(map[*models.RunningInput]*inputQueue)
+	a.aggregators = make(map[*models.RunningAggregator]*worker)
+	a.flushSem = make(chan struct{}, 1)
+	a.reloaded = make(chan struct{}, 1)
+	a.flushNow = make(chan struct{}, 1)
+	for _, input := range c.Inputs {
+		require.NoError(t, a.startServiceInput(input))
+		a.startGatherer(input)
+	}
+	for _, agg := range c.Aggregators {
+		a.startAggregator(agg)
+	}
+	return a
+}
+
+func testMetrics(t *testing.T, n int) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for i := 0; i < n; i++ {
+		m, err := metric.New("cpu", map[string]string{},
+			map[string]interface{}{"value": int64(i)}, time.Unix(0, 0))
+		require.NoError(t, err)
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+func TestReload(t *testing.T) {
+	keptInput := newReloadInput("kept", 1)
+	removedInput := newReloadInput("removed", 2)
+	keptAgg := newReloadAggregator("kept", 1)
+	removedAgg := newReloadAggregator("removed", 2)
+	keptOutput := newReloadOutput("kept", 1, true)
+	replacedOutput := newReloadOutput("replaced", 2, true)
+	removedOutput := newReloadOutput("removed", 3, true)
+
+	c := reloadConfig()
+	c.Inputs = []*models.RunningInput{keptInput, removedInput}
+	c.Aggregators = []*models.RunningAggregator{keptAgg, removedAgg}
+	c.Outputs = []*models.RunningOutput{keptOutput, replacedOutput, removedOutput}
+	a := newReloadAgent(t, c)
+	defer close(a.shutdown)
+
+	for _, m := range testMetrics(t, 2) {
+		keptOutput.AddMetric(m)
+	}
+	for _, m := range testMetrics(t, 3) {
+		replacedOutput.AddMetric(m)
+	}
+	var infos []telegraf.DeliveryInfo
+	removedMetrics, _ := metric.WithGroupTracking(testMetrics(t, 1),
+		func(info telegraf.DeliveryInfo) {
+			infos = append(infos, info)
+		})
+	removedOutput.AddMetric(removedMetrics[0])
+
+	addedInput := newReloadInput("added", 3)
+	newOutput := newReloadOutput("replaced", 4, false)
+	nc := reloadConfig()
+	nc.Inputs = []*models.RunningInput{newReloadInput("kept", 1), addedInput}
+	nc.Aggregators = []*models.RunningAggregator{newReloadAggregator("kept", 1)}
+	nc.Outputs = []*models.RunningOutput{newReloadOutput("kept", 1, false), newOutput}
+	require.NoError(t, a.Reload(nc))
+	defer a.stopInputs(a.Config.Inputs)
+	defer a.stopAggregators(a.Config.Aggregators)
+
+	// the unchanged plugins keep running
+	assert.Equal(t, []*models.RunningInput{keptInput, addedInput}, a.Config.Inputs)
+	assert.Equal(t, models.RunningAggregators{keptAgg}, a.Config.Aggregators)
+	assert.Equal(t, []*models.RunningOutput{keptOutput, newOutput}, a.Config.Outputs)
+	assert.Contains(t, a.inputs, keptInput)
+	assert.Contains(t, a.aggregators, keptAgg)
+	assert.True(t, addedInput.Input.(*reloadInput).started)
+
+	// the removed inputs and aggregators are stopped
+	assert.True(t, removedInput.Input.(*reloadInput).stopped)
+	assert.False(t, keptInput.Input.(*reloadInput).stopped)
+	assert.NotContains(t, a.inputs, removedInput)
+	assert.NotContains(t, a.aggregators, removedAgg)
+
+	// the kept output keeps its metrics, the replaced output hands them over
+	assert.Equal(t, 2, keptOutput.BufferLen())
+	assert.False(t, keptOutput.Output.(*reloadOutput).closed)
+	assert.Equal(t, 3, newOutput.BufferLen())
+	assert.Equal(t, 0, replacedOutput.BufferLen())
+	assert.True(t, replacedOutput.Output.(*reloadOutput).closed)
+
+	// the metrics of the removed output are rejected
+	assert.Equal(t, 0, removedOutput.BufferLen())
+	assert.True(t, removedOutput.Output.(*reloadOutput).closed)
+	require.Len(t, infos, 1)
+	assert.False(t, infos[0].Delivered())
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
@@ -11,6 +13,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/influxdata/telegraf/agent"
 	"github.com/influxdata/telegraf/internal/config"
@@ -34,6 +37,8 @@ var fTest = flag.Bool("test", false, "gather metrics, print them out, and exit")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fWatchConfig = flag.Bool("watch-config", false,
+	"reload the configuration when the config file or directory changes")
 var fVersion = flag.Bool("version", false, "display the version")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -83,10 +88,14 @@ The commands & flags are:
 
   config             print out full sample configuration to stdout
   version            print the version to stdout
+  secrets            manage the secrets of the keyring of the config file,
+                     ie. 'secrets set <name>', 'secrets delete <name>' or
+                     'secrets list'
 
   --config <file>     configuration file to load
   --test              gather metrics once, print them to stdout, and exit
   --config-directory  directory containing additional *.conf files
+  --watch-config      reload the config when the config file or directory changes
   --input-filter      filter the input plugins to enable, separator is :
   --output-filter     filter the output plugins to enable, separator is :
   --usage             print usage for a plugin, ie, 'telegraf --usage mysql'
@@ -113,10 +122,68 @@ Examples:
 
   # run telegraf with pprof
   telegraf --config telegraf.conf --pprof-addr localhost:6060
+
+  # store the password read from stdin in the keyring of the config file
+  telegraf --config telegraf.conf secrets set mysql_password
 `
 
 var stop chan struct{}
 
+// watchInterval is how often the config files are checked for changes when
+// --watch-config is set.
+const watchInterval = 5 * time.Second
+
+// loadConfig loads the config file and the config directory.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	err := c.LoadConfig(*fConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if *fConfigDirectory != "" {
+		err = c.LoadDirectory(*fConfigDirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !*fTest && len(c.Outputs) == 0 {
+		return nil, errors.New("Error: no outputs found, did you provide a valid config file?")
+	}
+	if len(c.Inputs) == 0 {
+		return nil, errors.New("Error: no inputs found, did you provide a valid config file?")
+	}
+	return c, nil
+}
+
+func setupLogging(c *config.Config) {
+	logger.SetupLogging(
+		c.Agent.Debug || *fDebug,
+		c.Agent.Quiet || *fQuiet,
+		c.Agent.Logfile,
+	)
+}
+
+// reload loads the configuration again and applies it to the running agent.
+// Only the plugins whose configuration changed are restarted. If the new
+// configuration cannot be loaded, the agent keeps running with the current
+// one.
+func reload(ag *agent.Agent, inputFilters, outputFilters []string) {
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Printf("E! Not reloading, keeping the current config: %s", err)
+		return
+	}
+	setupLogging(c)
+	if err := ag.Reload(c); err != nil {
+		log.Printf("E! Error reloading config: %s", err)
+	}
+	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+}
+
 func reloadLoop(
 	stop chan struct{},
 	inputFilters []string,
@@ -124,103 +191,132 @@ func reloadLoop(
 	aggregatorFilters []string,
 	processorFilters []string,
 ) {
-	reload := make(chan bool, 1)
-	reload <- true
-	for <-reload {
-		reload <- false
-
-		// If no other options are specified, load the config file and run.
-		c := config.NewConfig()
-		c.OutputFilters = outputFilters
-		c.InputFilters = inputFilters
-		err := c.LoadConfig(*fConfig)
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		if *fConfigDirectory != "" {
-			err = c.LoadDirectory(*fConfigDirectory)
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-		}
-		if !*fTest && len(c.Outputs) == 0 {
-			log.Fatalf("E! Error: no outputs found, did you provide a valid config file?")
-		}
-		if len(c.Inputs) == 0 {
-			log.Fatalf("E! Error: no inputs found, did you provide a valid config file?")
-		}
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
+
+	// Setup logging
+	setupLogging(ag.Config)
 
-		ag, err := agent.NewAgent(c)
+	if *fTest {
+		err = ag.Test()
 		if err != nil {
 			log.Fatal("E! " + err.Error())
 		}
+		os.Exit(0)
+	}
 
-		// Setup logging
-		logger.SetupLogging(
-			ag.Config.Agent.Debug || *fDebug,
-			ag.Config.Agent.Quiet || *fQuiet,
-			ag.Config.Agent.Logfile,
-		)
+	err = ag.Connect()
+	if err != nil {
+		log.Fatal("E! " + err.Error())
+	}
 
-		if *fTest {
-			err = ag.Test()
-			if err != nil {
-				log.Fatal("E! " + err.Error())
-			}
-			os.Exit(0)
-		}
+	shutdown := make(chan struct{})
+	signals := make(chan os.Signal)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
 
-		err = ag.Connect()
-		if err != nil {
-			log.Fatal("E! " + err.Error())
-		}
+	changed := make(chan struct{}, 1)
+	if *fWatchConfig {
+		go config.Watch(*fConfig, *fConfigDirectory, watchInterval,
+			changed, shutdown)
+	}
 
-		shutdown := make(chan struct{})
-		signals := make(chan os.Signal)
-		signal.Notify(signals, os.Interrupt, syscall.SIGHUP)
-		go func() {
+	go func() {
+		for {
 			select {
 			case sig := <-signals:
 				if sig == os.Interrupt {
 					close(shutdown)
+					return
 				}
 				if sig == syscall.SIGHUP {
 					log.Printf("I! Reloading Telegraf config\n")
-					<-reload
-					reload <- true
-					close(shutdown)
+					reload(ag, inputFilters, outputFilters)
 				}
+			case <-changed:
+				log.Printf("I! Config files changed, reloading Telegraf config\n")
+				reload(ag, inputFilters, outputFilters)
 			case <-stop:
 				close(shutdown)
+				return
 			}
-		}()
+		}
+	}()
 
-		log.Printf("I! Starting Telegraf (version %s)\n", version)
-		log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
-		log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
-		log.Printf("I! Tags enabled: %s", c.ListTags())
+	log.Printf("I! Starting Telegraf (version %s)\n", version)
+	log.Printf("I! Loaded outputs: %s", strings.Join(c.OutputNames(), " "))
+	log.Printf("I! Loaded inputs: %s", strings.Join(c.InputNames(), " "))
+	log.Printf("I! Tags enabled: %s", c.ListTags())
 
-		if *fPidfile != "" {
-			f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				log.Printf("E! Unable to create pidfile: %s", err)
-			} else {
-				fmt.Fprintf(f, "%d\n", os.Getpid())
-
-				f.Close()
-
-				defer func() {
-					err := os.Remove(*fPidfile)
-					if err != nil {
-						log.Printf("E! Unable to remove pidfile: %s", err)
-					}
-				}()
-			}
+	if *fPidfile != "" {
+		f, err := os.OpenFile(*fPidfile, os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("E! Unable to create pidfile: %s", err)
+		} else {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+
+			f.Close()
+
+			defer func() {
+				err := os.Remove(*fPidfile)
+				if err != nil {
+					log.Printf("E! Unable to remove pidfile: %s", err)
+				}
+			}()
 		}
+	}
+
+	ag.Run(shutdown)
+}
 
-		ag.Run(shutdown)
+// secrets runs the secrets command, which manages the secrets of the keyring
+// configured in the [agent] table of the config file.
+func secrets(args []string) error {
+	c := config.NewConfig()
+	if err := c.LoadAgentConfig(*fConfig); err != nil {
+		return err
+	}
+	k, err := c.Keyring()
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 1 && args[0] == "list" {
+		for _, name := range k.Names() {
+			fmt.Println(name)
+		}
+		return nil
 	}
+	if len(args) != 2 {
+		return errors.New("usage: telegraf secrets set|delete <name> or " +
+			"telegraf secrets list")
+	}
+
+	name := args[1]
+	switch args[0] {
+	case "set":
+		// the secret is read from stdin to keep it out of the shell history
+		secret, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		k.Set(name, strings.TrimRight(string(secret), "\r\n"))
+	case "delete":
+		if _, ok := k.Get(name); !ok {
+			return fmt.Errorf("no secret %q in keyring", name)
+		}
+		k.Delete(name)
+	default:
+		return fmt.Errorf("unknown secrets command %q", args[0])
+	}
+	return k.Save()
 }
 
 func usageExit(rc int) {
@@ -305,6 +401,11 @@ func main() {
 				processorFilters,
 			)
 			return
+		case "secrets":
+			if err := secrets(args[1:]); err != nil {
+				log.Fatalf("E! %s", err)
+			}
+			return
 		}
 	}
 
@@ -47,7 +47,8 @@ func (b *Buffer) Add(metrics ...telegraf.Metric) {
 		default:
 			b.mu.Lock()
 			MetricsDropped.Incr(1)
-			<-b.buf
+			dropped := <-b.buf
+			dropped.Reject()
 			b.buf <- metrics[i]
 			b.mu.Unlock()
 		}
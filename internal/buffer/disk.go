@@ -0,0 +1,491 @@
+package buffer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// Default maximum number of bytes kept on disk by a DiskBuffer.
+	DEFAULT_DISK_MAX_BYTES = 1024 * 1024 * 1024
+
+	// Default size of a single segment file of a DiskBuffer.
+	DEFAULT_DISK_SEGMENT_SIZE = 8 * 1024 * 1024
+
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+)
+
+// segment is a single append-only file of the on-disk queue.
+type segment struct {
+	id   uint64
+	path string
+	// size is the number of bytes in the file, including consumed records.
+	size int64
+	// count is the number of records not yet consumed.
+	count int
+}
+
+// DiskBuffer is a write-ahead queue of metrics stored in segment files in a
+// directory. It has the same API as Buffer, but survives restarts of the
+// agent: metrics left in the directory are replayed, in order, by the next
+// DiskBuffer opened on it.
+//
+// Every record is a single line made of the metric value type followed by the
+// metric in line protocol.
+type DiskBuffer struct {
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+
+	mu       sync.Mutex
+	segments []*segment
+	// offset is the read position in the oldest segment.
+	offset int64
+	// bytes is the total size of all segments.
+	bytes  int64
+	length int
+	w      *os.File
+
+	// peeked are the records returned by the last Peek, not yet committed.
+	peeked []peekedRange
+}
+
+// peekedRange is the part of a segment read by a Peek.
+type peekedRange struct {
+	s    *segment
+	recs int
+	read int64
+	// dropped is the number of records that could not be read.
+	dropped int
+}
+
+// NewDiskBuffer opens, or creates, the on-disk queue in dir.
+//   maxBytes is the maximum size of all segments. If Add would go over this
+//   limit, the oldest segment(s) will be dropped.
+//   segmentSize is the size after which a new segment file is started.
+func NewDiskBuffer(dir string, maxBytes, segmentSize int64) (*DiskBuffer, error) {
+	if maxBytes <= 0 {
+		maxBytes = DEFAULT_DISK_MAX_BYTES
+	}
+	if segmentSize <= 0 {
+		segmentSize = DEFAULT_DISK_SEGMENT_SIZE
+	}
+	// Dropping happens a segment at a time, so keep at least two of them
+	// within the limit.
+	if segmentSize > maxBytes/2 {
+		segmentSize = maxBytes / 2
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, err
+	}
+
+	b := &DiskBuffer{
+		dir:         dir,
+		maxBytes:    maxBytes,
+		segmentSize: segmentSize,
+	}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load scans the directory for segments left by a previous run.
+func (b *DiskBuffer) load() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, &segment{
+			id:   id,
+			path: filepath.Join(b.dir, name),
+			size: f.Size(),
+		})
+	}
+	sort.Slice(b.segments, func(i, j int) bool {
+		return b.segments[i].id < b.segments[j].id
+	})
+
+	cursorID, cursorOffset := b.readCursor()
+	// drop segments that were fully consumed before the cursor was advanced
+	for len(b.segments) > 0 && b.segments[0].id < cursorID {
+		os.Remove(b.segments[0].path)
+		b.segments = b.segments[1:]
+	}
+	if len(b.segments) > 0 && b.segments[0].id == cursorID {
+		b.offset = cursorOffset
+	}
+
+	for i, s := range b.segments {
+		var start int64
+		if i == 0 {
+			start = b.offset
+		}
+		if err := b.scan(s, start, i == len(b.segments)-1); err != nil {
+			return err
+		}
+		b.bytes += s.size
+		b.length += s.count
+	}
+
+	if len(b.segments) == 0 {
+		return b.rotate()
+	}
+	last := b.segments[len(b.segments)-1]
+	b.w, err = os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0640)
+	return err
+}
+
+// scan counts the records of the segment from the given offset. If the
+// segment is the last one, a partial record left by a crash is truncated.
+func (b *DiskBuffer) scan(s *segment, start int64, last bool) error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	end := start
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		end += int64(len(line))
+		s.count++
+	}
+
+	if end < s.size && last {
+		log.Printf("W! Truncating partial record at the end of %s", s.path)
+		if err := os.Truncate(s.path, end); err != nil {
+			return err
+		}
+		s.size = end
+	}
+	return nil
+}
+
+func (b *DiskBuffer) readCursor() (uint64, int64) {
+	contents, err := ioutil.ReadFile(filepath.Join(b.dir, cursorFile))
+	if err != nil {
+		return 0, 0
+	}
+	var id uint64
+	var offset int64
+	if _, err := fmt.Sscanf(string(contents), "%d %d", &id, &offset); err != nil {
+		log.Printf("W! Ignoring invalid buffer cursor in %s: %s", b.dir, err)
+		return 0, 0
+	}
+	return id, offset
+}
+
+// writeCursor persists the read position, so that consumed metrics are not
+// replayed after a restart.
+func (b *DiskBuffer) writeCursor() error {
+	var id uint64
+	if len(b.segments) > 0 {
+		id = b.segments[0].id
+	}
+	tmp := filepath.Join(b.dir, cursorFile+".tmp")
+	contents := fmt.Sprintf("%d %d\n", id, b.offset)
+	if err := ioutil.WriteFile(tmp, []byte(contents), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(b.dir, cursorFile))
+}
+
+// rotate starts a new segment and makes it the write target.
+func (b *DiskBuffer) rotate() error {
+	var id uint64
+	if len(b.segments) > 0 {
+		id = b.segments[len(b.segments)-1].id + 1
+	}
+	s := &segment{
+		id:   id,
+		path: filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, segmentSuffix)),
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	if b.w != nil {
+		b.w.Close()
+	}
+	b.w = f
+	b.segments = append(b.segments, s)
+	return nil
+}
+
+// dropOldest removes the oldest segment, with all its unconsumed records.
+func (b *DiskBuffer) dropOldest() {
+	s := b.segments[0]
+	MetricsDropped.Incr(int64(s.count))
+	b.length -= s.count
+	b.bytes -= s.size
+	b.segments = b.segments[1:]
+	b.offset = 0
+	if err := os.Remove(s.path); err != nil {
+		log.Printf("E! Error removing buffer segment %s: %s", s.path, err)
+	}
+}
+
+// IsEmpty returns true if DiskBuffer is empty.
+func (b *DiskBuffer) IsEmpty() bool {
+	return b.Len() == 0
+}
+
+// Len returns the current length of the buffer.
+func (b *DiskBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.length
+}
+
+// Add appends metrics to the buffer.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var dropped bool
+	for _, m := range metrics {
+		MetricsWritten.Incr(1)
+		if b.w == nil {
+			MetricsDropped.Incr(1)
+			m.Reject()
+			continue
+		}
+		rec := encodeRecord(m)
+
+		last := b.segments[len(b.segments)-1]
+		if last.size > 0 && last.size+int64(len(rec)) > b.segmentSize {
+			if err := b.rotate(); err != nil {
+				log.Printf("E! Error creating buffer segment in %s: %s", b.dir, err)
+				MetricsDropped.Incr(1)
+				m.Reject()
+				continue
+			}
+			last = b.segments[len(b.segments)-1]
+		}
+		for b.bytes+int64(len(rec)) > b.maxBytes && len(b.segments) > 1 {
+			b.dropOldest()
+			dropped = true
+		}
+
+		n, err := b.w.Write(rec)
+		last.size += int64(n)
+		b.bytes += int64(n)
+		if err != nil {
+			log.Printf("E! Error writing to buffer segment %s: %s", last.path, err)
+			MetricsDropped.Incr(1)
+			m.Reject()
+			continue
+		}
+		last.count++
+		b.length++
+		// the metric is persisted, its delivery no longer depends on the
+		// output.
+		m.Accept()
+	}
+	if !dropped {
+		return
+	}
+	if err := b.writeCursor(); err != nil {
+		log.Printf("E! Error writing buffer cursor in %s: %s", b.dir, err)
+	}
+}
+
+// Batch returns a batch of metrics of size batchSize, removing them from the
+// buffer. The batch can be less than batchSize if the length of DiskBuffer is
+// less than batchSize.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := b.peek(batchSize)
+	b.commit()
+	return out
+}
+
+// Peek returns a batch of metrics of size batchSize from the head of the
+// buffer, without removing them. The metrics are removed by Commit, once
+// they have been written, so that a batch that could not be written is read
+// again by the next Peek, and is replayed after a restart.
+func (b *DiskBuffer) Peek(batchSize int) []telegraf.Metric {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.peek(batchSize)
+}
+
+// Commit removes the metrics returned by the last Peek from the buffer.
+func (b *DiskBuffer) Commit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.commit()
+}
+
+func (b *DiskBuffer) peek(batchSize int) []telegraf.Metric {
+	b.peeked = b.peeked[:0]
+	out := make([]telegraf.Metric, 0, min(b.length, batchSize))
+	offset, left := b.offset, b.length
+	for _, s := range b.segments {
+		if len(out) >= batchSize || left == 0 {
+			break
+		}
+		metrics, recs, read, err := readSegment(s, offset, batchSize-len(out))
+		if err != nil {
+			log.Printf("E! Error reading buffer segment %s, dropping it: %s",
+				s.path, err)
+			recs = s.count
+			read = s.size - offset
+		}
+		out = append(out, metrics...)
+		b.peeked = append(b.peeked, peekedRange{
+			s:       s,
+			recs:    recs,
+			read:    read,
+			dropped: recs - len(metrics),
+		})
+		offset, left = 0, left-recs
+	}
+	return out
+}
+
+func (b *DiskBuffer) commit() {
+	if len(b.peeked) == 0 {
+		return
+	}
+	for _, p := range b.peeked {
+		s := b.segments[0]
+		// the segment was dropped by Add after the peek.
+		if p.s != s {
+			continue
+		}
+		MetricsDropped.Incr(int64(p.dropped))
+		b.offset += p.read
+		b.length -= p.recs
+		s.count -= p.recs
+
+		if s.count == 0 && len(b.segments) > 1 {
+			b.bytes -= s.size
+			b.segments = b.segments[1:]
+			b.offset = 0
+			os.Remove(s.path)
+		}
+	}
+	b.peeked = b.peeked[:0]
+
+	if err := b.writeCursor(); err != nil {
+		log.Printf("E! Error writing buffer cursor in %s: %s", b.dir, err)
+	}
+}
+
+// readSegment reads at most n records of the segment from the given offset.
+// It returns the decoded metrics, the number of records and the number of
+// bytes read. Records that cannot be decoded are skipped.
+func readSegment(
+	s *segment,
+	offset int64,
+	n int,
+) ([]telegraf.Metric, int, int64, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, 0, 0, err
+	}
+
+	var out []telegraf.Metric
+	var recs int
+	var read int64
+	r := bufio.NewReader(f)
+	for recs < n && recs < s.count {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return out, recs, read, err
+		}
+		recs++
+		read += int64(len(line))
+
+		m, err := decodeRecord(line)
+		if err != nil {
+			log.Printf("E! Dropping invalid record in %s: %s", s.path, err)
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, recs, read, nil
+}
+
+// Close closes the file handles of the buffer. Unconsumed metrics stay on
+// disk and will be replayed by the next DiskBuffer opened on the directory.
+func (b *DiskBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w == nil {
+		return nil
+	}
+	err := b.w.Close()
+	b.w = nil
+	return err
+}
+
+func encodeRecord(m telegraf.Metric) []byte {
+	rec := make([]byte, 0, m.Len()+2)
+	rec = strconv.AppendInt(rec, int64(m.Type()), 10)
+	rec = append(rec, ' ')
+	return append(rec, m.Serialize()...)
+}
+
+func decodeRecord(line []byte) (telegraf.Metric, error) {
+	i := bytes.IndexByte(line, ' ')
+	if i < 1 {
+		return nil, fmt.Errorf("invalid record %q", line)
+	}
+	mType, err := strconv.Atoi(string(line[:i]))
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := metric.Parse(line[i+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(metrics) != 1 {
+		return nil, fmt.Errorf("invalid record %q", line)
+	}
+	m := metrics[0]
+	if telegraf.ValueType(mType) == m.Type() {
+		return m, nil
+	}
+	return metric.New(m.Name(), m.Tags(), m.Fields(), m.Time(),
+		telegraf.ValueType(mType))
+}
@@ -0,0 +1,196 @@
+package buffer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// metrics of identical serialized length, to make segment sizes predictable.
+var diskMetricList = []telegraf.Metric{
+	testutil.TestMetric(1, "mymetric1"),
+	testutil.TestMetric(2, "mymetric2"),
+	testutil.TestMetric(3, "mymetric3"),
+	testutil.TestMetric(4, "mymetric4"),
+	testutil.TestMetric(5, "mymetric5"),
+}
+
+func newTestDiskBuffer(t *testing.T, maxBytes, segmentSize int64) (*DiskBuffer, string) {
+	dir, err := ioutil.TempDir("", "telegraf-buffer")
+	require.NoError(t, err)
+	b, err := NewDiskBuffer(dir, maxBytes, segmentSize)
+	require.NoError(t, err)
+	return b, dir
+}
+
+func TestDiskBufferBasicFuncs(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	assert.True(t, b.IsEmpty())
+	assert.Zero(t, b.Len())
+
+	b.Add(metricList...)
+	assert.False(t, b.IsEmpty())
+	assert.Equal(t, 5, b.Len())
+
+	batch := b.Batch(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, metricList[0].String(), batch[0].String())
+	assert.Equal(t, metricList[1].String(), batch[1].String())
+	assert.Equal(t, 3, b.Len())
+
+	batch = b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, metricList[4].String(), batch[2].String())
+	assert.True(t, b.IsEmpty())
+}
+
+func TestDiskBufferKeepsValueType(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	b.Add(testutil.TestMetric(1, "untyped"))
+	m, err := metric.New("counter",
+		map[string]string{"tag1": "value1"},
+		map[string]interface{}{"value": int64(1)},
+		time.Now(),
+		telegraf.Counter,
+	)
+	require.NoError(t, err)
+	b.Add(m)
+
+	batch := b.Batch(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, telegraf.Untyped, batch[0].Type())
+	assert.Equal(t, telegraf.Counter, batch[1].Type())
+	assert.Equal(t, m.String(), batch[1].String())
+}
+
+func TestDiskBufferReplayAfterReopen(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+
+	b.Add(metricList...)
+	b.Batch(1)
+	require.NoError(t, b.Close())
+
+	b, err := NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.Equal(t, 4, b.Len())
+	batch := b.Batch(10)
+	require.Len(t, batch, 4)
+	for i, m := range batch {
+		assert.Equal(t, metricList[i+1].String(), m.String())
+	}
+}
+
+func TestDiskBufferPeekCommit(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+
+	b.Add(metricList...)
+	batch := b.Peek(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, metricList[0].String(), batch[0].String())
+	assert.Equal(t, 5, b.Len())
+
+	// until committed, the same batch is returned
+	batch = b.Peek(2)
+	require.Len(t, batch, 2)
+	assert.Equal(t, metricList[0].String(), batch[0].String())
+
+	b.Commit()
+	assert.Equal(t, 3, b.Len())
+
+	// a batch that is not committed is replayed after a restart
+	b.Peek(2)
+	require.NoError(t, b.Close())
+	b, err := NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+	defer b.Close()
+
+	assert.Equal(t, 3, b.Len())
+	batch = b.Batch(10)
+	require.Len(t, batch, 3)
+	assert.Equal(t, metricList[2].String(), batch[0].String())
+}
+
+func TestDiskBufferSegments(t *testing.T) {
+	size := int64(len(encodeRecord(diskMetricList[0])))
+	// room for two records per segment
+	b, dir := newTestDiskBuffer(t, 100*size, 2*size)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+
+	b.Add(diskMetricList...)
+	segments, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	assert.Len(t, segments, 3)
+
+	batch := b.Batch(5)
+	require.Len(t, batch, 5)
+	for i, m := range batch {
+		assert.Equal(t, diskMetricList[i].String(), m.String())
+	}
+
+	// consumed segments are removed
+	segments, err = filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	require.NoError(t, err)
+	assert.Len(t, segments, 1)
+}
+
+func TestDiskBufferDropsOldestSegment(t *testing.T) {
+	size := int64(len(encodeRecord(diskMetricList[0])))
+	b, dir := newTestDiskBuffer(t, 4*size, 2*size)
+	defer os.RemoveAll(dir)
+	defer b.Close()
+	MetricsDropped.Set(0)
+
+	b.Add(diskMetricList...)
+	assert.Equal(t, 3, b.Len())
+	assert.Equal(t, int64(2), MetricsDropped.Get())
+
+	batch := b.Batch(5)
+	require.Len(t, batch, 3)
+	assert.Equal(t, diskMetricList[2].String(), batch[0].String())
+}
+
+func TestDiskBufferTruncatesPartialRecord(t *testing.T) {
+	b, dir := newTestDiskBuffer(t, 0, 0)
+	defer os.RemoveAll(dir)
+
+	b.Add(metricList[:2]...)
+	require.NoError(t, b.Close())
+
+	// simulate a crash in the middle of a write
+	segment := filepath.Join(dir, "00000000000000000000"+segmentSuffix)
+	f, err := os.OpenFile(segment, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte("0 mymetric,hos"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	b, err = NewDiskBuffer(dir, 0, 0)
+	require.NoError(t, err)
+	defer b.Close()
+	assert.Equal(t, 2, b.Len())
+
+	b.Add(metricList[2])
+	batch := b.Batch(5)
+	require.Len(t, batch, 3)
+	assert.Equal(t, metricList[2].String(), batch[2].String())
+}
@@ -19,6 +19,8 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/models"
+	"github.com/influxdata/telegraf/internal/retry"
+	"github.com/influxdata/telegraf/internal/schedule"
 	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -53,9 +55,14 @@ type Config struct {
 	Agent       *AgentConfig
 	Inputs      []*models.RunningInput
 	Outputs     []*models.RunningOutput
-	Aggregators []*models.RunningAggregator
+	Aggregators models.RunningAggregators
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors models.RunningProcessors
+
+	// keyring is opened on the first @{keyring:<name>} reference
+	keyring *Keyring
+	// secretValues are the settings values holding resolved secrets
+	secretValues map[string]bool
 }
 
 func NewConfig() *Config {
@@ -65,6 +72,9 @@ func NewConfig() *Config {
 			Interval:      internal.Duration{Duration: 10 * time.Second},
 			RoundInterval: true,
 			FlushInterval: internal.Duration{Duration: 10 * time.Second},
+
+			SeriesLimitWindow: internal.Duration{Duration: time.Hour},
+			SeriesLimitAction: models.SeriesLimitDrop,
 		},
 
 		Tags:          make(map[string]string),
@@ -73,6 +83,7 @@ func NewConfig() *Config {
 		Processors:    make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+		secretValues:  make(map[string]bool),
 	}
 	return c
 }
@@ -139,6 +150,28 @@ type AgentConfig struct {
 	Quiet        bool
 	Hostname     string
 	OmitHostname bool
+
+	// APIAddress is the address the HTTP management API listens on. The API
+	// is disabled when it is empty. It has no authentication, so it must be
+	// bound to localhost.
+	APIAddress string `toml:"api_address"`
+
+	// SecretKeyring is the encrypted keyring file resolving the
+	// @{keyring:<name>} references of the plugin settings. Its password is
+	// read from SecretKeyringPasswordFile or, if unset, from the
+	// TELEGRAF_KEYRING_PASSWORD environment variable.
+	SecretKeyring             string `toml:"secret_keyring"`
+	SecretKeyringPasswordFile string `toml:"secret_keyring_password_file"`
+
+	// SeriesLimit is the maximum number of series of each measurement seen
+	// over SeriesLimitWindow, 0 for no limit. SeriesLimits overrides it for
+	// the measurements it holds.
+	SeriesLimit       int               `toml:"series_limit"`
+	SeriesLimits      map[string]int    `toml:"series_limits"`
+	SeriesLimitWindow internal.Duration `toml:"series_limit_window"`
+	// SeriesLimitAction is the action on the metrics of the new series over
+	// the limit, either models.SeriesLimitDrop or models.SeriesLimitCollapse.
+	SeriesLimitAction string `toml:"series_limit_action"`
 }
 
 // Inputs returns a list of strings of the configured inputs.
@@ -252,6 +285,28 @@ var header = `# Telegraf Configuration
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
 
+  ## Address of the HTTP management API, disabled if empty. The API has no
+  ## authentication, bind it to localhost only.
+  # api_address = "localhost:8091"
+
+  ## Encrypted keyring resolving the @{keyring:<name>} secrets of the plugin
+  ## settings, managed with "telegraf secrets". Its password is read from
+  ## the password file or the TELEGRAF_KEYRING_PASSWORD environment variable.
+  # secret_keyring = "/etc/telegraf/secrets.keyring"
+  # secret_keyring_password_file = "/etc/telegraf/secrets.password"
+
+  ## Limit the number of series of each measurement seen over the window,
+  ## 0 for no limit. The metrics of the new series over the limit are either
+  ## dropped, or with the "collapse" action, their tags with new values are
+  ## set to "other". The top offending tags are logged, and reported by the
+  ## internal input.
+  # series_limit = 0
+  # series_limit_window = "1h"
+  # series_limit_action = "drop"
+  ## The limits of specific measurements, replacing series_limit.
+  # [agent.series_limits]
+  #   statsd_timing = 1000
+
 
 ###############################################################################
 #                            OUTPUT PLUGINS                                   #
@@ -553,6 +608,32 @@ func getDefaultConfigPath() (string, error) {
 		" in $TELEGRAF_CONFIG_PATH, %s, or %s", homefile, etcfile)
 }
 
+// LoadAgentConfig loads the [agent] table of the given config file only,
+// without building the plugins nor resolving their secrets.
+func (c *Config) LoadAgentConfig(path string) error {
+	var err error
+	if path == "" {
+		if path, err = getDefaultConfigPath(); err != nil {
+			return err
+		}
+	}
+	tbl, err := parseFile(path)
+	if err != nil {
+		return fmt.Errorf("Error parsing %s, %s", path, err)
+	}
+
+	if val, ok := tbl.Fields["agent"]; ok {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return fmt.Errorf("%s: invalid configuration", path)
+		}
+		if err = toml.UnmarshalTable(subTable, c.Agent); err != nil {
+			return fmt.Errorf("Error parsing %s, %s", path, err)
+		}
+	}
+	return nil
+}
+
 // LoadConfig loads the given config file and applies it to c
 func (c *Config) LoadConfig(path string) error {
 	var err error
@@ -590,6 +671,12 @@ func (c *Config) LoadConfig(path string) error {
 			log.Printf("E! Could not parse [agent] config\n")
 			return fmt.Errorf("Error parsing %s, %s", path, err)
 		}
+		switch c.Agent.SeriesLimitAction {
+		case models.SeriesLimitDrop, models.SeriesLimitCollapse:
+		default:
+			return fmt.Errorf("Error parsing %s, invalid series_limit_action %q",
+				path, c.Agent.SeriesLimitAction)
+		}
 	}
 
 	// Parse all the rest of the plugins:
@@ -602,69 +689,43 @@ func (c *Config) LoadConfig(path string) error {
 		switch name {
 		case "agent", "global_tags", "tags":
 		case "outputs":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				// legacy [outputs.influxdb] support
-				case *ast.Table:
-					if err = c.addOutput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addOutput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
+			tables, err := pluginTables(subTable, true)
+			if err != nil {
+				return fmt.Errorf("%s, file %s", err, path)
+			}
+			for _, t := range tables {
+				if err = c.addOutput(t.name, t.table); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
 				}
 			}
 		case "inputs", "plugins":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				// legacy [inputs.cpu] support
-				case *ast.Table:
-					if err = c.addInput(pluginName, pluginSubTable); err != nil {
-						return fmt.Errorf("Error parsing %s, %s", path, err)
-					}
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addInput(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
+			tables, err := pluginTables(subTable, true)
+			if err != nil {
+				return fmt.Errorf("%s, file %s", err, path)
+			}
+			for _, t := range tables {
+				if err = c.addInput(t.name, t.table); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
 				}
 			}
 		case "processors":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addProcessor(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
+			tables, err := pluginTables(subTable, false)
+			if err != nil {
+				return fmt.Errorf("%s, file %s", err, path)
+			}
+			for _, t := range tables {
+				if err = c.addProcessor(t.name, t.table); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
 				}
 			}
 		case "aggregators":
-			for pluginName, pluginVal := range subTable.Fields {
-				switch pluginSubTable := pluginVal.(type) {
-				case []*ast.Table:
-					for _, t := range pluginSubTable {
-						if err = c.addAggregator(pluginName, t); err != nil {
-							return fmt.Errorf("Error parsing %s, %s", path, err)
-						}
-					}
-				default:
-					return fmt.Errorf("Unsupported config format: %s, file %s",
-						pluginName, path)
+			tables, err := pluginTables(subTable, false)
+			if err != nil {
+				return fmt.Errorf("%s, file %s", err, path)
+			}
+			for _, t := range tables {
+				if err = c.addAggregator(t.name, t.table); err != nil {
+					return fmt.Errorf("Error parsing %s, %s", path, err)
 				}
 			}
 		// Assume it's an input input for legacy config file support if no other
@@ -676,12 +737,49 @@ func (c *Config) LoadConfig(path string) error {
 		}
 	}
 
-	if len(c.Processors) > 1 {
-		sort.Sort(c.Processors)
-	}
+	// The sorts are stable so that plugins of the same order keep the order
+	// in which they are defined, files being loaded in lexical order.
+	sort.Stable(c.Processors)
+	sort.Stable(c.Aggregators)
 	return nil
 }
 
+// pluginTable is the table of a plugin in a config file.
+type pluginTable struct {
+	name  string
+	table *ast.Table
+}
+
+// pluginTables returns the plugin tables of the given section, such as
+// [[inputs.cpu]], in the order they are defined in the file. Single tables,
+// such as the legacy [inputs.cpu], are only supported if single is true.
+func pluginTables(section *ast.Table, single bool) ([]pluginTable, error) {
+	var tables []pluginTable
+	for name, val := range section.Fields {
+		switch t := val.(type) {
+		case *ast.Table:
+			if !single {
+				return nil, fmt.Errorf("Unsupported config format: %s", name)
+			}
+			tables = append(tables, pluginTable{name: name, table: t})
+		case []*ast.Table:
+			for _, tbl := range t {
+				tables = append(tables, pluginTable{name: name, table: tbl})
+			}
+		default:
+			return nil, fmt.Errorf("Unsupported config format: %s", name)
+		}
+	}
+
+	sort.SliceStable(tables, func(i, j int) bool {
+		if tables[i].table.Line != tables[j].table.Line {
+			return tables[i].table.Line < tables[j].table.Line
+		}
+		return tables[i].name < tables[j].name
+	})
+	return tables, nil
+}
+
 // trimBOM trims the Byte-Order-Marks from the beginning of the file.
 // this is for Windows compatability only.
 // see https://github.com/influxdata/telegraf/issues/1378
@@ -718,10 +816,16 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	}
 	aggregator := creator()
 
+	secrets, err := c.resolveSecrets(table)
+	if err != nil {
+		return err
+	}
+	checksum := tableChecksum(name, table, secrets)
 	conf, err := buildAggregator(name, table)
 	if err != nil {
 		return err
 	}
+	conf.Checksum = checksum
 
 	if err := toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
@@ -738,10 +842,16 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	}
 	processor := creator()
 
+	secrets, err := c.resolveSecrets(table)
+	if err != nil {
+		return err
+	}
+	checksum := tableChecksum(name, table, secrets)
 	processorConfig, err := buildProcessor(name, table)
 	if err != nil {
 		return err
 	}
+	processorConfig.Checksum = checksum
 
 	if err := toml.UnmarshalTable(table, processor); err != nil {
 		return err
@@ -767,6 +877,14 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	}
 	output := creator()
 
+	// the buffer settings of the agent are part of the output's configuration
+	secrets, err := c.resolveSecrets(table)
+	if err != nil {
+		return err
+	}
+	checksum := tableChecksum(name, table, secrets,
+		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
 	switch t := output.(type) {
@@ -782,6 +900,7 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	outputConfig.Checksum = checksum
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
@@ -808,6 +927,12 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	}
 	input := creator()
 
+	secrets, err := c.resolveSecrets(table)
+	if err != nil {
+		return err
+	}
+	checksum := tableChecksum(name, table, secrets)
+
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
 	switch t := input.(type) {
@@ -823,6 +948,7 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	pluginConfig.Checksum = checksum
 
 	if err := toml.UnmarshalTable(table, input); err != nil {
 		return err
@@ -877,6 +1003,18 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	if node, ok := tbl.Fields["order"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Integer); ok {
+				var err error
+				conf.Order, err = strconv.ParseInt(b.Value, 10, 64)
+				if err != nil {
+					log.Printf("Error parsing int value for %s: %s\n", name, err)
+				}
+			}
+		}
+	}
+
 	if node, ok := tbl.Fields["drop_original"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -922,8 +1060,11 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		}
 	}
 
+	conf.Routes = buildRoutes(tbl)
+
 	delete(tbl.Fields, "period")
 	delete(tbl.Fields, "delay")
+	delete(tbl.Fields, "order")
 	delete(tbl.Fields, "drop_original")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
@@ -971,6 +1112,25 @@ func buildProcessor(name string, tbl *ast.Table) (*models.ProcessorConfig, error
 	return conf, nil
 }
 
+// buildRoutes returns the routes of an input, aggregator or output, and
+// removes them from the table.
+func buildRoutes(tbl *ast.Table) []string {
+	var routes []string
+	if node, ok := tbl.Fields["routes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						routes = append(routes, str.Value)
+					}
+				}
+			}
+		}
+	}
+	delete(tbl.Fields, "routes")
+	return routes
+}
+
 // buildFilter builds a Filter
 // (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
 // be inserted into the models.OutputConfig/models.InputConfig
@@ -1159,6 +1319,89 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["schedule"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				sched, err := schedule.ParseCron(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.Schedule = sched
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["round_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				cp.RoundInterval, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["collection_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				dur, err := time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.CollectionJitter = dur
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["run_once"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if b, ok := kv.Value.(*ast.Boolean); ok {
+				var err error
+				cp.RunOnce, err = strconv.ParseBool(b.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	cp.Routes = buildRoutes(tbl)
+
+	if node, ok := tbl.Fields["metric_queue_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				size, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				cp.QueueSize = size
+			}
+		}
+	}
+
+	cp.QueueOverflow = models.OverflowBlock
+	if node, ok := tbl.Fields["metric_queue_overflow"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.QueueOverflow = str.Value
+			}
+		}
+	}
+	switch cp.QueueOverflow {
+	case models.OverflowBlock, models.OverflowDropOldest, models.OverflowDropNewest:
+	default:
+		return nil, fmt.Errorf("invalid metric_queue_overflow %q for input %s",
+			cp.QueueOverflow, name)
+	}
+
+	delete(tbl.Fields, "schedule")
+	delete(tbl.Fields, "round_interval")
+	delete(tbl.Fields, "collection_jitter")
+	delete(tbl.Fields, "run_once")
+	delete(tbl.Fields, "metric_queue_size")
+	delete(tbl.Fields, "metric_queue_overflow")
 	delete(tbl.Fields, "name_prefix")
 	delete(tbl.Fields, "name_suffix")
 	delete(tbl.Fields, "name_override")
@@ -1344,6 +1587,13 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	oc := &models.OutputConfig{
 		Name:   name,
 		Filter: filter,
+		Retry: retry.Policy{
+			InitialInterval: 15 * time.Second,
+			MaxInterval:     10 * time.Minute,
+			Multiplier:      2,
+			Jitter:          0.25,
+			Threshold:       3,
+		},
 	}
 	// Outputs don't support FieldDrop/FieldPass, so set to NameDrop/NamePass
 	if len(oc.Filter.FieldDrop) > 0 {
@@ -1352,5 +1602,109 @@ func buildOutput(name string, tbl *ast.Table) (*models.OutputConfig, error) {
 	if len(oc.Filter.FieldPass) > 0 {
 		oc.Filter.NamePass = oc.Filter.FieldPass
 	}
+
+	if node, ok := tbl.Fields["metric_buffer_dir"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.BufferDirectory = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_buffer_max_bytes"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.BufferMaxBytes, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["metric_buffer_segment_size"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				oc.BufferSegmentSize, err = strconv.ParseInt(integer.Value, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_initial_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Retry.InitialInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_max_interval"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				oc.Retry.MaxInterval, err = time.ParseDuration(str.Value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_multiplier"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			oc.Retry.Multiplier, err = floatValue("retry_multiplier", kv.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["retry_jitter"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			oc.Retry.Jitter, err = floatValue("retry_jitter", kv.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["circuit_breaker_threshold"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if integer, ok := kv.Value.(*ast.Integer); ok {
+				threshold, err := strconv.Atoi(integer.Value)
+				if err != nil {
+					return nil, err
+				}
+				oc.Retry.Threshold = threshold
+			}
+		}
+	}
+
+	oc.Routes = buildRoutes(tbl)
+
+	delete(tbl.Fields, "metric_buffer_dir")
+	delete(tbl.Fields, "metric_buffer_max_bytes")
+	delete(tbl.Fields, "metric_buffer_segment_size")
+	delete(tbl.Fields, "retry_initial_interval")
+	delete(tbl.Fields, "retry_max_interval")
+	delete(tbl.Fields, "retry_multiplier")
+	delete(tbl.Fields, "retry_jitter")
+	delete(tbl.Fields, "circuit_breaker_threshold")
 	return oc, nil
 }
+
+// floatValue returns the value of a setting that is a float, or an integer.
+func floatValue(name string, value ast.Value) (float64, error) {
+	switch v := value.(type) {
+	case *ast.Float:
+		return strconv.ParseFloat(v.Value, 64)
+	case *ast.Integer:
+		return strconv.ParseFloat(v.Value, 64)
+	}
+	return 0, fmt.Errorf("%s must be a number, got %s", name, value.Source())
+}
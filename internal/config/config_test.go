@@ -1,17 +1,21 @@
 package config
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf/internal/models"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/minmax"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/inputs/exec"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	_ "github.com/influxdata/telegraf/plugins/processors/printer"
 
+	"github.com/influxdata/toml"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -51,9 +55,11 @@ func TestConfig_LoadSingleInputWithEnvVars(t *testing.T) {
 		Interval: 10 * time.Second,
 	}
 	mConfig.Tags = make(map[string]string)
+	mConfig.QueueOverflow = models.OverflowBlock
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.Checksum = c.Inputs[0].Config.Checksum
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 }
@@ -90,9 +96,11 @@ func TestConfig_LoadSingleInput(t *testing.T) {
 		Interval: 5 * time.Second,
 	}
 	mConfig.Tags = make(map[string]string)
+	mConfig.QueueOverflow = models.OverflowBlock
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.Checksum = c.Inputs[0].Config.Checksum
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 }
@@ -136,9 +144,11 @@ func TestConfig_LoadDirectory(t *testing.T) {
 		Interval: 5 * time.Second,
 	}
 	mConfig.Tags = make(map[string]string)
+	mConfig.QueueOverflow = models.OverflowBlock
 
 	assert.Equal(t, memcached, c.Inputs[0].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.Checksum = c.Inputs[0].Config.Checksum
 	assert.Equal(t, mConfig, c.Inputs[0].Config,
 		"Testdata did not produce correct memcached metadata.")
 
@@ -152,14 +162,17 @@ func TestConfig_LoadDirectory(t *testing.T) {
 		MeasurementSuffix: "_myothercollector",
 	}
 	eConfig.Tags = make(map[string]string)
+	eConfig.QueueOverflow = models.OverflowBlock
 	assert.Equal(t, ex, c.Inputs[1].Input,
 		"Merged Testdata did not produce a correct exec struct.")
+	eConfig.Checksum = c.Inputs[1].Config.Checksum
 	assert.Equal(t, eConfig, c.Inputs[1].Config,
 		"Merged Testdata did not produce correct exec metadata.")
 
 	memcached.Servers = []string{"192.168.1.1"}
 	assert.Equal(t, memcached, c.Inputs[2].Input,
 		"Testdata did not produce a correct memcached struct.")
+	mConfig.Checksum = c.Inputs[2].Config.Checksum
 	assert.Equal(t, mConfig, c.Inputs[2].Config,
 		"Testdata did not produce correct memcached metadata.")
 
@@ -168,9 +181,116 @@ func TestConfig_LoadDirectory(t *testing.T) {
 
 	pConfig := &models.InputConfig{Name: "procstat"}
 	pConfig.Tags = make(map[string]string)
+	pConfig.QueueOverflow = models.OverflowBlock
 
 	assert.Equal(t, pstat, c.Inputs[3].Input,
 		"Merged Testdata did not produce a correct procstat struct.")
+	pConfig.Checksum = c.Inputs[3].Config.Checksum
 	assert.Equal(t, pConfig, c.Inputs[3].Config,
 		"Merged Testdata did not produce correct procstat metadata.")
 }
+
+func TestConfig_Checksum(t *testing.T) {
+	c1 := NewConfig()
+	assert.NoError(t, c1.LoadConfig("./testdata/single_plugin.toml"))
+	c2 := NewConfig()
+	assert.NoError(t, c2.LoadConfig("./testdata/single_plugin.toml"))
+	c3 := NewConfig()
+	assert.NoError(t, c3.LoadConfig("./testdata/subconfig/memcached.conf"))
+
+	assert.NotZero(t, c1.Inputs[0].Config.Checksum)
+	assert.Equal(t, c1.Inputs[0].Config.Checksum, c2.Inputs[0].Config.Checksum,
+		"Unchanged configuration should have the same checksum")
+	assert.NotEqual(t, c1.Inputs[0].Config.Checksum, c3.Inputs[0].Config.Checksum,
+		"Different configurations should have different checksums")
+}
+
+func TestConfig_PluginOrder(t *testing.T) {
+	c := NewConfig()
+	assert.NoError(t, c.LoadDirectory("./testdata/ordered"))
+
+	var processors []string
+	for _, p := range c.Processors {
+		processors = append(processors, p.Config.Filter.NamePass[0])
+	}
+	assert.Equal(t, []string{"b", "d", "c", "a"}, processors)
+
+	var aggregators []string
+	for _, a := range c.Aggregators {
+		aggregators = append(aggregators, a.Config.Filter.NamePass[0])
+	}
+	assert.Equal(t, []string{"b", "a"}, aggregators)
+	assert.Equal(t, []string{"archive", "kafka"}, c.Aggregators[0].Config.Routes)
+	assert.Len(t, c.Aggregators[1].Config.Routes, 0)
+}
+
+func TestConfig_InputSchedule(t *testing.T) {
+	tbl, err := toml.Parse([]byte(`
+schedule = "*/5 * * * *"
+round_interval = true
+collection_jitter = "3s"
+run_once = true
+`))
+	assert.NoError(t, err)
+
+	conf, err := buildInput("cpu", tbl)
+	assert.NoError(t, err)
+	assert.NotNil(t, conf.Schedule)
+	assert.True(t, conf.RoundInterval)
+	assert.Equal(t, 3*time.Second, conf.CollectionJitter)
+	assert.True(t, conf.RunOnce)
+	assert.Len(t, tbl.Fields, 0)
+
+	tbl, err = toml.Parse([]byte(`schedule = "*/5 * *"`))
+	assert.NoError(t, err)
+	_, err = buildInput("cpu", tbl)
+	assert.Error(t, err)
+}
+
+func TestConfig_OutputRetry(t *testing.T) {
+	tbl, err := toml.Parse([]byte(``))
+	assert.NoError(t, err)
+	conf, err := buildOutput("file", tbl)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, conf.Retry.Threshold)
+	assert.Equal(t, 2.0, conf.Retry.Multiplier)
+
+	tbl, err = toml.Parse([]byte(`
+retry_multiplier = 3
+retry_jitter = 0.1
+circuit_breaker_threshold = 0
+`))
+	assert.NoError(t, err)
+	conf, err = buildOutput("file", tbl)
+	assert.NoError(t, err)
+	assert.Equal(t, 3.0, conf.Retry.Multiplier)
+	assert.Equal(t, 0.1, conf.Retry.Jitter)
+	assert.Equal(t, 0, conf.Retry.Threshold)
+	assert.Len(t, tbl.Fields, 0)
+
+	tbl, err = toml.Parse([]byte(`retry_multiplier = "3"`))
+	assert.NoError(t, err)
+	_, err = buildOutput("file", tbl)
+	assert.Error(t, err)
+}
+
+func TestConfig_SeriesLimits(t *testing.T) {
+	c := NewConfig()
+	assert.Equal(t, time.Hour, c.Agent.SeriesLimitWindow.Duration)
+	assert.Equal(t, models.SeriesLimitDrop, c.Agent.SeriesLimitAction)
+
+	assert.NoError(t, c.LoadConfig("./testdata/series_limits.toml"))
+	assert.Equal(t, 1000, c.Agent.SeriesLimit)
+	assert.Equal(t, 30*time.Minute, c.Agent.SeriesLimitWindow.Duration)
+	assert.Equal(t, models.SeriesLimitCollapse, c.Agent.SeriesLimitAction)
+	assert.Equal(t, map[string]int{"statsd_timing": 100, "docker": 0},
+		c.Agent.SeriesLimits)
+
+	f, err := ioutil.TempFile("", "telegraf")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString("[agent]\n  series_limit_action = \"truncate\"\n")
+	assert.NoError(t, err)
+	f.Close()
+	assert.Error(t, NewConfig().LoadConfig(f.Name()))
+}
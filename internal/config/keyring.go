@@ -0,0 +1,185 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// KeyringPasswordEnv is the environment variable holding the password of the
+// keyring when no password file is configured.
+const KeyringPasswordEnv = "TELEGRAF_KEYRING_PASSWORD"
+
+// keyringVersion is the version of the keyring file format.
+const keyringVersion = 1
+
+var errKeyringPassword = errors.New("wrong keyring password or corrupted keyring")
+
+// keyringFile is the on-disk format of a keyring. The secrets are encrypted
+// with AES-256-GCM, with a key derived from the password by scrypt.
+type keyringFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// Keyring is a file of secrets encrypted with a password. Its secrets are
+// referenced in the configuration as @{keyring:<name>}.
+type Keyring struct {
+	path     string
+	password []byte
+	secrets  map[string]string
+}
+
+// OpenKeyring opens the keyring at path with the given password. A missing
+// keyring file is opened as an empty keyring, which is created on Save.
+func OpenKeyring(path string, password []byte) (*Keyring, error) {
+	k := &Keyring{
+		path:     path,
+		password: password,
+		secrets:  make(map[string]string),
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f keyringFile
+	if err := json.Unmarshal(contents, &f); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %s", path, err)
+	}
+	if f.Version != keyringVersion {
+		return nil, fmt.Errorf("unsupported keyring version %d in %s",
+			f.Version, path)
+	}
+
+	gcm, err := keyringCipher(password, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	data, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, errKeyringPassword
+	}
+	if err := json.Unmarshal(data, &k.secrets); err != nil {
+		return nil, fmt.Errorf("invalid keyring %s: %s", path, err)
+	}
+	return k, nil
+}
+
+// keyringPassword returns the password of the keyring, read from the given
+// file or, if empty, from the KeyringPasswordEnv environment variable.
+func keyringPassword(passwordFile string) ([]byte, error) {
+	if passwordFile != "" {
+		password, err := ioutil.ReadFile(passwordFile)
+		if err != nil {
+			return nil, err
+		}
+		return trimNewline(password), nil
+	}
+	if password := os.Getenv(KeyringPasswordEnv); password != "" {
+		return []byte(password), nil
+	}
+	return nil, fmt.Errorf("no keyring password, set %s or "+
+		"secret_keyring_password_file", KeyringPasswordEnv)
+}
+
+func keyringCipher(password, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(password, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Get returns the secret of the given name.
+func (k *Keyring) Get(name string) (string, bool) {
+	s, ok := k.secrets[name]
+	return s, ok
+}
+
+// Set sets the secret of the given name. It is written by Save.
+func (k *Keyring) Set(name, secret string) {
+	k.secrets[name] = secret
+}
+
+// Delete removes the secret of the given name. It is written by Save.
+func (k *Keyring) Delete(name string) {
+	delete(k.secrets, name)
+}
+
+// Names returns the sorted names of the secrets of the keyring.
+func (k *Keyring) Names() []string {
+	names := make([]string, 0, len(k.secrets))
+	for name := range k.secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save encrypts the keyring and writes it to its file, readable by its owner
+// only. The file is replaced atomically.
+func (k *Keyring) Save() error {
+	data, err := json.Marshal(k.secrets)
+	if err != nil {
+		return err
+	}
+
+	f := keyringFile{
+		Version: keyringVersion,
+		Salt:    make([]byte, 32),
+	}
+	if _, err := io.ReadFull(rand.Reader, f.Salt); err != nil {
+		return err
+	}
+	gcm, err := keyringCipher(k.password, f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, data, nil)
+
+	contents, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(k.path), ".keyring")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.path)
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// tableChecksum returns a checksum of the plugin name and the contents of
+// its table, plus any extra settings the plugin depends on. It must be
+// called before the table is consumed by the build functions.
+func tableChecksum(name string, tbl *ast.Table, extra ...interface{}) uint64 {
+	h := fnv.New64a()
+	io.WriteString(h, name)
+	writeTable(h, tbl)
+	for _, e := range extra {
+		fmt.Fprintf(h, "\x00%v", e)
+	}
+	return h.Sum64()
+}
+
+func writeTable(h hash.Hash64, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		io.WriteString(h, "\x00"+k+"=")
+		switch v := tbl.Fields[k].(type) {
+		case *ast.KeyValue:
+			io.WriteString(h, v.Value.Source())
+		case *ast.Table:
+			io.WriteString(h, "{")
+			writeTable(h, v)
+			io.WriteString(h, "}")
+		case []*ast.Table:
+			for _, t := range v {
+				io.WriteString(h, "[")
+				writeTable(h, t)
+				io.WriteString(h, "]")
+			}
+		}
+	}
+}
+
+// Watch polls the config file and the *.conf files of the config directory
+// every interval, and sends on changed when any of them is created, removed
+// or modified. It returns when stop is closed.
+func Watch(
+	path string,
+	directory string,
+	interval time.Duration,
+	changed chan<- struct{},
+	stop <-chan struct{},
+) {
+	last := filesSignature(path, directory)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			sig := filesSignature(path, directory)
+			if sig == last {
+				continue
+			}
+			last = sig
+			select {
+			case changed <- struct{}{}:
+			default:
+				// a reload is already pending
+			}
+		}
+	}
+}
+
+// filesSignature returns a string that changes whenever the config files
+// change.
+func filesSignature(path string, directory string) string {
+	var sig string
+	add := func(p string) {
+		info, err := os.Stat(p)
+		if err != nil {
+			sig += fmt.Sprintf("%s:missing;", p)
+			return
+		}
+		sig += fmt.Sprintf("%s:%d:%d;", p, info.Size(), info.ModTime().UnixNano())
+	}
+
+	if path != "" {
+		add(path)
+	}
+	if directory != "" {
+		err := filepath.Walk(directory, func(p string, info os.FileInfo, _ error) error {
+			if info == nil || info.IsDir() {
+				return nil
+			}
+			if filepath.Ext(p) == ".conf" {
+				add(p)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("E! Error watching config directory %s: %s", directory, err)
+		}
+	}
+	return sig
+}
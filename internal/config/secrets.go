@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/toml/ast"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// secretRe matches the references to secrets in the config, such as
+// @{file:/etc/telegraf/mysql.pass}.
+var secretRe = regexp.MustCompile(`@\{(\w+):([^}]*)\}`)
+
+// secretExecTimeout bounds the time a command resolving a secret may take.
+const secretExecTimeout = 10 * time.Second
+
+// SecretProvider resolves the secrets of a kind of reference. The argument
+// is the text following the colon, ie. the path of @{file:<path>}.
+type SecretProvider interface {
+	Resolve(arg string) (string, error)
+}
+
+// SecretProviderFunc is a function used as a SecretProvider.
+type SecretProviderFunc func(arg string) (string, error)
+
+func (f SecretProviderFunc) Resolve(arg string) (string, error) {
+	return f(arg)
+}
+
+// SecretProviders are the providers of @{<provider>:<arg>} references, keyed
+// by provider. The keyring provider depends on the agent settings and is
+// handled by the Config itself.
+var SecretProviders = map[string]SecretProvider{
+	"file": SecretProviderFunc(fileSecret),
+	"exec": SecretProviderFunc(execSecret),
+}
+
+// fileSecret returns the contents of a file, without its trailing newline.
+func fileSecret(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(trimNewline(contents)), nil
+}
+
+// execSecret returns the output of a command, without its trailing newline.
+func execSecret(command string) (string, error) {
+	args, err := shellquote.Split(command)
+	if err != nil || len(args) == 0 {
+		return "", fmt.Errorf("unable to parse command %q", command)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := internal.RunTimeout(cmd, secretExecTimeout); err != nil {
+		return "", fmt.Errorf("command %q: %s", command, err)
+	}
+	return string(trimNewline(out.Bytes())), nil
+}
+
+func trimNewline(b []byte) []byte {
+	return bytes.TrimRight(b, "\r\n")
+}
+
+// Keyring opens the keyring of the agent settings. It is opened once and
+// kept for the next calls.
+func (c *Config) Keyring() (*Keyring, error) {
+	if c.keyring != nil {
+		return c.keyring, nil
+	}
+	if c.Agent.SecretKeyring == "" {
+		return nil, fmt.Errorf("no secret_keyring configured")
+	}
+	password, err := keyringPassword(c.Agent.SecretKeyringPasswordFile)
+	if err != nil {
+		return nil, err
+	}
+	k, err := OpenKeyring(c.Agent.SecretKeyring, password)
+	if err != nil {
+		return nil, err
+	}
+	c.keyring = k
+	return k, nil
+}
+
+// secret resolves the secret of the given provider.
+func (c *Config) secret(provider, arg string) (string, error) {
+	if provider == "keyring" {
+		k, err := c.Keyring()
+		if err != nil {
+			return "", err
+		}
+		s, ok := k.Get(arg)
+		if !ok {
+			return "", fmt.Errorf("no secret %q in keyring %s",
+				arg, c.Agent.SecretKeyring)
+		}
+		return s, nil
+	}
+
+	p, ok := SecretProviders[provider]
+	if !ok {
+		return "", fmt.Errorf("unknown secret provider %q", provider)
+	}
+	return p.Resolve(arg)
+}
+
+// resolveSecrets replaces the references to secrets in the string values of
+// a plugin table by the secrets. Only the values given to the plugin change:
+// the source of the table keeps the references, so that the secrets never
+// end up in the logs. The values holding secrets are recorded, to redact them
+// from the dumped settings of the plugins.
+//
+// It returns a checksum of the resolved values, which the checksum of the
+// plugin includes so that the plugin is reloaded when a secret changes.
+func (c *Config) resolveSecrets(tbl *ast.Table) (uint64, error) {
+	h := fnv.New64a()
+	err := c.resolveTable(tbl, h)
+	return h.Sum64(), err
+}
+
+func (c *Config) resolveTable(tbl *ast.Table, h hash.Hash64) error {
+	// sorted, for the checksum not to depend on the order of the map
+	keys := make([]string, 0, len(tbl.Fields))
+	for k := range tbl.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := c.resolveNode(tbl.Fields[k], h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveNode(node interface{}, h hash.Hash64) error {
+	switch n := node.(type) {
+	case *ast.KeyValue:
+		if err := c.resolveValue(n.Value, h); err != nil {
+			return fmt.Errorf("%s (line %d): %s", n.Key, n.Line, err)
+		}
+	case *ast.Table:
+		return c.resolveTable(n, h)
+	case []*ast.Table:
+		for _, t := range n {
+			if err := c.resolveTable(t, h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Config) resolveValue(value ast.Value, h hash.Hash64) error {
+	switch v := value.(type) {
+	case *ast.String:
+		if !secretRe.MatchString(v.Value) {
+			return nil
+		}
+		var err error
+		v.Value = secretRe.ReplaceAllStringFunc(v.Value, func(ref string) string {
+			if err != nil {
+				return ref
+			}
+			match := secretRe.FindStringSubmatch(ref)
+			var s string
+			if s, err = c.secret(match[1], match[2]); err != nil {
+				err = fmt.Errorf("could not resolve secret %s: %s", ref, err)
+				return ref
+			}
+			return s
+		})
+		if err != nil {
+			return err
+		}
+		io.WriteString(h, v.Value+"\x00")
+		c.secretValues[v.Value] = true
+	case *ast.Array:
+		for _, elem := range v.Value {
+			if err := c.resolveValue(elem, h); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/influxdata/toml"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type secretPlugin struct {
+	Servers []string
+	DSN     string `toml:"dsn"`
+	Auth    struct {
+		Password string
+	}
+}
+
+// parseSecrets resolves the secrets of a plugin table and unmarshals it.
+func parseSecrets(c *Config, conf string) (*secretPlugin, error) {
+	tbl, err := toml.Parse([]byte(conf))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.resolveSecrets(tbl); err != nil {
+		return nil, err
+	}
+	p := &secretPlugin{}
+	err = toml.UnmarshalTable(tbl, p)
+	return p, err
+}
+
+func TestResolveSecrets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on windows, it runs echo")
+	}
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(path, []byte("s3cr3t-file\n"), 0600))
+
+	c := NewConfig()
+	p, err := parseSecrets(c, `
+servers = ["tcp(@{exec:echo s3cr3t-exec})/", "localhost"]
+dsn = "user:@{file:`+path+`}@localhost"
+[auth]
+  password = "@{exec:echo s3cr3t-nested}"
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tcp(s3cr3t-exec)/", "localhost"}, p.Servers)
+	assert.Equal(t, "user:s3cr3t-file@localhost", p.DSN)
+	assert.Equal(t, "s3cr3t-nested", p.Auth.Password)
+
+	// the resolved secrets are redacted whatever the name of the setting
+	assert.Equal(t, map[string]interface{}{
+		"servers": []interface{}{Redacted, "localhost"},
+		"dsn":     Redacted,
+		"auth": map[string]interface{}{
+			"password": Redacted,
+		},
+	}, c.PluginSettings(p))
+
+	// the settings of another config are not
+	assert.Equal(t, "tcp(s3cr3t-exec)/", NewConfig().PluginSettings(p)["servers"].([]interface{})[0])
+}
+
+// Verify that only the whole values holding a secret are redacted, so that
+// a short secret does not redact the settings it is a substring of.
+func TestResolveSecretsRedactsWholeValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(path, []byte("admin\n"), 0600))
+
+	c := NewConfig()
+	p, err := parseSecrets(c, `
+servers = ["admin.example.com"]
+dsn = "@{file:`+path+`}"
+`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"servers": []interface{}{"admin.example.com"},
+		"dsn":     Redacted,
+		"auth": map[string]interface{}{
+			"password": "",
+		},
+	}, c.PluginSettings(p))
+}
+
+// Verify that the checksum of the resolved secrets changes with the secrets.
+func TestResolveSecretsChecksum(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "password")
+
+	checksum := func(secret string) uint64 {
+		require.NoError(t, ioutil.WriteFile(path, []byte(secret), 0600))
+		tbl, err := toml.Parse([]byte(`dsn = "user:@{file:` + path + `}@localhost"`))
+		require.NoError(t, err)
+		sum, err := NewConfig().resolveSecrets(tbl)
+		require.NoError(t, err)
+		return sum
+	}
+	assert.Equal(t, checksum("old"), checksum("old"))
+	assert.NotEqual(t, checksum("old"), checksum("new"))
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	for _, conf := range []string{
+		`dsn = "@{file:/nonexistent/password}"`,
+		`dsn = "@{vault:secret/mysql}"`,
+		`dsn = "@{keyring:mysql}"`,
+	} {
+		_, err := parseSecrets(NewConfig(), conf)
+		assert.Error(t, err, conf)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keyring")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secrets.keyring")
+
+	k, err := OpenKeyring(path, []byte("password"))
+	require.NoError(t, err)
+	k.Set("mysql", "s3cr3t-keyring")
+	k.Set("riemann", "other")
+	k.Delete("riemann")
+	require.NoError(t, k.Save())
+
+	contents, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(contents), "s3cr3t-keyring")
+
+	_, err = OpenKeyring(path, []byte("wrong"))
+	assert.Equal(t, errKeyringPassword, err)
+
+	os.Setenv(KeyringPasswordEnv, "password")
+	defer os.Unsetenv(KeyringPasswordEnv)
+	c := NewConfig()
+	c.Agent.SecretKeyring = path
+	p, err := parseSecrets(c, `dsn = "@{keyring:mysql}"`)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-keyring", p.DSN)
+
+	keyring, err := c.Keyring()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql"}, keyring.Names())
+}
@@ -0,0 +1,308 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Actions of the cardinality limiter on the metrics of the series over the
+// limit of their measurement.
+const (
+	SeriesLimitDrop     = "drop"
+	SeriesLimitCollapse = "collapse"
+)
+
+// CollapsedTagValue replaces the values of the tags collapsed by the
+// cardinality limiter.
+const CollapsedTagValue = "other"
+
+// limitLogInterval is the minimum interval between the warnings logged for a
+// measurement over its limit.
+var limitLogInterval = time.Minute
+
+// CardinalityLimiter limits the number of series of each measurement seen
+// over a sliding window. The metrics of the new series over the limit are
+// either dropped, or their tags with the most values are collapsed into a
+// single value.
+type CardinalityLimiter struct {
+	// enabled is set while a limit is configured, letting Apply skip the
+	// lock otherwise.
+	enabled int32
+
+	mu     sync.Mutex
+	limit  int
+	limits map[string]int
+	window time.Duration
+	action string
+
+	measurements map[string]*seriesSet
+	now          func() time.Time
+}
+
+func NewCardinalityLimiter() *CardinalityLimiter {
+	return &CardinalityLimiter{
+		window:       time.Hour,
+		action:       SeriesLimitDrop,
+		measurements: make(map[string]*seriesSet),
+		now:          time.Now,
+	}
+}
+
+// Configure sets the limit of the measurements, 0 for no limit, the limits
+// of specific measurements, the window the series are counted over and the
+// action on the series over the limit. The series already seen are kept.
+func (l *CardinalityLimiter) Configure(
+	limit int,
+	limits map[string]int,
+	window time.Duration,
+	action string,
+) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	l.limits = limits
+	enabled := limit > 0
+	for _, n := range limits {
+		if n > 0 {
+			enabled = true
+		}
+	}
+	if enabled {
+		atomic.StoreInt32(&l.enabled, 1)
+	} else {
+		atomic.StoreInt32(&l.enabled, 0)
+	}
+	if window > 0 {
+		l.window = window
+	}
+	if action != "" {
+		l.action = action
+	}
+}
+
+// seriesSet holds the series of a measurement seen within the window.
+type seriesSet struct {
+	series map[uint64]*seriesEntry
+	// values counts the series of each value of each tag.
+	values map[string]map[string]int
+	// oldest is a lower bound of the time the series were last seen.
+	oldest time.Time
+
+	// offenders counts the metrics limited because of each tag.
+	offenders map[string]int64
+	limited   int64
+	lastLog   time.Time
+
+	// The stats of the measurement, registered once it reaches its limit.
+	seriesStat  selfstat.Stat
+	limitedStat selfstat.Stat
+	tagStats    map[string]selfstat.Stat
+}
+
+type seriesEntry struct {
+	tags     map[string]string
+	lastSeen time.Time
+}
+
+func newSeriesSet() *seriesSet {
+	return &seriesSet{
+		series:    make(map[uint64]*seriesEntry),
+		values:    make(map[string]map[string]int),
+		offenders: make(map[string]int64),
+		tagStats:  make(map[string]selfstat.Stat),
+	}
+}
+
+// Apply returns the metric if its series is within the limit of its
+// measurement, the metric with its offending tags collapsed, or nil if the
+// metric is dropped.
+func (l *CardinalityLimiter) Apply(m telegraf.Metric) telegraf.Metric {
+	if atomic.LoadInt32(&l.enabled) == 0 {
+		return m
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limit := l.limit
+	if n, ok := l.limits[m.Name()]; ok {
+		limit = n
+	}
+	if limit <= 0 {
+		return m
+	}
+
+	s, ok := l.measurements[m.Name()]
+	if !ok {
+		s = newSeriesSet()
+		l.measurements[m.Name()] = s
+	}
+
+	now := l.now()
+	if e, ok := s.series[m.HashID()]; ok {
+		e.lastSeen = now
+		return m
+	}
+	if len(s.series) >= limit {
+		s.expire(now.Add(-l.window))
+	}
+	if len(s.series) < limit {
+		s.add(m.HashID(), m.Tags(), now)
+		s.updateStats()
+		return m
+	}
+
+	offenders := s.offendingTags(m.Tags())
+	defer l.report(m.Name(), s, limit, now)
+	s.limited++
+	for _, k := range offenders {
+		s.offenders[k]++
+	}
+	if l.action != SeriesLimitCollapse || len(offenders) == 0 {
+		return nil
+	}
+
+	for _, k := range offenders {
+		m.AddTag(k, CollapsedTagValue)
+	}
+	if e, ok := s.series[m.HashID()]; ok {
+		e.lastSeen = now
+	} else {
+		// the collapsed series go over the limit, they are bounded by the
+		// values already seen.
+		s.add(m.HashID(), m.Tags(), now)
+	}
+	return m
+}
+
+func (s *seriesSet) add(id uint64, tags map[string]string, now time.Time) {
+	if len(s.series) == 0 {
+		s.oldest = now
+	}
+	s.series[id] = &seriesEntry{tags: tags, lastSeen: now}
+	for k, v := range tags {
+		values, ok := s.values[k]
+		if !ok {
+			values = make(map[string]int)
+			s.values[k] = values
+		}
+		values[v]++
+	}
+}
+
+// expire forgets the series last seen before the given time.
+func (s *seriesSet) expire(before time.Time) {
+	if !s.oldest.Before(before) {
+		return
+	}
+	oldest := time.Time{}
+	for id, e := range s.series {
+		if e.lastSeen.Before(before) {
+			delete(s.series, id)
+			for k, v := range e.tags {
+				s.values[k][v]--
+				if s.values[k][v] == 0 {
+					delete(s.values[k], v)
+				}
+			}
+			continue
+		}
+		if oldest.IsZero() || e.lastSeen.Before(oldest) {
+			oldest = e.lastSeen
+		}
+	}
+	s.oldest = oldest
+}
+
+// offendingTags returns the tags of a new series whose values were not seen
+// yet. If all the values were seen, the tag with the most values is returned.
+func (s *seriesSet) offendingTags(tags map[string]string) []string {
+	var offenders []string
+	var top string
+	for k, v := range tags {
+		if v == CollapsedTagValue {
+			continue
+		}
+		if _, ok := s.values[k][v]; !ok {
+			offenders = append(offenders, k)
+		}
+		if top == "" || len(s.values[k]) > len(s.values[top]) ||
+			(len(s.values[k]) == len(s.values[top]) && k < top) {
+			top = k
+		}
+	}
+	if len(offenders) == 0 && top != "" {
+		offenders = append(offenders, top)
+	}
+	sort.Strings(offenders)
+	return offenders
+}
+
+// report updates the stats of a measurement over its limit, and logs its
+// top offending tags.
+func (l *CardinalityLimiter) report(name string, s *seriesSet, limit int, now time.Time) {
+	if s.limitedStat == nil {
+		tags := map[string]string{"measurement": name}
+		s.seriesStat = selfstat.Register("cardinality", "series", tags)
+		s.limitedStat = selfstat.Register("cardinality", "metrics_limited", tags)
+	}
+	s.limitedStat.Incr(1)
+	for k := range s.offenders {
+		if _, ok := s.tagStats[k]; !ok {
+			s.tagStats[k] = selfstat.Register("cardinality", "tag_values",
+				map[string]string{"measurement": name, "tag": k})
+		}
+	}
+	s.updateStats()
+
+	if now.Sub(s.lastLog) < limitLogInterval {
+		return
+	}
+	s.lastLog = now
+
+	keys := make([]string, 0, len(s.offenders))
+	for k := range s.offenders {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if s.offenders[keys[i]] != s.offenders[keys[j]] {
+			return s.offenders[keys[i]] > s.offenders[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	if len(keys) > 3 {
+		keys = keys[:3]
+	}
+	top := make([]string, len(keys))
+	for i, k := range keys {
+		top[i] = fmt.Sprintf("%s (%d values)", k, len(s.values[k]))
+	}
+
+	verb := "dropped"
+	if l.action == SeriesLimitCollapse {
+		verb = "collapsed"
+	}
+	log.Printf("W! Measurement %s reached its limit of %d series, %d metrics "+
+		"%s, top offending tags: %s\n", name, limit, s.limited, verb,
+		strings.Join(top, ", "))
+	s.limited = 0
+	s.offenders = make(map[string]int64)
+}
+
+func (s *seriesSet) updateStats() {
+	if s.seriesStat == nil {
+		return
+	}
+	s.seriesStat.Set(int64(len(s.series)))
+	for k, stat := range s.tagStats {
+		stat.Set(int64(len(s.values[k])))
+	}
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/selfstat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLimiterMetric(t *testing.T, name string, tags map[string]string) telegraf.Metric {
+	m, err := metric.New(name, tags,
+		map[string]interface{}{"value": int64(1)}, time.Now())
+	require.NoError(t, err)
+	return m
+}
+
+func newTestLimiter(limit int, action string) (*CardinalityLimiter, *time.Time) {
+	now := time.Unix(1500000000, 0)
+	l := NewCardinalityLimiter()
+	l.now = func() time.Time { return now }
+	l.Configure(limit, nil, time.Hour, action)
+	return l, &now
+}
+
+func TestCardinalityLimiterNoLimit(t *testing.T) {
+	l, _ := newTestLimiter(0, SeriesLimitDrop)
+	for _, id := range []string{"a", "b", "c"} {
+		m := newLimiterMetric(t, "http", map[string]string{"request_id": id})
+		assert.Equal(t, m, l.Apply(m))
+	}
+	assert.Empty(t, l.measurements)
+}
+
+// Verify that removing the limits disables the limiter.
+func TestCardinalityLimiterDisabled(t *testing.T) {
+	l, _ := newTestLimiter(1, SeriesLimitDrop)
+	l.Configure(0, map[string]int{"http": 1}, time.Hour, SeriesLimitDrop)
+	assert.Equal(t, int32(1), l.enabled)
+
+	l.Configure(0, map[string]int{"http": 0}, time.Hour, SeriesLimitDrop)
+	assert.Equal(t, int32(0), l.enabled)
+	for _, id := range []string{"a", "b"} {
+		m := newLimiterMetric(t, "http", map[string]string{"request_id": id})
+		assert.Equal(t, m, l.Apply(m))
+	}
+	assert.Empty(t, l.measurements)
+}
+
+func TestCardinalityLimiterDrop(t *testing.T) {
+	l, _ := newTestLimiter(2, SeriesLimitDrop)
+	// the stats are global, and count the metrics limited by the previous
+	// runs of the test
+	limited := selfstat.Register("cardinality", "metrics_limited",
+		map[string]string{"measurement": "http"})
+	before := limited.Get()
+
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "a"})))
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "b"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "c"})))
+
+	// the series seen are kept, and other measurements have their own budget
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "a"})))
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "disk", map[string]string{"request_id": "c"})))
+
+	assert.Equal(t, before+1, limited.Get())
+	stat := selfstat.Register("cardinality", "tag_values",
+		map[string]string{"measurement": "http", "tag": "request_id"})
+	assert.Equal(t, int64(2), stat.Get())
+}
+
+func TestCardinalityLimiterMeasurementLimits(t *testing.T) {
+	l, _ := newTestLimiter(1, SeriesLimitDrop)
+	l.Configure(1, map[string]int{"http": 0, "disk": 2}, time.Hour, SeriesLimitDrop)
+
+	for _, id := range []string{"a", "b", "c"} {
+		assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"id": id})))
+	}
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "disk", map[string]string{"id": "a"})))
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "disk", map[string]string{"id": "b"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "disk", map[string]string{"id": "c"})))
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "cpu", map[string]string{"id": "a"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "cpu", map[string]string{"id": "b"})))
+}
+
+func TestCardinalityLimiterCollapse(t *testing.T) {
+	l, _ := newTestLimiter(2, SeriesLimitCollapse)
+
+	l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "a", "request_id": "1"}))
+	l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "b", "request_id": "2"}))
+
+	m := l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "a", "request_id": "3"}))
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]string{"host": "a", "request_id": "other"}, m.Tags())
+
+	m = l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "a", "request_id": "4"}))
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]string{"host": "a", "request_id": "other"}, m.Tags())
+
+	// a new combination of the values seen collapses the tag with the most
+	// values
+	m = l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "b", "request_id": "1"}))
+	require.NotNil(t, m)
+	assert.Equal(t, map[string]string{"host": "b", "request_id": "other"}, m.Tags())
+
+	assert.Len(t, l.measurements["http"].series, 4)
+}
+
+func TestCardinalityLimiterCollapseNoTags(t *testing.T) {
+	l, _ := newTestLimiter(1, SeriesLimitCollapse)
+
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"host": "a"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{})))
+}
+
+func TestCardinalityLimiterWindow(t *testing.T) {
+	l, now := newTestLimiter(2, SeriesLimitDrop)
+
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "a"})))
+	*now = now.Add(30 * time.Minute)
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "b"})))
+	*now = now.Add(20 * time.Minute)
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "a"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "c"})))
+
+	// b was last seen more than a window ago
+	*now = now.Add(45 * time.Minute)
+	assert.NotNil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "c"})))
+	assert.Nil(t, l.Apply(newLimiterMetric(t, "http", map[string]string{"request_id": "b"})))
+	assert.Equal(t, map[string]int{"a": 1, "c": 1}, l.measurements["http"].values["request_id"])
+}
+
+func TestMakeMetricCardinalityLimiter(t *testing.T) {
+	l, _ := newTestLimiter(1, SeriesLimitDrop)
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "TestRunningInput",
+	})
+	ri.SetCardinalityLimiter(l)
+
+	fields := map[string]interface{}{"value": int64(1)}
+	m := ri.MakeMetric("RITest", fields, map[string]string{"id": "a"},
+		telegraf.Untyped, time.Now())
+	assert.NotNil(t, m)
+	m = ri.MakeMetric("RITest", fields, map[string]string{"id": "b"},
+		telegraf.Untyped, time.Now())
+	assert.Nil(t, m)
+}
@@ -0,0 +1,23 @@
+package models
+
+// DefaultRoute is the route of the plugins that do not set any routes.
+const DefaultRoute = "default"
+
+// RoutesMatch returns true if the two sets of routes share a route. Empty
+// routes stand for the default route.
+func RoutesMatch(a, b []string) bool {
+	if len(a) == 0 {
+		a = []string{DefaultRoute}
+	}
+	if len(b) == 0 {
+		b = []string{DefaultRoute}
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutesMatch(t *testing.T) {
+	assert.True(t, RoutesMatch(nil, nil))
+	assert.True(t, RoutesMatch(nil, []string{DefaultRoute}))
+	assert.True(t, RoutesMatch([]string{"archive", "kafka"}, []string{"kafka"}))
+	assert.False(t, RoutesMatch(nil, []string{"kafka"}))
+	assert.False(t, RoutesMatch([]string{"archive"}, []string{"kafka"}))
+}
@@ -28,11 +28,18 @@ func NewRunningAggregator(
 	}
 }
 
+type RunningAggregators []*RunningAggregator
+
+func (ra RunningAggregators) Len() int           { return len(ra) }
+func (ra RunningAggregators) Swap(i, j int)      { ra[i], ra[j] = ra[j], ra[i] }
+func (ra RunningAggregators) Less(i, j int) bool { return ra[i].Config.Order < ra[j].Config.Order }
+
 // AggregatorConfig containing configuration parameters for the running
 // aggregator plugin.
 type AggregatorConfig struct {
 	Name string
 
+	Order             int64
 	DropOriginal      bool
 	NameOverride      string
 	MeasurementPrefix string
@@ -42,12 +49,29 @@ type AggregatorConfig struct {
 
 	Period time.Duration
 	Delay  time.Duration
+
+	// Routes are the pipelines the aggregated metrics are sent to.
+	Routes []string
+
+	// Checksum identifies the configuration of the plugin. It is used on
+	// reload to find the plugins whose configuration did not change.
+	Checksum uint64
 }
 
 func (r *RunningAggregator) Name() string {
 	return "aggregators." + r.Config.Name
 }
 
+// Routes returns the routes of the metrics made by the aggregator.
+func (r *RunningAggregator) Routes() []string {
+	return r.Config.Routes
+}
+
+// Aggregator returns the aggregator plugin.
+func (r *RunningAggregator) Aggregator() telegraf.Aggregator {
+	return r.a
+}
+
 func (r *RunningAggregator) MakeMetric(
 	measurement string,
 	fields map[string]interface{},
@@ -81,6 +105,13 @@ func (r *RunningAggregator) MakeMetric(
 // Before applying to the plugin, it will run any defined filters on the metric.
 // Apply returns true if the original metric should be dropped.
 func (r *RunningAggregator) Add(in telegraf.Metric) bool {
+	// the aggregators would treat the quantiles and buckets of summaries and
+	// histograms as plain fields, these metrics pass through untouched.
+	if in.Type() == telegraf.Summary || in.Type() == telegraf.Histogram {
+		in.Drop()
+		return false
+	}
+
 	if r.Config.Filter.IsActive() {
 		// check if the aggregator should apply this metric
 		name := in.Name()
@@ -89,10 +120,12 @@ func (r *RunningAggregator) Add(in telegraf.Metric) bool {
 		t := in.Time()
 		if ok := r.Config.Filter.Apply(name, fields, tags); !ok {
 			// aggregator should not apply this metric
+			in.Drop()
 			return false
 		}
 
-		in, _ = metric.New(name, tags, fields, t)
+		in.Drop()
+		in, _ = metric.New(name, tags, fields, t, in.Type())
 	}
 
 	r.metrics <- in
@@ -153,9 +186,11 @@ func (r *RunningAggregator) Run(
 				m.Time().After(r.periodEnd.Add(truncation).Add(r.Config.Delay)) {
 				// the metric is outside the current aggregation period, so
 				// skip it.
+				m.Drop()
 				continue
 			}
 			r.add(m)
+			m.Drop()
 		case <-periodT.C:
 			r.periodStart = r.periodEnd
 			r.periodEnd = r.periodStart.Add(r.Config.Period)
@@ -44,6 +44,28 @@ func TestAdd(t *testing.T) {
 	assert.Equal(t, int64(101), atomic.LoadInt64(&a.sum))
 }
 
+// Verify that summaries and histograms are not aggregated, nor dropped.
+func TestAddSummaryHistogram(t *testing.T) {
+	for _, typ := range []telegraf.ValueType{telegraf.Summary, telegraf.Histogram} {
+		a := &TestAggregator{}
+		ra := NewRunningAggregator(a, &AggregatorConfig{
+			Name:         "TestRunningAggregator",
+			DropOriginal: true,
+			Period:       time.Millisecond * 500,
+		})
+
+		m := ra.MakeMetric(
+			"RITest",
+			map[string]interface{}{"count": float64(101), "sum": float64(202)},
+			map[string]string{},
+			typ,
+			time.Now(),
+		)
+		assert.False(t, ra.Add(m))
+		assert.Len(t, ra.metrics, 0)
+	}
+}
+
 func TestAddMetricsOutsideCurrentPeriod(t *testing.T) {
 	a := &TestAggregator{}
 	ra := NewRunningAggregator(a, &AggregatorConfig{
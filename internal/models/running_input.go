@@ -2,22 +2,44 @@ package models
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/schedule"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
 var GlobalMetricsGathered = selfstat.Register("agent", "metrics_gathered", map[string]string{})
 
+// Overflow policies of the queue of an input.
+const (
+	OverflowBlock      = "block"
+	OverflowDropOldest = "drop_oldest"
+	OverflowDropNewest = "drop_newest"
+)
+
 type RunningInput struct {
 	Input  telegraf.Input
 	Config *InputConfig
 
 	trace       bool
 	defaultTags map[string]string
+	limiter     *CardinalityLimiter
 
 	MetricsGathered selfstat.Stat
+	MetricsDropped  selfstat.Stat
+
+	mu     sync.Mutex
+	status InputStatus
+}
+
+// InputStatus is the outcome of the latest gathers of an input.
+type InputStatus struct {
+	LastGather         time.Time
+	LastGatherDuration time.Duration
+	LastError          string
+	LastErrorTime      time.Time
 }
 
 func NewRunningInput(
@@ -32,6 +54,11 @@ func NewRunningInput(
 			"metrics_gathered",
 			map[string]string{"input": config.Name},
 		),
+		MetricsDropped: selfstat.Register(
+			"gather",
+			"metrics_dropped",
+			map[string]string{"input": config.Name},
+		),
 	}
 }
 
@@ -44,12 +71,41 @@ type InputConfig struct {
 	Tags              map[string]string
 	Filter            Filter
 	Interval          time.Duration
+
+	// Schedule, if set, is the cron schedule of the gathers. It replaces
+	// the interval, which still bounds the duration of a gather.
+	Schedule *schedule.Cron
+	// RoundInterval aligns the gathers on the interval on the wall clock.
+	RoundInterval bool
+	// CollectionJitter, if set, replaces the collection jitter of the agent.
+	CollectionJitter time.Duration
+	// RunOnce makes the input gather a single time, at startup.
+	RunOnce bool
+
+	// Routes are the pipelines the metrics of the input are sent to.
+	Routes []string
+
+	// QueueSize is the number of metrics queued between the input and the
+	// agent. When 0, the input sends its metrics straight to the agent.
+	QueueSize int
+	// QueueOverflow is the policy applied when the queue is full, one of
+	// OverflowBlock, OverflowDropOldest or OverflowDropNewest.
+	QueueOverflow string
+
+	// Checksum identifies the configuration of the plugin. It is used on
+	// reload to find the plugins whose configuration did not change.
+	Checksum uint64
 }
 
 func (r *RunningInput) Name() string {
 	return "inputs." + r.Config.Name
 }
 
+// Routes returns the routes of the metrics made by the input.
+func (r *RunningInput) Routes() []string {
+	return r.Config.Routes
+}
+
 // MakeMetric either returns a metric, or returns nil if the metric doesn't
 // need to be created (because of filtering, an error, etc.)
 func (r *RunningInput) MakeMetric(
@@ -74,6 +130,10 @@ func (r *RunningInput) MakeMetric(
 		t,
 	)
 
+	if r.limiter != nil && m != nil {
+		m = r.limiter.Apply(m)
+	}
+
 	if r.trace && m != nil {
 		fmt.Print("> " + m.String())
 	}
@@ -83,6 +143,29 @@ func (r *RunningInput) MakeMetric(
 	return m
 }
 
+// SetLastGather records the start time and the duration of the latest gather.
+func (r *RunningInput) SetLastGather(start time.Time, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.LastGather = start
+	r.status.LastGatherDuration = elapsed
+}
+
+// SetLastError records the latest error reported by the input.
+func (r *RunningInput) SetLastError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status.LastError = err.Error()
+	r.status.LastErrorTime = time.Now()
+}
+
+// Status returns the outcome of the latest gathers of the input.
+func (r *RunningInput) Status() InputStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
 func (r *RunningInput) Trace() bool {
 	return r.trace
 }
@@ -94,3 +177,9 @@ func (r *RunningInput) SetTrace(trace bool) {
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
+
+// SetCardinalityLimiter sets the limiter of the series of the metrics made by
+// the input.
+func (r *RunningInput) SetCardinalityLimiter(limiter *CardinalityLimiter) {
+	r.limiter = limiter
+}
@@ -337,3 +337,20 @@ type testInput struct{}
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func TestRunningInputStatus(t *testing.T) {
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name: "TestRunningInput",
+	})
+	assert.Equal(t, InputStatus{}, ri.Status())
+
+	start := time.Now()
+	ri.SetLastGather(start, time.Second)
+	ri.SetLastError(fmt.Errorf("gather failed"))
+
+	status := ri.Status()
+	assert.Equal(t, start, status.LastGather)
+	assert.Equal(t, time.Second, status.LastGatherDuration)
+	assert.Equal(t, "gather failed", status.LastError)
+	assert.False(t, status.LastErrorTime.IsZero())
+}
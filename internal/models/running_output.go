@@ -1,12 +1,14 @@
 package models
 
 import (
+	"io"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal/buffer"
-	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/internal/retry"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -32,8 +34,44 @@ type RunningOutput struct {
 	BufferLimit     selfstat.Stat
 	WriteTime       selfstat.Stat
 
+	CircuitState    selfstat.Stat
+	CircuitOpen     selfstat.Stat
+	CircuitHalfOpen selfstat.Stat
+	CircuitClosed   selfstat.Stat
+
 	metrics     *buffer.Buffer
-	failMetrics *buffer.Buffer
+	failMetrics metricBuffer
+
+	breaker   *retry.Breaker
+	connected bool
+	connMu    sync.Mutex
+}
+
+// metricBuffer is the interface shared by the in-memory buffer.Buffer and the
+// persistent buffer.DiskBuffer.
+type metricBuffer interface {
+	IsEmpty() bool
+	Len() int
+	Add(metrics ...telegraf.Metric)
+	Batch(batchSize int) []telegraf.Metric
+}
+
+// persistentBuffer is a metricBuffer that keeps a batch until it is committed
+// as written.
+type persistentBuffer interface {
+	metricBuffer
+	Peek(batchSize int) []telegraf.Metric
+	Commit()
+}
+
+// asPersistent returns b as a persistentBuffer, if its metrics are kept on
+// disk.
+func asPersistent(b metricBuffer) (persistentBuffer, bool) {
+	if l, ok := b.(*lazyBuffer); ok {
+		b = l.get()
+	}
+	pb, ok := b.(persistentBuffer)
+	return pb, ok
 }
 
 func NewRunningOutput(
@@ -49,10 +87,14 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DEFAULT_METRIC_BATCH_SIZE
 	}
+	var policy retry.Policy
+	if conf != nil {
+		policy = conf.Retry
+	}
 	ro := &RunningOutput{
 		Name:              name,
 		metrics:           buffer.NewBuffer(batchSize),
-		failMetrics:       buffer.NewBuffer(bufferLimit),
+		failMetrics:       newFailBuffer(name, conf, bufferLimit),
 		Output:            output,
 		Config:            conf,
 		MetricBufferLimit: bufferLimit,
@@ -82,11 +124,94 @@ func NewRunningOutput(
 			"write_time_ns",
 			map[string]string{"output": name},
 		),
+		CircuitState: selfstat.Register(
+			"write",
+			"circuit_state",
+			map[string]string{"output": name},
+		),
+		CircuitOpen: selfstat.Register(
+			"write",
+			"circuit_open",
+			map[string]string{"output": name},
+		),
+		CircuitHalfOpen: selfstat.Register(
+			"write",
+			"circuit_half_open",
+			map[string]string{"output": name},
+		),
+		CircuitClosed: selfstat.Register(
+			"write",
+			"circuit_closed",
+			map[string]string{"output": name},
+		),
+		breaker: retry.NewBreaker(policy),
 	}
+	ro.breaker.OnStateChange = ro.circuitStateChanged
 	ro.BufferLimit.Incr(int64(ro.MetricBufferLimit))
 	return ro
 }
 
+// newFailBuffer returns the buffer holding metrics that failed to be written.
+// If the output has a buffer directory configured, the metrics are persisted
+// on disk, otherwise they are kept in memory.
+//
+// The buffer directory is only opened when the buffer is first used, so that
+// on reload a replaced output can release it before its successor opens it.
+func newFailBuffer(name string, conf *OutputConfig, bufferLimit int) metricBuffer {
+	if conf == nil || conf.BufferDirectory == "" {
+		return buffer.NewBuffer(bufferLimit)
+	}
+	return &lazyBuffer{open: func() metricBuffer {
+		b, err := buffer.NewDiskBuffer(conf.BufferDirectory,
+			conf.BufferMaxBytes, conf.BufferSegmentSize)
+		if err != nil {
+			log.Printf("E! Could not open buffer directory %s for output [%s], "+
+				"falling back to an in-memory buffer: %s",
+				conf.BufferDirectory, name, err)
+			return buffer.NewBuffer(bufferLimit)
+		}
+		if n := b.Len(); n > 0 {
+			log.Printf("I! Output [%s] replaying %d metrics from %s",
+				name, n, conf.BufferDirectory)
+		}
+		return b
+	}}
+}
+
+// lazyBuffer is a metricBuffer that is created on first use.
+type lazyBuffer struct {
+	once sync.Once
+	open func() metricBuffer
+	b    metricBuffer
+}
+
+func (l *lazyBuffer) get() metricBuffer {
+	l.once.Do(func() {
+		l.b = l.open()
+	})
+	return l.b
+}
+
+func (l *lazyBuffer) IsEmpty() bool                  { return l.get().IsEmpty() }
+func (l *lazyBuffer) Len() int                       { return l.get().Len() }
+func (l *lazyBuffer) Add(metrics ...telegraf.Metric) { l.get().Add(metrics...) }
+func (l *lazyBuffer) Batch(n int) []telegraf.Metric  { return l.get().Batch(n) }
+
+// Close closes the underlying buffer, if it was ever opened.
+func (l *lazyBuffer) Close() error {
+	opened := true
+	l.once.Do(func() {
+		opened = false
+	})
+	if !opened {
+		return nil
+	}
+	if c, ok := l.b.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // AddMetric adds a metric to the output. This function can also write cached
 // points if FlushBufferWhenFull is true.
 func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
@@ -95,18 +220,18 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	}
 	// Filter any tagexclude/taginclude parameters before adding metric
 	if ro.Config.Filter.IsActive() {
-		// In order to filter out tags, we need to create a new metric, since
-		// metrics are immutable once created.
 		name := m.Name()
 		tags := m.Tags()
 		fields := m.Fields()
-		t := m.Time()
 		if ok := ro.Config.Filter.Apply(name, fields, tags); !ok {
 			ro.MetricsFiltered.Incr(1)
+			m.Drop()
 			return
 		}
-		// error is not possible if creating from another metric, so ignore.
-		m, _ = metric.New(name, tags, fields, t)
+		// The metric is modified in place rather than re-created, so that it
+		// keeps its type and delivery tracking. The agent hands every output
+		// its own copy of the metric.
+		removeFiltered(m, fields, tags)
 	}
 
 	ro.metrics.Add(m)
@@ -119,14 +244,44 @@ func (ro *RunningOutput) AddMetric(m telegraf.Metric) {
 	}
 }
 
+// removeFiltered removes the tags and fields of m that are not in the
+// filtered tags and fields.
+func removeFiltered(
+	m telegraf.Metric,
+	fields map[string]interface{},
+	tags map[string]string,
+) {
+	for k := range m.Tags() {
+		if _, ok := tags[k]; !ok {
+			m.RemoveTag(k)
+		}
+	}
+	for k := range m.Fields() {
+		if _, ok := fields[k]; !ok {
+			m.RemoveField(k)
+		}
+	}
+}
+
 // Write writes all cached points to this output.
 func (ro *RunningOutput) Write() error {
 	nFails, nMetrics := ro.failMetrics.Len(), ro.metrics.Len()
 	ro.BufferSize.Set(int64(nFails + nMetrics))
 	log.Printf("D! Output [%s] buffer fullness: %d / %d metrics. ",
 		ro.Name, nFails+nMetrics, ro.MetricBufferLimit)
+
+	// The buffered metrics are left in place while the circuit breaker is
+	// open, rather than rotated through the fail buffer on every flush.
+	if ro.breaker.Blocked() {
+		log.Printf("D! Output [%s] circuit breaker is open, skipping write "+
+			"until %s", ro.Name, ro.breaker.NextAttempt().Format(time.RFC3339))
+		return nil
+	}
+
 	var err error
-	if !ro.failMetrics.IsEmpty() {
+	if pb, ok := asPersistent(ro.failMetrics); ok {
+		err = ro.writePersistent(pb, nFails)
+	} else if !ro.failMetrics.IsEmpty() {
 		// how many batches of failed writes we need to write.
 		nBatches := nFails/ro.MetricBatchSize + 1
 		batchSize := ro.MetricBatchSize
@@ -159,7 +314,31 @@ func (ro *RunningOutput) Write() error {
 
 	if err != nil {
 		ro.failMetrics.Add(batch...)
-		return err
+	}
+	if err == retry.ErrOpen {
+		log.Printf("D! Output [%s] circuit breaker is open, skipping write "+
+			"until %s", ro.Name, ro.breaker.NextAttempt().Format(time.RFC3339))
+		return nil
+	}
+	return err
+}
+
+// writePersistent writes the first n metrics of a persistent buffer, a batch
+// at a time from its head. A batch is only removed from the buffer once it
+// is written: after a failure, it and the metrics behind it stay in place on
+// disk rather than being rotated, and are retried on the next write.
+func (ro *RunningOutput) writePersistent(pb persistentBuffer, n int) error {
+	for n > 0 {
+		batchSize := ro.MetricBatchSize
+		if n < batchSize {
+			batchSize = n
+		}
+		n -= batchSize
+		batch := pb.Peek(batchSize)
+		if err := ro.write(batch); err != nil {
+			return err
+		}
+		pb.Commit()
 	}
 	return nil
 }
@@ -169,20 +348,137 @@ func (ro *RunningOutput) write(metrics []telegraf.Metric) error {
 	if nMetrics == 0 {
 		return nil
 	}
+	if !ro.breaker.Allow() {
+		return retry.ErrOpen
+	}
+	if err := ro.ensureConnected(); err != nil {
+		log.Printf("E! Failed to connect to output [%s], next attempt at %s, "+
+			"error was '%s'\n", ro.Name,
+			ro.breaker.NextAttempt().Format(time.RFC3339), err)
+		return err
+	}
+
 	start := time.Now()
 	err := ro.Output.Write(metrics)
 	elapsed := time.Since(start)
-	if err == nil {
-		log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
-			ro.Name, nMetrics, elapsed)
-		ro.MetricsWritten.Incr(int64(nMetrics))
-		ro.WriteTime.Incr(elapsed.Nanoseconds())
+	if err != nil {
+		ro.breaker.Failure()
+		return err
 	}
-	return err
+	ro.breaker.Success()
+	for _, m := range metrics {
+		m.Accept()
+	}
+	log.Printf("D! Output [%s] wrote batch of %d metrics in %s\n",
+		ro.Name, nMetrics, elapsed)
+	ro.MetricsWritten.Incr(int64(nMetrics))
+	ro.WriteTime.Incr(elapsed.Nanoseconds())
+	return nil
+}
+
+// Connect connects the output. If the connection fails, the circuit breaker
+// of the output opens and the connection is retried, with backoff, by the
+// following writes.
+func (ro *RunningOutput) Connect() error {
+	ro.connMu.Lock()
+	defer ro.connMu.Unlock()
+	return ro.connect()
+}
+
+func (ro *RunningOutput) ensureConnected() error {
+	ro.connMu.Lock()
+	defer ro.connMu.Unlock()
+	if ro.connected {
+		return nil
+	}
+	return ro.connect()
+}
+
+func (ro *RunningOutput) connect() error {
+	if err := ro.Output.Connect(); err != nil {
+		ro.breaker.Trip()
+		return err
+	}
+	ro.connected = true
+	return nil
+}
+
+// circuitStateChanged logs and counts the transitions of the circuit breaker.
+func (ro *RunningOutput) circuitStateChanged(state retry.State) {
+	ro.CircuitState.Set(int64(state))
+	switch state {
+	case retry.Open:
+		ro.CircuitOpen.Incr(1)
+		log.Printf("W! Output [%s] circuit breaker is open, suspending writes",
+			ro.Name)
+	case retry.HalfOpen:
+		ro.CircuitHalfOpen.Incr(1)
+		log.Printf("I! Output [%s] circuit breaker is half-open, trying again",
+			ro.Name)
+	case retry.Closed:
+		ro.CircuitClosed.Incr(1)
+		log.Printf("I! Output [%s] circuit breaker is closed, resuming writes",
+			ro.Name)
+	}
+}
+
+// Subscribes returns true if the output receives the metrics sent on the
+// given routes.
+func (ro *RunningOutput) Subscribes(routes []string) bool {
+	return RoutesMatch(ro.Config.Routes, routes)
+}
+
+// BufferLen returns the number of metrics waiting to be written.
+func (ro *RunningOutput) BufferLen() int {
+	return ro.metrics.Len() + ro.failMetrics.Len()
+}
+
+// BreakerState returns the state of the circuit breaker of the output.
+func (ro *RunningOutput) BreakerState() retry.State {
+	return ro.breaker.State()
+}
+
+// Drain removes and returns the metrics buffered in memory, oldest first, so
+// that they can be handed over to the output replacing this one on reload.
+// Metrics of a persistent buffer are moved to disk instead, where they are
+// picked up by the next output using the same buffer directory.
+func (ro *RunningOutput) Drain() []telegraf.Metric {
+	pending := ro.metrics.Batch(ro.metrics.Len())
+	if _, ok := asPersistent(ro.failMetrics); ok {
+		ro.failMetrics.Add(pending...)
+		return nil
+	}
+	return append(ro.failMetrics.Batch(ro.failMetrics.Len()), pending...)
+}
+
+// Close releases the resources held by the output's buffer. Metrics kept in
+// a persistent buffer are replayed the next time the output is created.
+func (ro *RunningOutput) Close() error {
+	if c, ok := ro.failMetrics.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
 }
 
 // OutputConfig containing name and filter
 type OutputConfig struct {
 	Name   string
 	Filter Filter
+
+	// BufferDirectory is the directory of the persistent buffer. If empty,
+	// metrics are buffered in memory.
+	BufferDirectory   string
+	BufferMaxBytes    int64
+	BufferSegmentSize int64
+
+	// Retry is the backoff and circuit breaker policy for failed writes and
+	// connection attempts.
+	Retry retry.Policy
+
+	// Routes are the pipelines the output receives the metrics of.
+	Routes []string
+
+	// Checksum identifies the configuration of the plugin. It is used on
+	// reload to find the plugins whose configuration did not change.
+	Checksum uint64
 }
@@ -2,10 +2,15 @@ package models
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/retry"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
@@ -479,6 +484,266 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+// Verify that metrics that failed to be written survive a restart when the
+// output has a persistent buffer.
+func TestRunningOutputPersistentBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 100, 1000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.NoError(t, ro.Close())
+
+	// "restart" the output
+	m = &mockOutput{}
+	ro = NewRunningOutput("test", m, conf, 100, 1000)
+	defer ro.Close()
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+
+	received := m.Metrics()
+	require.Len(t, received, 10)
+	for i, metric := range append(first5, next5...) {
+		assert.Equal(t, metric.String(), received[i].String())
+	}
+}
+
+// Verify that no write is attempted while the circuit breaker is open.
+func TestRunningOutputCircuitBreaker(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: retry.Policy{
+			InitialInterval: 50 * time.Millisecond,
+			Threshold:       2,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 10, 100)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	require.Error(t, ro.Write())
+	assert.Equal(t, int64(retry.Open), ro.CircuitState.Get())
+
+	// writes are skipped, and metrics kept, while the breaker is open
+	m.failWrite = false
+	for _, metric := range next5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 10)
+	assert.Equal(t, int64(retry.Closed), ro.CircuitState.Get())
+}
+
+// countingBuffer counts the batches taken from a metricBuffer.
+type countingBuffer struct {
+	metricBuffer
+	batches int
+}
+
+func (c *countingBuffer) Batch(n int) []telegraf.Metric {
+	c.batches++
+	return c.metricBuffer.Batch(n)
+}
+
+// Verify that the failed metrics are not rotated while the circuit breaker
+// is open.
+func TestRunningOutputCircuitBreakerKeepsBuffer(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: retry.Policy{
+			InitialInterval: time.Minute,
+			Threshold:       1,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 100)
+	// the first full batch fails, and opens the breaker
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Equal(t, retry.Open, ro.breaker.State())
+
+	failMetrics := &countingBuffer{metricBuffer: ro.failMetrics}
+	ro.failMetrics = failMetrics
+	for i := 0; i < 3; i++ {
+		require.NoError(t, ro.Write())
+	}
+	assert.Equal(t, 0, failMetrics.batches)
+	assert.Equal(t, 5, ro.metrics.Len()+failMetrics.Len())
+}
+
+// Verify that an output that failed to connect is reconnected on write.
+func TestRunningOutputReconnect(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+		Retry: retry.Policy{
+			InitialInterval: 50 * time.Millisecond,
+		},
+	}
+
+	m := &mockOutput{}
+	m.failConnect = true
+	ro := NewRunningOutput("test", m, conf, 10, 100)
+	require.Error(t, ro.Connect())
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+
+	m.failConnect = false
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 5)
+}
+
+// Verify that the buffered metrics can be handed over to another output.
+func TestRunningOutputDrain(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{},
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 4, 12)
+
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	for _, metric := range next5[:2] {
+		ro.AddMetric(metric)
+	}
+
+	assert.Equal(t, 7, ro.BufferLen())
+
+	drained := ro.Drain()
+	require.Len(t, drained, 7)
+	assert.Equal(t, 0, ro.BufferLen())
+	assert.Equal(t, first5[0].String(), drained[0].String())
+	assert.Equal(t, next5[1].String(), drained[6].String())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	assert.Len(t, m.Metrics(), 0)
+}
+
+// Verify that tracked metrics are reported once written, filtered or dropped.
+func TestRunningOutputTracking(t *testing.T) {
+	conf := &OutputConfig{
+		Filter: Filter{
+			NameDrop: []string{"metric1"},
+		},
+	}
+	assert.NoError(t, conf.Filter.Compile())
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 4)
+
+	var infos []telegraf.DeliveryInfo
+	notify := func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	}
+	filtered, _ := metric.WithGroupTracking(first5[:1], notify)
+	rejected, rejectedID := metric.WithGroupTracking(first5[1:3], notify)
+	written, writtenID := metric.WithGroupTracking(first5[3:], notify)
+
+	ro.AddMetric(filtered[0])
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+
+	for _, metric := range append(rejected, written...) {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	assert.Len(t, infos, 1)
+
+	// overflowing the buffer drops the oldest metrics
+	for _, metric := range next5[:2] {
+		ro.AddMetric(metric)
+	}
+	require.Len(t, infos, 2)
+	assert.Equal(t, rejectedID, infos[1].ID())
+	assert.False(t, infos[1].Delivered())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	require.Len(t, infos, 3)
+	assert.Equal(t, writtenID, infos[2].ID())
+	assert.True(t, infos[2].Delivered())
+}
+
+// Verify that a failed write leaves the metrics of a persistent buffer in
+// place, rather than rewriting them to disk, and that they are written in
+// order once the output recovers.
+func TestRunningOutputPersistentBufferRetriesHead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-output-buffer")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	conf := &OutputConfig{
+		Filter:          Filter{},
+		BufferDirectory: dir,
+	}
+
+	m := &mockOutput{}
+	m.failWrite = true
+	ro := NewRunningOutput("test", m, conf, 2, 1000)
+	defer ro.Close()
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+	require.Error(t, ro.Write())
+	size := bufferDirSize(t, dir)
+	require.Error(t, ro.Write())
+	assert.Equal(t, size, bufferDirSize(t, dir))
+	assert.Equal(t, 5, ro.failMetrics.Len())
+
+	m.failWrite = false
+	require.NoError(t, ro.Write())
+	received := m.Metrics()
+	require.Len(t, received, 5)
+	for i, metric := range first5 {
+		assert.Equal(t, metric.String(), received[i].String())
+	}
+}
+
+func bufferDirSize(t *testing.T, dir string) int64 {
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	var size int64
+	for _, f := range files {
+		size += f.Size()
+	}
+	return size
+}
+
 type mockOutput struct {
 	sync.Mutex
 
@@ -486,9 +751,14 @@ type mockOutput struct {
 
 	// if true, mock a write failure
 	failWrite bool
+	// if true, mock a connection failure
+	failConnect bool
 }
 
 func (m *mockOutput) Connect() error {
+	if m.failConnect {
+		return fmt.Errorf("Failed Connect!")
+	}
 	return nil
 }
 
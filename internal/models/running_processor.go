@@ -2,6 +2,7 @@ package models
 
 import (
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 )
 
 type RunningProcessor struct {
@@ -21,24 +22,43 @@ type ProcessorConfig struct {
 	Name   string
 	Order  int64
 	Filter Filter
+
+	// Checksum identifies the configuration of the plugin. It is used on
+	// reload to find the plugins whose configuration did not change.
+	Checksum uint64
 }
 
 func (rp *RunningProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	ret := []telegraf.Metric{}
 
-	for _, metric := range in {
+	for _, m := range in {
 		if rp.Config.Filter.IsActive() {
 			// check if the filter should be applied to this metric
-			if ok := rp.Config.Filter.Apply(metric.Name(), metric.Fields(), metric.Tags()); !ok {
+			if ok := rp.Config.Filter.Apply(m.Name(), m.Fields(), m.Tags()); !ok {
 				// this means filter should not be applied
-				ret = append(ret, metric)
+				ret = append(ret, m)
 				continue
 			}
 		}
 		// This metric should pass through the filter, so call the filter Apply
 		// function and append results to the output slice.
-		ret = append(ret, rp.Processor.Apply(metric)...)
+		out := rp.Processor.Apply(m)
+		if !contains(out, m) {
+			// the processor dropped or replaced the metric: a tracked metric
+			// group completes once its replacements, if any, are written.
+			out = metric.WithTrackingOf(m, out)
+		}
+		ret = append(ret, out...)
 	}
 
 	return ret
 }
+
+func contains(metrics []telegraf.Metric, m telegraf.Metric) bool {
+	for _, v := range metrics {
+		if v == m {
+			return true
+		}
+	}
+	return false
+}
@@ -4,9 +4,11 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/testutil"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type TestProcessor struct {
@@ -115,3 +117,54 @@ func TestRunningProcessor_DroppedMetric(t *testing.T) {
 	}
 	assert.Equal(t, expectedNames, actualNames)
 }
+
+func TestRunningProcessor_WithTagPass(t *testing.T) {
+	inmetrics := []telegraf.Metric{
+		testutil.TestMetric(1, "foo"),
+		testutil.TestMetric(1, "dropme"),
+		testutil.TestMetric(1, "bar"),
+	}
+
+	rfp := NewTestRunningProcessor()
+	rfp.Config.Filter.TagPass = []TagFilter{
+		{Name: "tag1", Filter: []string{"nomatch"}},
+	}
+	assert.NoError(t, rfp.Config.Filter.Compile())
+
+	// metrics not selected by the filter are routed around the processor
+	filteredMetrics := rfp.Apply(inmetrics...)
+	assert.Equal(t, inmetrics, filteredMetrics)
+
+	rfp.Config.Filter.TagPass[0].Filter = []string{"value1"}
+	assert.NoError(t, rfp.Config.Filter.Compile())
+
+	filteredMetrics = rfp.Apply(inmetrics...)
+	actualNames := []string{
+		filteredMetrics[0].Name(),
+		filteredMetrics[1].Name(),
+	}
+	assert.Equal(t, []string{"fuz", "baz"}, actualNames)
+}
+
+func TestRunningProcessor_TrackedMetrics(t *testing.T) {
+	var infos []telegraf.DeliveryInfo
+	inmetrics, _ := metric.WithGroupTracking([]telegraf.Metric{
+		testutil.TestMetric(1, "foo"),
+		testutil.TestMetric(1, "dropme"),
+		testutil.TestMetric(1, "baz"),
+	}, func(info telegraf.DeliveryInfo) {
+		infos = append(infos, info)
+	})
+
+	rfp := NewTestRunningProcessor()
+	filteredMetrics := rfp.Apply(inmetrics...)
+	require.Len(t, filteredMetrics, 2)
+	assert.Equal(t, "fuz", filteredMetrics[0].Name())
+
+	// the group is not complete until the replacement of foo is written
+	filteredMetrics[1].Accept()
+	assert.Len(t, infos, 0)
+	filteredMetrics[0].Accept()
+	require.Len(t, infos, 1)
+	assert.True(t, infos[0].Delivered())
+}
@@ -0,0 +1,205 @@
+// retry is a package for limiting how often telegraf retries an operation
+// that keeps failing, such as writing to or connecting to an output. It
+// implements exponential backoff with jitter and a circuit breaker.
+package retry
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+// Possible values for the State enum.
+const (
+	// Closed lets every call through.
+	Closed State = iota
+	// HalfOpen lets a single trial call through after the backoff interval,
+	// and rejects the others until the result of the trial is recorded.
+	HalfOpen
+	// Open rejects every call until the backoff interval has elapsed.
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half-open"
+	case Open:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// Policy configures the backoff between attempts and when the circuit
+// breaker opens.
+type Policy struct {
+	// InitialInterval is the time to wait after the breaker opens for the
+	// first time.
+	InitialInterval time.Duration
+	// MaxInterval caps the time to wait between attempts.
+	MaxInterval time.Duration
+	// Multiplier is the factor the interval grows by every time the breaker
+	// opens again without a success in between.
+	Multiplier float64
+	// Jitter randomizes each interval by up to +/- this fraction of it.
+	Jitter float64
+	// Threshold is the number of consecutive failures after which the
+	// breaker opens. If 0, failures never open the breaker, only Trip does.
+	Threshold int
+}
+
+// Breaker is a circuit breaker with exponential backoff.
+// It is safe for concurrent use.
+type Breaker struct {
+	policy Policy
+
+	// OnStateChange, if set, is called on every state transition.
+	OnStateChange func(State)
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	interval  time.Duration
+	openUntil time.Time
+	rand      *rand.Rand
+
+	// trial is true while the trial call of a half-open breaker is in
+	// flight.
+	trial bool
+
+	now func() time.Time
+}
+
+// NewBreaker returns a closed Breaker using the given policy.
+func NewBreaker(p Policy) *Breaker {
+	if p.Multiplier < 1 {
+		p.Multiplier = 1
+	}
+	if p.MaxInterval < p.InitialInterval {
+		p.MaxInterval = p.InitialInterval
+	}
+	return &Breaker{
+		policy: p,
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:    time.Now,
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// NextAttempt returns the time after which an open breaker lets a call
+// through again.
+func (b *Breaker) NextAttempt() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.openUntil
+}
+
+// Allow returns true if a call may be attempted now. An open breaker whose
+// backoff interval has elapsed turns half-open and allows a single trial
+// call: further calls are rejected until the trial is recorded by Success or
+// Failure.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == Closed {
+		return true
+	}
+	if b.blocked() {
+		return false
+	}
+	b.setState(HalfOpen)
+	b.trial = true
+	return true
+}
+
+// Blocked returns true if the breaker is open and its backoff interval has
+// not elapsed yet, or if it is half-open with its trial call in flight, that
+// is if Allow would return false. Unlike Allow, it does not change the state
+// of the breaker.
+func (b *Breaker) Blocked() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.blocked()
+}
+
+func (b *Breaker) blocked() bool {
+	if b.state == HalfOpen {
+		return b.trial
+	}
+	return b.state == Open && b.now().Before(b.openUntil)
+}
+
+// Success records a successful call, closing the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.interval = 0
+	b.trial = false
+	b.setState(Closed)
+}
+
+// Failure records a failed call. The breaker opens when the trial call of a
+// half-open breaker fails, or when the failure threshold is reached.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == HalfOpen ||
+		(b.policy.Threshold > 0 && b.failures >= b.policy.Threshold) {
+		b.open()
+	}
+}
+
+// Trip records a failure that opens the breaker regardless of the threshold.
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.open()
+}
+
+func (b *Breaker) open() {
+	b.trial = false
+	if b.interval == 0 {
+		b.interval = b.policy.InitialInterval
+	} else {
+		b.interval = time.Duration(float64(b.interval) * b.policy.Multiplier)
+	}
+	if b.interval > b.policy.MaxInterval {
+		b.interval = b.policy.MaxInterval
+	}
+
+	wait := b.interval
+	if b.policy.Jitter > 0 {
+		delta := b.policy.Jitter * float64(wait)
+		wait += time.Duration(delta * (2*b.rand.Float64() - 1))
+	}
+	b.openUntil = b.now().Add(wait)
+	b.setState(Open)
+}
+
+func (b *Breaker) setState(s State) {
+	if b.state == s {
+		return
+	}
+	b.state = s
+	if b.OnStateChange != nil {
+		b.OnStateChange(s)
+	}
+}
@@ -0,0 +1,152 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestBreaker(p Policy) (*Breaker, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1000, 0)}
+	b := NewBreaker(p)
+	b.now = clock.now
+	return b, clock
+}
+
+func TestBreakerThreshold(t *testing.T) {
+	b, _ := newTestBreaker(Policy{
+		InitialInterval: time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2,
+		Threshold:       3,
+	})
+
+	b.Failure()
+	b.Failure()
+	assert.Equal(t, Closed, b.State())
+	assert.True(t, b.Allow())
+
+	b.Failure()
+	assert.Equal(t, Open, b.State())
+	assert.False(t, b.Allow())
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b, _ := newTestBreaker(Policy{
+		InitialInterval: time.Second,
+		Threshold:       2,
+	})
+
+	b.Failure()
+	b.Success()
+	b.Failure()
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerNoThreshold(t *testing.T) {
+	b, _ := newTestBreaker(Policy{InitialInterval: time.Second})
+
+	for i := 0; i < 100; i++ {
+		b.Failure()
+	}
+	assert.Equal(t, Closed, b.State())
+
+	b.Trip()
+	assert.Equal(t, Open, b.State())
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	b, clock := newTestBreaker(Policy{
+		InitialInterval: time.Second,
+		MaxInterval:     time.Minute,
+		Multiplier:      2,
+	})
+
+	b.Trip()
+	assert.True(t, b.Blocked())
+	assert.False(t, b.Allow())
+
+	clock.t = clock.t.Add(time.Second)
+	assert.False(t, b.Blocked())
+	assert.Equal(t, Open, b.State())
+	assert.True(t, b.Allow())
+	assert.Equal(t, HalfOpen, b.State())
+
+	// a single trial call is let through until its result is recorded
+	assert.True(t, b.Blocked())
+	assert.False(t, b.Allow())
+
+	// a failed trial call opens the breaker again
+	b.Failure()
+	assert.Equal(t, Open, b.State())
+
+	clock.t = clock.t.Add(2 * time.Second)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreakerBackoff(t *testing.T) {
+	b, clock := newTestBreaker(Policy{
+		InitialInterval: time.Second,
+		MaxInterval:     5 * time.Second,
+		Multiplier:      2,
+	})
+
+	expected := []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		5 * time.Second,
+		5 * time.Second,
+	}
+	for _, interval := range expected {
+		b.Trip()
+		assert.Equal(t, clock.t.Add(interval), b.NextAttempt())
+	}
+
+	b.Success()
+	b.Trip()
+	assert.Equal(t, clock.t.Add(time.Second), b.NextAttempt())
+}
+
+func TestBreakerJitter(t *testing.T) {
+	b, clock := newTestBreaker(Policy{
+		InitialInterval: 10 * time.Second,
+		MaxInterval:     10 * time.Second,
+		Jitter:          0.5,
+	})
+
+	for i := 0; i < 100; i++ {
+		b.Trip()
+		wait := b.NextAttempt().Sub(clock.t)
+		assert.True(t, wait >= 5*time.Second && wait <= 15*time.Second,
+			"wait %s is out of the jitter range", wait)
+	}
+}
+
+func TestBreakerOnStateChange(t *testing.T) {
+	b, clock := newTestBreaker(Policy{InitialInterval: time.Second})
+	var states []State
+	b.OnStateChange = func(s State) {
+		states = append(states, s)
+	}
+
+	b.Trip()
+	b.Trip()
+	clock.t = clock.t.Add(time.Minute)
+	b.Allow()
+	b.Success()
+	b.Success()
+
+	assert.Equal(t, []State{Open, HalfOpen, Closed}, states)
+}
@@ -0,0 +1,200 @@
+// Package schedule computes the times at which periodic tasks, such as the
+// gathers of an input, run.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule returns the times at which a task runs.
+type Schedule interface {
+	// Next returns the first time after t at which the task runs.
+	Next(t time.Time) time.Time
+}
+
+type every struct {
+	interval time.Duration
+	align    bool
+}
+
+// Every returns a schedule running every interval. If align is true, the runs
+// are aligned on multiples of the interval since the zero time, which puts
+// them on wall clock boundaries in UTC, ie. a 5m interval runs at :00, :05,
+// :10 and so on.
+func Every(interval time.Duration, align bool) Schedule {
+	return &every{interval: interval, align: align}
+}
+
+func (e *every) Next(t time.Time) time.Time {
+	if e.align {
+		return t.Truncate(e.interval).Add(e.interval)
+	}
+	return t.Add(e.interval)
+}
+
+// Cron is a schedule given by a cron expression.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are set when the day of month or the day of week
+	// is a *, in which case the days only need to match the other field.
+	domStar, dowStar bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard cron expression of five fields: minute, hour,
+// day of month, month and day of week. Fields are lists of values, ranges
+// such as 1-5 and steps such as */15 or 0-30/10. The descriptors @yearly,
+// @monthly, @weekly, @daily and @hourly are supported as well.
+func ParseCron(spec string) (*Cron, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := descriptors[spec]; ok {
+		spec = d
+	}
+
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields, got %d",
+			spec, len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		b, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %s", spec, err)
+		}
+		bits[i] = b
+	}
+
+	// 7 is Sunday as well
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &Cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: parts[2] == "*",
+		dowStar: parts[4] == "*",
+	}, nil
+}
+
+// parseField returns the bitset of the values matched by a field.
+func parseField(s string, f field) (uint64, error) {
+	max := f.max
+	if f.name == "day of week" {
+		max = 7
+	}
+
+	var bits uint64
+	for _, expr := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(expr, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(expr[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, expr)
+			}
+			expr = expr[:i]
+		}
+
+		lo, hi := f.min, max
+		switch {
+		case expr == "*":
+		case strings.Contains(expr, "-"):
+			bounds := strings.SplitN(expr, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, expr)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, expr)
+			}
+		default:
+			v, err := strconv.Atoi(expr)
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, expr)
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		if lo < f.min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, expr, f.min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Next returns the first time after t matched by the cron expression, in the
+// location of t. It returns the zero time if there is none within five
+// years, as for the 30th of February.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows cron: when both the day of month and the day of week
+// are restricted, a day matches if either does.
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
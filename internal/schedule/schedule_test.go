@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04:05", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestEvery(t *testing.T) {
+	s := Every(5*time.Minute, false)
+	assert.Equal(t, date("2018-03-01 10:08:30"), s.Next(date("2018-03-01 10:03:30")))
+
+	s = Every(5*time.Minute, true)
+	assert.Equal(t, date("2018-03-01 10:05:00"), s.Next(date("2018-03-01 10:03:30")))
+	assert.Equal(t, date("2018-03-01 10:10:00"), s.Next(date("2018-03-01 10:05:00")))
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		next string
+	}{
+		{"* * * * *", "2018-03-01 10:03:30", "2018-03-01 10:04:00"},
+		{"*/15 * * * *", "2018-03-01 10:03:30", "2018-03-01 10:15:00"},
+		{"*/15 * * * *", "2018-03-01 10:45:00", "2018-03-01 11:00:00"},
+		{"5,35 8-9 * * *", "2018-03-01 09:40:00", "2018-03-02 08:05:00"},
+		{"0 0 1 * *", "2018-03-01 10:00:00", "2018-04-01 00:00:00"},
+		{"@hourly", "2018-12-31 23:30:00", "2019-01-01 00:00:00"},
+		// 2018-03-03 is a Saturday
+		{"30 6 * * 1-5", "2018-03-02 07:00:00", "2018-03-05 06:30:00"},
+		{"0 0 * * 7", "2018-03-01 00:00:00", "2018-03-04 00:00:00"},
+		// either the day of month or the day of week
+		{"0 0 15 * 1", "2018-03-01 00:00:00", "2018-03-05 00:00:00"},
+		{"0 0 29 2 *", "2018-03-01 00:00:00", "2020-02-29 00:00:00"},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		require.NoError(t, err, tt.spec)
+		assert.Equal(t, date(tt.next), c.Next(date(tt.from)), tt.spec)
+	}
+}
+
+func TestCronNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	require.NoError(t, err)
+	assert.True(t, c.Next(date("2018-03-01 00:00:00")).IsZero())
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		_, err := ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
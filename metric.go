@@ -8,11 +8,20 @@ import (
 type ValueType int
 
 // Possible values for the ValueType enum.
+//
+// Summary and Histogram metrics carry their structure in their fields: the
+// "sum" and "count" of the observations, and a field per quantile or per
+// bucket, named after the quantile or the upper bound of the bucket (e.g.
+// "0.99", "+Inf"). The buckets hold the cumulative counts of the observations
+// lower than or equal to their bound. See metric.NewSummary and
+// metric.NewHistogram.
 const (
 	_ ValueType = iota
 	Counter
 	Gauge
 	Untyped
+	Summary
+	Histogram
 )
 
 type Metric interface {
@@ -59,4 +68,15 @@ type Metric interface {
 	// aggregator things:
 	SetAggregate(bool)
 	IsAggregate() bool
+
+	// delivery tracking things, see TrackingAccumulator. These are no-ops
+	// for metrics that are not tracked.
+	//
+	// Accept marks the metric as written by an output.
+	Accept()
+	// Reject marks the metric as not deliverable by an output.
+	Reject()
+	// Drop marks the metric as consumed without being written, for instance
+	// by a filter or an aggregator.
+	Drop()
 }
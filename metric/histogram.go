@@ -0,0 +1,163 @@
+package metric
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// The fields of the sum and count of the observations of summary and
+// histogram metrics.
+const (
+	SumField   = "sum"
+	CountField = "count"
+)
+
+// Bucket is a bucket of a histogram, counting the observations lower than or
+// equal to its upper bound.
+type Bucket struct {
+	UpperBound float64
+	Count      uint64
+}
+
+// Quantile is a quantile of a summary.
+type Quantile struct {
+	Quantile float64
+	Value    float64
+}
+
+// NewHistogram returns a Histogram metric holding the given buckets, and the
+// count and sum of the observations.
+func NewHistogram(
+	name string,
+	tags map[string]string,
+	buckets []Bucket,
+	count uint64,
+	sum float64,
+	t time.Time,
+) (telegraf.Metric, error) {
+	fields := make(map[string]interface{}, len(buckets)+2)
+	for _, b := range buckets {
+		fields[formatKey(b.UpperBound)] = float64(b.Count)
+	}
+	fields[CountField] = float64(count)
+	fields[SumField] = sum
+	return New(name, tags, fields, t, telegraf.Histogram)
+}
+
+// NewSummary returns a Summary metric holding the given quantiles, and the
+// count and sum of the observations.
+func NewSummary(
+	name string,
+	tags map[string]string,
+	quantiles []Quantile,
+	count uint64,
+	sum float64,
+	t time.Time,
+) (telegraf.Metric, error) {
+	fields := make(map[string]interface{}, len(quantiles)+2)
+	for _, q := range quantiles {
+		fields[formatKey(q.Quantile)] = q.Value
+	}
+	fields[CountField] = float64(count)
+	fields[SumField] = sum
+	return New(name, tags, fields, t, telegraf.Summary)
+}
+
+// GetHistogram returns the buckets of a Histogram metric, sorted by upper
+// bound, and the count and sum of its observations. Fields which are not
+// named after a bound are ignored.
+func GetHistogram(m telegraf.Metric) ([]Bucket, uint64, float64, error) {
+	if m.Type() != telegraf.Histogram {
+		return nil, 0, 0, fmt.Errorf("%s is not a histogram", m.Name())
+	}
+	values, count, sum, err := structure(m)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	buckets := make([]Bucket, 0, len(values))
+	for bound, v := range values {
+		if v < 0 {
+			return nil, 0, 0, fmt.Errorf("%s: negative count of bucket %v",
+				m.Name(), bound)
+		}
+		buckets = append(buckets, Bucket{UpperBound: bound, Count: uint64(v)})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].UpperBound < buckets[j].UpperBound
+	})
+	return buckets, count, sum, nil
+}
+
+// GetSummary returns the quantiles of a Summary metric, sorted, and the count
+// and sum of its observations. Fields which are not named after a quantile
+// are ignored.
+func GetSummary(m telegraf.Metric) ([]Quantile, uint64, float64, error) {
+	if m.Type() != telegraf.Summary {
+		return nil, 0, 0, fmt.Errorf("%s is not a summary", m.Name())
+	}
+	values, count, sum, err := structure(m)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	quantiles := make([]Quantile, 0, len(values))
+	for q, v := range values {
+		quantiles = append(quantiles, Quantile{Quantile: q, Value: v})
+	}
+	sort.Slice(quantiles, func(i, j int) bool {
+		return quantiles[i].Quantile < quantiles[j].Quantile
+	})
+	return quantiles, count, sum, nil
+}
+
+// structure returns the numeric fields of a summary or histogram metric keyed
+// by quantile or bound, and its count and sum.
+func structure(m telegraf.Metric) (map[float64]float64, uint64, float64, error) {
+	var count uint64
+	var sum float64
+	var hasCount, hasSum bool
+	values := make(map[float64]float64)
+	for k, v := range m.Fields() {
+		fv, ok := toFloat(v)
+		if !ok {
+			continue
+		}
+		switch k {
+		case CountField:
+			if fv < 0 {
+				return nil, 0, 0, fmt.Errorf("%s: negative count", m.Name())
+			}
+			count, hasCount = uint64(fv), true
+		case SumField:
+			sum, hasSum = fv, true
+		default:
+			if key, err := strconv.ParseFloat(k, 64); err == nil {
+				values[key] = fv
+			}
+		}
+	}
+	if !hasCount || !hasSum {
+		return nil, 0, 0, fmt.Errorf("%s: no %s or %s field",
+			m.Name(), CountField, SumField)
+	}
+	return values, count, sum, nil
+}
+
+func formatKey(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
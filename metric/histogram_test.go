@@ -0,0 +1,64 @@
+package metric
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogram(t *testing.T) {
+	now := time.Now()
+	buckets := []Bucket{
+		{UpperBound: 0.1, Count: 2},
+		{UpperBound: 0.5, Count: 5},
+		{UpperBound: math.Inf(1), Count: 6},
+	}
+	m, err := NewHistogram("rpc_duration_seconds",
+		map[string]string{"service": "api"}, buckets, 6, 1.5, now)
+	require.NoError(t, err)
+	assert.Equal(t, telegraf.Histogram, m.Type())
+	assert.Equal(t, map[string]interface{}{
+		"0.1":   float64(2),
+		"0.5":   float64(5),
+		"+Inf":  float64(6),
+		"count": float64(6),
+		"sum":   float64(1.5),
+	}, m.Fields())
+
+	// the structure is kept by copies
+	b, count, sum, err := GetHistogram(m.Copy())
+	require.NoError(t, err)
+	assert.Equal(t, buckets, b)
+	assert.Equal(t, uint64(6), count)
+	assert.Equal(t, float64(1.5), sum)
+
+	_, _, _, err = GetSummary(m)
+	assert.Error(t, err)
+}
+
+func TestSummary(t *testing.T) {
+	quantiles := []Quantile{
+		{Quantile: 0.5, Value: 0.2},
+		{Quantile: 0.99, Value: 0.9},
+	}
+	m, err := NewSummary("rpc_duration_seconds", nil, quantiles, 10, 3, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, telegraf.Summary, m.Type())
+
+	// fields added by processors are ignored
+	m.AddField("team", "api")
+	q, count, sum, err := GetSummary(m)
+	require.NoError(t, err)
+	assert.Equal(t, quantiles, q)
+	assert.Equal(t, uint64(10), count)
+	assert.Equal(t, float64(3), sum)
+
+	require.NoError(t, m.RemoveField("sum"))
+	_, _, _, err = GetSummary(m)
+	assert.Error(t, err)
+}
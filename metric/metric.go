@@ -151,6 +151,11 @@ func (m *metric) IsAggregate() bool {
 	return m.aggregate
 }
 
+// Accept, Reject and Drop are no-ops, a plain metric is not tracked.
+func (m *metric) Accept() {}
+func (m *metric) Reject() {}
+func (m *metric) Drop()   {}
+
 func (m *metric) Type() telegraf.ValueType {
 	return m.mType
 }
@@ -233,7 +238,7 @@ func (m *metric) Split(maxSize int) []telegraf.Metric {
 		if i >= len(m.fields) {
 			// hit the end of the field byte slice
 			if len(fields) > 0 {
-				out = append(out, copyWith(m.name, m.tags, fields, m.t))
+				out = append(out, copyWith(m.name, m.tags, fields, m.t, m.mType))
 			}
 			break
 		}
@@ -253,7 +258,7 @@ func (m *metric) Split(maxSize int) []telegraf.Metric {
 			// selected field anyways. This means that the given maxSize is too
 			// small for a single field to fit.
 			if len(fields) > 0 {
-				out = append(out, copyWith(m.name, m.tags, fields, m.t))
+				out = append(out, copyWith(m.name, m.tags, fields, m.t, m.mType))
 			}
 
 			fields = make([]byte, 0, maxSize)
@@ -409,17 +414,13 @@ func (m *metric) AddTag(key, value string) {
 }
 
 func (m *metric) HasTag(key string) bool {
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	return indexKey(m.tags, key) != -1
 }
 
 func (m *metric) RemoveTag(key string) {
 	m.hashID = 0
 
-	i := bytes.Index(m.tags, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.tags, key)
 	if i == -1 {
 		return
 	}
@@ -439,26 +440,26 @@ func (m *metric) AddField(key string, value interface{}) {
 }
 
 func (m *metric) HasField(key string) bool {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
-	if i == -1 {
-		return false
-	}
-	return true
+	return indexKey(m.fields, key) != -1
 }
 
 func (m *metric) RemoveField(key string) error {
-	i := bytes.Index(m.fields, []byte(escape(key, "tagkey")+"="))
+	i := indexKey(m.fields, key)
 	if i == -1 {
 		return nil
 	}
 
 	var tmp []byte
-	if i != 0 {
-		tmp = m.fields[0 : i-1]
-	}
 	j := indexUnescapedByte(m.fields[i:], ',')
-	if j != -1 {
-		tmp = append(tmp, m.fields[i+j:]...)
+	switch {
+	case i != 0:
+		tmp = m.fields[0 : i-1]
+		if j != -1 {
+			tmp = append(tmp, m.fields[i+j:]...)
+		}
+	case j != -1:
+		// the first field, the next one starts the fields
+		tmp = m.fields[j+1:]
 	}
 
 	if len(tmp) == 0 {
@@ -469,16 +470,48 @@ func (m *metric) RemoveField(key string) error {
 	return nil
 }
 
+// SetField sets a field of m, replacing its value if it exists. The new value
+// is added before the old one is removed, since the last field of a metric
+// cannot be removed.
+func SetField(m telegraf.Metric, key string, value interface{}) {
+	exists := m.HasField(key)
+	m.AddField(key, value)
+	if exists {
+		// removes the first field of the key, ie. the old value
+		m.RemoveField(key)
+	}
+}
+
+// indexKey returns the index of the given key in the serialized tags or
+// fields, or -1. The key must start the fields or follow an unescaped comma,
+// so that it does not match the end of another key.
+func indexKey(b []byte, key string) int {
+	k := []byte(escape(key, "tagkey") + "=")
+	for i := 0; i < len(b); {
+		j := bytes.Index(b[i:], k)
+		if j == -1 {
+			return -1
+		}
+		j += i
+		if j == 0 || (b[j-1] == ',' && (j < 2 || b[j-2] != '\\')) {
+			return j
+		}
+		i = j + 1
+	}
+	return -1
+}
+
 func (m *metric) Copy() telegraf.Metric {
-	return copyWith(m.name, m.tags, m.fields, m.t)
+	return copyWith(m.name, m.tags, m.fields, m.t, m.mType)
 }
 
-func copyWith(name, tags, fields, t []byte) telegraf.Metric {
+func copyWith(name, tags, fields, t []byte, mType telegraf.ValueType) telegraf.Metric {
 	out := metric{
 		name:   make([]byte, len(name)),
 		tags:   make([]byte, len(tags)),
 		fields: make([]byte, len(fields)),
 		t:      make([]byte, len(t)),
+		mType:  mType,
 	}
 	copy(out.name, name)
 	copy(out.tags, tags)
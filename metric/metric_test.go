@@ -101,6 +101,46 @@ func TestNewMetric_Tags(t *testing.T) {
 	assert.Equal(t, "cpu value=1 "+fmt.Sprint(now.UnixNano())+"\n", m.String())
 }
 
+// Test keys which are the end of other keys.
+func TestNewMetric_KeySuffix(t *testing.T) {
+	now := time.Now()
+	tags := map[string]string{
+		"myhost": "localhost",
+	}
+	fields := map[string]interface{}{
+		"usage_idle": float64(1),
+		"idle":       float64(2),
+	}
+	m, err := New("cpu", tags, fields, now)
+	assert.NoError(t, err)
+
+	assert.False(t, m.HasTag("host"))
+	m.RemoveTag("host")
+	assert.Equal(t, tags, m.Tags())
+
+	assert.NoError(t, m.RemoveField("idle"))
+	assert.False(t, m.HasField("idle"))
+	assert.Equal(t, map[string]interface{}{"usage_idle": float64(1)}, m.Fields())
+}
+
+func TestRemoveFirstField(t *testing.T) {
+	m, err := New("cpu", nil, map[string]interface{}{"a": int64(1)}, time.Now())
+	assert.NoError(t, err)
+	m.AddField("b", int64(2))
+
+	assert.NoError(t, m.RemoveField("a"))
+	assert.Equal(t, map[string]interface{}{"b": int64(2)}, m.Fields())
+}
+
+func TestSetField(t *testing.T) {
+	m, err := New("cpu", nil, map[string]interface{}{"a": int64(1)}, time.Now())
+	assert.NoError(t, err)
+
+	SetField(m, "a", "one")
+	SetField(m, "b", int64(2))
+	assert.Equal(t, map[string]interface{}{"a": "one", "b": int64(2)}, m.Fields())
+}
+
 func TestSerialize(t *testing.T) {
 	now := time.Now()
 	tags := map[string]string{
@@ -0,0 +1,143 @@
+package metric
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/influxdata/telegraf"
+)
+
+// NotifyFunc is called once all the metrics of a tracked group have been
+// accepted, rejected or dropped.
+type NotifyFunc func(telegraf.DeliveryInfo)
+
+var lastTrackingID uint64
+
+func newTrackingID() telegraf.TrackingID {
+	return telegraf.TrackingID(atomic.AddUint64(&lastTrackingID, 1))
+}
+
+type trackingData struct {
+	id       telegraf.TrackingID
+	refCount int32
+	rejected int32
+	notify   NotifyFunc
+	once     sync.Once
+}
+
+func (d *trackingData) incr() {
+	atomic.AddInt32(&d.refCount, 1)
+}
+
+func (d *trackingData) decr() {
+	if atomic.AddInt32(&d.refCount, -1) == 0 {
+		d.once.Do(func() {
+			d.notify(&deliveryInfo{
+				id:        d.id,
+				delivered: atomic.LoadInt32(&d.rejected) == 0,
+			})
+		})
+	}
+}
+
+type deliveryInfo struct {
+	id        telegraf.TrackingID
+	delivered bool
+}
+
+func (r *deliveryInfo) ID() telegraf.TrackingID {
+	return r.id
+}
+
+func (r *deliveryInfo) Delivered() bool {
+	return r.delivered
+}
+
+// trackingMetric is a metric taking part in a tracked group. Every copy of
+// it holds a reference on the group, which is released by Accept, Reject or
+// Drop.
+type trackingMetric struct {
+	telegraf.Metric
+	d *trackingData
+	// released guards against a copy being released more than once.
+	released int32
+}
+
+// WithGroupTracking wraps the metrics of a group so that notify is called
+// once every metric, and every copy of it, has been accepted, rejected or
+// dropped. An empty group is reported as delivered immediately.
+func WithGroupTracking(group []telegraf.Metric, notify NotifyFunc) ([]telegraf.Metric, telegraf.TrackingID) {
+	d := &trackingData{
+		id:       newTrackingID(),
+		refCount: int32(len(group)),
+		notify:   notify,
+	}
+
+	if len(group) == 0 {
+		notify(&deliveryInfo{id: d.id, delivered: true})
+		return group, d.id
+	}
+
+	out := make([]telegraf.Metric, 0, len(group))
+	for _, m := range group {
+		out = append(out, &trackingMetric{Metric: m, d: d})
+	}
+	return out, d.id
+}
+
+// WithTrackingOf hands the tracking of m over to the metrics replacing it, as
+// when a processor returns new metrics in place of m: the tracked group of m
+// then completes once the replacements are accepted, rejected or dropped,
+// rather than once m is. m is released and the replacements are returned,
+// wrapped if m is tracked.
+func WithTrackingOf(m telegraf.Metric, replacements []telegraf.Metric) []telegraf.Metric {
+	t, ok := m.(*trackingMetric)
+	if !ok {
+		m.Drop()
+		return replacements
+	}
+
+	out := make([]telegraf.Metric, 0, len(replacements))
+	for _, r := range replacements {
+		if rt, ok := r.(*trackingMetric); ok && rt.d == t.d {
+			// copies of m already hold a reference on the group
+			out = append(out, r)
+			continue
+		}
+		t.d.incr()
+		out = append(out, &trackingMetric{Metric: r, d: t.d})
+	}
+	t.Drop()
+	return out
+}
+
+func (m *trackingMetric) Copy() telegraf.Metric {
+	m.d.incr()
+	return &trackingMetric{Metric: m.Metric.Copy(), d: m.d}
+}
+
+// Accept, Reject and Drop are passed on to the wrapped metric, which may be
+// tracked itself when a processor replaced a metric by a tracked one.
+func (m *trackingMetric) Accept() {
+	m.release()
+	m.Metric.Accept()
+}
+
+func (m *trackingMetric) Reject() {
+	if atomic.LoadInt32(&m.released) == 0 {
+		atomic.AddInt32(&m.d.rejected, 1)
+	}
+	m.release()
+	m.Metric.Reject()
+}
+
+func (m *trackingMetric) Drop() {
+	m.release()
+	m.Metric.Drop()
+}
+
+func (m *trackingMetric) release() {
+	if atomic.CompareAndSwapInt32(&m.released, 0, 1) {
+		m.d.decr()
+	}
+}
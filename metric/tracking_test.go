@@ -0,0 +1,139 @@
+package metric
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type deliveries struct {
+	infos []telegraf.DeliveryInfo
+}
+
+func (d *deliveries) notify(info telegraf.DeliveryInfo) {
+	d.infos = append(d.infos, info)
+}
+
+func trackingGroup(t *testing.T, n int) []telegraf.Metric {
+	group := make([]telegraf.Metric, 0, n)
+	for i := 0; i < n; i++ {
+		m, err := New("cpu",
+			map[string]string{},
+			map[string]interface{}{"value": int64(i)},
+			time.Unix(0, 0),
+		)
+		require.NoError(t, err)
+		group = append(group, m)
+	}
+	return group
+}
+
+func TestTrackingAccept(t *testing.T) {
+	d := &deliveries{}
+	group, id := WithGroupTracking(trackingGroup(t, 2), d.notify)
+
+	group[0].Accept()
+	assert.Len(t, d.infos, 0)
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingReject(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(trackingGroup(t, 2), d.notify)
+
+	group[0].Reject()
+	group[1].Accept()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingDropIsDelivered(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(trackingGroup(t, 1), d.notify)
+
+	group[0].Drop()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingCopy(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(trackingGroup(t, 1), d.notify)
+
+	c := group[0].Copy()
+	group[0].Accept()
+	assert.Len(t, d.infos, 0)
+
+	// releasing a metric twice does not count twice
+	group[0].Accept()
+	assert.Len(t, d.infos, 0)
+
+	c.Accept()
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingEmptyGroup(t *testing.T) {
+	d := &deliveries{}
+	group, id := WithGroupTracking(nil, d.notify)
+
+	assert.Len(t, group, 0)
+	require.Len(t, d.infos, 1)
+	assert.Equal(t, id, d.infos[0].ID())
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingIDsAreUnique(t *testing.T) {
+	d := &deliveries{}
+	_, id1 := WithGroupTracking(trackingGroup(t, 1), d.notify)
+	_, id2 := WithGroupTracking(trackingGroup(t, 1), d.notify)
+	assert.NotEqual(t, id1, id2)
+}
+
+func TestTrackingReplaced(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(trackingGroup(t, 1), d.notify)
+
+	replacements := WithTrackingOf(group[0], trackingGroup(t, 2))
+	require.Len(t, replacements, 2)
+	assert.Len(t, d.infos, 0)
+
+	replacements[0].Accept()
+	assert.Len(t, d.infos, 0)
+	replacements[1].Reject()
+	require.Len(t, d.infos, 1)
+	assert.False(t, d.infos[0].Delivered())
+}
+
+func TestTrackingReplacedByNothing(t *testing.T) {
+	d := &deliveries{}
+	group, _ := WithGroupTracking(trackingGroup(t, 1), d.notify)
+
+	replacements := WithTrackingOf(group[0], nil)
+	assert.Len(t, replacements, 0)
+	require.Len(t, d.infos, 1)
+	assert.True(t, d.infos[0].Delivered())
+}
+
+func TestTrackingReplacedByTracked(t *testing.T) {
+	d1 := &deliveries{}
+	group1, _ := WithGroupTracking(trackingGroup(t, 1), d1.notify)
+	d2 := &deliveries{}
+	group2, _ := WithGroupTracking(trackingGroup(t, 1), d2.notify)
+
+	replacements := WithTrackingOf(group1[0], group2)
+	require.Len(t, replacements, 1)
+
+	replacements[0].Accept()
+	require.Len(t, d1.infos, 1)
+	assert.True(t, d1.infos[0].Delivered())
+	require.Len(t, d2.infos, 1)
+	assert.True(t, d2.infos[0].Delivered())
+}
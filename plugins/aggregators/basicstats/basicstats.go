@@ -0,0 +1,286 @@
+package basicstats
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	statCount = 1 << iota
+	statSum
+	statMin
+	statMax
+	statMean
+	statS2
+	statStdev
+	statFirst
+	statLast
+	statPercentiles
+)
+
+var statNames = map[string]int{
+	"count":       statCount,
+	"sum":         statSum,
+	"min":         statMin,
+	"max":         statMax,
+	"mean":        statMean,
+	"s2":          statS2,
+	"stdev":       statStdev,
+	"first":       statFirst,
+	"last":        statLast,
+	"percentiles": statPercentiles,
+}
+
+var defaultStats = []string{"count", "min", "max", "mean", "s2", "stdev"}
+
+var defaultPercentiles = []float64{50, 90, 99}
+
+type BasicStats struct {
+	Stats       []string
+	Percentiles []float64
+	Fields      map[string][]string
+
+	// stats and fieldStats are the bitsets of the statistics computed for
+	// every field and for specific fields, built on the first Add.
+	configured  bool
+	stats       int
+	fieldStats  map[string]int
+	percentiles []percentile
+
+	cache map[uint64]aggregate
+}
+
+type percentile struct {
+	suffix string
+	q      float64
+}
+
+func NewBasicStats() *BasicStats {
+	b := &BasicStats{
+		Stats:       defaultStats,
+		Percentiles: defaultPercentiles,
+	}
+	b.Reset()
+	return b
+}
+
+type aggregate struct {
+	fields map[string]*stats
+	name   string
+	tags   map[string]string
+}
+
+type stats struct {
+	stats int
+
+	count       int64
+	sum         float64
+	min, max    float64
+	mean, m2    float64
+	first, last float64
+	digest      *tdigest
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Statistics computed for each field, emitted as <field>_<statistic>.
+  ## Supported statistics are count, sum, min, max, mean, s2 (the sample
+  ## variance), stdev, first, last and percentiles.
+  # stats = ["count", "min", "max", "mean", "s2", "stdev"]
+
+  ## Percentiles estimated by the percentiles statistic, emitted as
+  ## <field>_p<percentile>, ie. field_p99 or field_p99_9.
+  # percentiles = [50.0, 90.0, 99.0]
+
+  ## Statistics computed for specific fields instead of stats.
+  # [aggregators.basicstats.fields]
+  #   usage_idle = ["mean", "percentiles"]
+`
+
+func (b *BasicStats) SampleConfig() string {
+	return sampleConfig
+}
+
+func (b *BasicStats) Description() string {
+	return "Keep the aggregate basic statistics of each metric passing through."
+}
+
+// configure builds the bitsets of the statistics to compute. Unknown
+// statistics and invalid percentiles are logged and ignored.
+func (b *BasicStats) configure() {
+	b.configured = true
+	b.stats = parseStats(b.Stats)
+	b.fieldStats = make(map[string]int, len(b.Fields))
+	for field, names := range b.Fields {
+		b.fieldStats[field] = parseStats(names)
+	}
+
+	b.percentiles = nil
+	for _, p := range b.Percentiles {
+		if p <= 0 || p > 100 {
+			log.Printf("E! basicstats: percentile %v out of range (0, 100]", p)
+			continue
+		}
+		suffix := strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", -1)
+		b.percentiles = append(b.percentiles, percentile{
+			suffix: "_p" + suffix,
+			q:      p / 100,
+		})
+	}
+}
+
+func parseStats(names []string) int {
+	var bits int
+	for _, name := range names {
+		stat, ok := statNames[name]
+		if !ok {
+			log.Printf("E! basicstats: unknown statistic %q", name)
+			continue
+		}
+		bits |= stat
+	}
+	return bits
+}
+
+func (b *BasicStats) Add(in telegraf.Metric) {
+	if !b.configured {
+		b.configure()
+	}
+
+	id := in.HashID()
+	a, ok := b.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*stats),
+		}
+		b.cache[id] = a
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		s, ok := a.fields[k]
+		if !ok {
+			// hit an uncached field of a cached metric
+			s = b.newStats(k)
+			a.fields[k] = s
+		}
+		s.add(fv)
+	}
+}
+
+func (b *BasicStats) newStats(field string) *stats {
+	s := &stats{stats: b.stats}
+	if bits, ok := b.fieldStats[field]; ok {
+		s.stats = bits
+	}
+	if s.stats&statPercentiles != 0 && len(b.percentiles) > 0 {
+		s.digest = newTDigest(defaultCompression)
+	}
+	return s
+}
+
+// add adds a value to the statistics, computing the variance with Welford's
+// algorithm.
+func (s *stats) add(v float64) {
+	s.count++
+	if s.count == 1 {
+		s.min, s.max, s.first = v, v, v
+	}
+	s.min = math.Min(s.min, v)
+	s.max = math.Max(s.max, v)
+	s.sum += v
+	s.last = v
+
+	delta := v - s.mean
+	s.mean += delta / float64(s.count)
+	s.m2 += delta * (v - s.mean)
+
+	if s.digest != nil {
+		s.digest.Add(v)
+	}
+}
+
+func (b *BasicStats) Push(acc telegraf.Accumulator) {
+	for _, aggregate := range b.cache {
+		fields := map[string]interface{}{}
+		for k, s := range aggregate.fields {
+			if s.stats&statCount != 0 {
+				fields[k+"_count"] = s.count
+			}
+			if s.stats&statSum != 0 {
+				fields[k+"_sum"] = s.sum
+			}
+			if s.stats&statMin != 0 {
+				fields[k+"_min"] = s.min
+			}
+			if s.stats&statMax != 0 {
+				fields[k+"_max"] = s.max
+			}
+			if s.stats&statMean != 0 {
+				fields[k+"_mean"] = s.mean
+			}
+			if s.stats&statFirst != 0 {
+				fields[k+"_first"] = s.first
+			}
+			if s.stats&statLast != 0 {
+				fields[k+"_last"] = s.last
+			}
+			// the sample variance needs two values at least
+			if s.count > 1 {
+				variance := s.m2 / float64(s.count-1)
+				if s.stats&statS2 != 0 {
+					fields[k+"_s2"] = variance
+				}
+				if s.stats&statStdev != 0 {
+					fields[k+"_stdev"] = math.Sqrt(variance)
+				}
+			}
+			if s.digest != nil {
+				for _, p := range b.percentiles {
+					fields[k+p.suffix] = s.digest.Quantile(p.q)
+				}
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddFields(aggregate.name, fields, aggregate.tags)
+		}
+	}
+}
+
+func (b *BasicStats) Reset() {
+	b.cache = make(map[uint64]aggregate)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("basicstats", func() telegraf.Aggregator {
+		return NewBasicStats()
+	})
+}
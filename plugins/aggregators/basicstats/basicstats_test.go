@@ -0,0 +1,147 @@
+package basicstats
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var m1, _ = metric.New("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a": int64(1),
+		"b": float64(2),
+		"c": int64(4),
+	},
+	time.Now(),
+)
+var m2, _ = metric.New("m1",
+	map[string]string{"foo": "bar"},
+	map[string]interface{}{
+		"a":        int64(3),
+		"b":        float64(6),
+		"ignoreme": "string",
+		"andme":    true,
+	},
+	time.Now(),
+)
+
+func BenchmarkApply(b *testing.B) {
+	bs := NewBasicStats()
+
+	for n := 0; n < b.N; n++ {
+		bs.Add(m1)
+		bs.Add(m2)
+	}
+}
+
+// Test two metrics getting added with the default statistics.
+func TestBasicStatsWithPeriod(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+
+	bs.Add(m1)
+	bs.Add(m2)
+	bs.Push(&acc)
+
+	expectedFields := map[string]interface{}{
+		"a_count": int64(2),
+		"a_min":   float64(1),
+		"a_max":   float64(3),
+		"a_mean":  float64(2),
+		"a_s2":    float64(2),
+		"a_stdev": math.Sqrt(2),
+		"b_count": int64(2),
+		"b_min":   float64(2),
+		"b_max":   float64(6),
+		"b_mean":  float64(4),
+		"b_s2":    float64(8),
+		"b_stdev": math.Sqrt(8),
+		"c_count": int64(1),
+		"c_min":   float64(4),
+		"c_max":   float64(4),
+		"c_mean":  float64(4),
+	}
+	expectedTags := map[string]string{
+		"foo": "bar",
+	}
+	acc.AssertContainsTaggedFields(t, "m1", expectedFields, expectedTags)
+}
+
+// Test two metrics getting added with a push/reset in between (simulates
+// getting added in different periods.)
+func TestBasicStatsDifferentPeriods(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	bs.Stats = []string{"count", "sum"}
+
+	bs.Add(m1)
+	bs.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1", map[string]interface{}{
+		"a_count": int64(1),
+		"a_sum":   float64(1),
+		"b_count": int64(1),
+		"b_sum":   float64(2),
+		"c_count": int64(1),
+		"c_sum":   float64(4),
+	}, map[string]string{"foo": "bar"})
+
+	acc.ClearMetrics()
+	bs.Reset()
+	bs.Add(m2)
+	bs.Push(&acc)
+	acc.AssertContainsTaggedFields(t, "m1", map[string]interface{}{
+		"a_count": int64(1),
+		"a_sum":   float64(3),
+		"b_count": int64(1),
+		"b_sum":   float64(6),
+	}, map[string]string{"foo": "bar"})
+}
+
+func TestBasicStatsFieldStats(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	bs.Stats = []string{"first", "last", "unknown"}
+	bs.Fields = map[string][]string{
+		"b": {"sum"},
+		"c": {},
+	}
+
+	bs.Add(m1)
+	bs.Add(m2)
+	bs.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "m1", map[string]interface{}{
+		"a_first": float64(1),
+		"a_last":  float64(3),
+		"b_sum":   float64(8),
+	}, map[string]string{"foo": "bar"})
+}
+
+func TestBasicStatsPercentiles(t *testing.T) {
+	acc := testutil.Accumulator{}
+	bs := NewBasicStats()
+	bs.Stats = []string{"percentiles"}
+	bs.Percentiles = []float64{50, 99.9, 150}
+
+	for i := 1; i <= 1000; i++ {
+		m, _ := metric.New("m1",
+			map[string]string{"foo": "bar"},
+			map[string]interface{}{"a": int64(i)},
+			time.Now(),
+		)
+		bs.Add(m)
+	}
+	bs.Push(&acc)
+
+	m, ok := acc.Get("m1")
+	assert.True(t, ok)
+	assert.Len(t, m.Fields, 2)
+	assert.InDelta(t, 500, m.Fields["a_p50"], 5)
+	assert.InDelta(t, 999, m.Fields["a_p99_9"], 1)
+}
@@ -0,0 +1,123 @@
+package basicstats
+
+import (
+	"math"
+	"sort"
+)
+
+// defaultCompression bounds the number of centroids of a digest to about
+// its value, keeping the percentiles within a fraction of a percent.
+const defaultCompression = 100
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+type centroids []centroid
+
+func (c centroids) Len() int           { return len(c) }
+func (c centroids) Less(i, j int) bool { return c[i].mean < c[j].mean }
+func (c centroids) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+
+// tdigest is a merging t-digest, a sketch of the distribution of a stream of
+// values from which percentiles are estimated in bounded memory. The values
+// are buffered and merged into the centroids once the buffer is full, the
+// centroids near the extremes being kept smaller, so that the tails of the
+// distribution are the most accurate.
+type tdigest struct {
+	compression float64
+	merged      centroids
+	unmerged    centroids
+	count       float64
+	min, max    float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add adds a value to the digest.
+func (t *tdigest) Add(v float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	t.unmerged = append(t.unmerged, centroid{mean: v, count: 1})
+	t.count++
+	t.min = math.Min(t.min, v)
+	t.max = math.Max(t.max, v)
+	if len(t.unmerged) >= int(8*t.compression) {
+		t.process()
+	}
+}
+
+// process merges the buffered values into the centroids.
+func (t *tdigest) process() {
+	if len(t.unmerged) == 0 {
+		return
+	}
+
+	all := append(t.merged, t.unmerged...)
+	sort.Sort(all)
+
+	merged := make(centroids, 0, len(t.merged)+1)
+	merged = append(merged, all[0])
+	var before float64
+	for _, c := range all[1:] {
+		cur := &merged[len(merged)-1]
+		if t.scale((before+cur.count+c.count)/t.count)-t.scale(before/t.count) <= 1 {
+			cur.count += c.count
+			cur.mean += (c.mean - cur.mean) * c.count / cur.count
+			continue
+		}
+		before += cur.count
+		merged = append(merged, c)
+	}
+
+	t.merged = merged
+	t.unmerged = t.unmerged[:0]
+}
+
+// scale maps quantiles to the k-scale of the digest, over which each centroid
+// spans one unit at most. Its slope is steepest near 0 and 1, keeping the
+// centroids small at the tails.
+func (t *tdigest) scale(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+// Quantile returns the estimated value at quantile q, between 0 and 1, of the
+// values added to the digest, or NaN if there are none.
+func (t *tdigest) Quantile(q float64) float64 {
+	t.process()
+	if t.count == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return t.min
+	}
+	if q >= 1 {
+		return t.max
+	}
+
+	// interpolate between the centers of the centroids, and between the
+	// extremes and the centers of the first and last centroids
+	target := q * t.count
+	prevMean, prevCenter := t.min, 0.0
+	var cumulative float64
+	for _, c := range t.merged {
+		center := cumulative + c.count/2
+		if target < center {
+			return prevMean + (target-prevCenter)/(center-prevCenter)*(c.mean-prevMean)
+		}
+		prevMean, prevCenter = c.mean, center
+		cumulative += c.count
+	}
+	if t.count == prevCenter {
+		return t.max
+	}
+	return prevMean + (target-prevCenter)/(t.count-prevCenter)*(t.max-prevMean)
+}
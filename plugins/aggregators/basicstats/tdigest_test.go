@@ -0,0 +1,38 @@
+package basicstats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTDigestEmpty(t *testing.T) {
+	d := newTDigest(defaultCompression)
+	assert.True(t, math.IsNaN(d.Quantile(0.5)))
+}
+
+func TestTDigestSingle(t *testing.T) {
+	d := newTDigest(defaultCompression)
+	d.Add(42)
+	assert.Equal(t, float64(42), d.Quantile(0))
+	assert.Equal(t, float64(42), d.Quantile(0.5))
+	assert.Equal(t, float64(42), d.Quantile(1))
+}
+
+func TestTDigestUniform(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	d := newTDigest(defaultCompression)
+	for i := 0; i < 100000; i++ {
+		d.Add(r.Float64() * 100)
+	}
+
+	// the centroids are bounded by the compression, not by the values
+	assert.True(t, len(d.merged) <= defaultCompression)
+	assert.InDelta(t, 0, d.Quantile(0), 0.01)
+	assert.InDelta(t, 50, d.Quantile(0.5), 1)
+	assert.InDelta(t, 99, d.Quantile(0.99), 0.2)
+	assert.InDelta(t, 99.9, d.Quantile(0.999), 0.05)
+	assert.InDelta(t, 100, d.Quantile(1), 0.01)
+}
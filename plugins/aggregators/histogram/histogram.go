@@ -0,0 +1,257 @@
+package histogram
+
+import (
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+type HistogramAggregator struct {
+	Configs []config `toml:"config"`
+	// ResetBuckets resets the counts at each period instead of accumulating
+	// them like the counters of a Prometheus histogram.
+	ResetBuckets  bool              `toml:"reset"`
+	SeriesTimeout internal.Duration `toml:"series_timeout"`
+
+	configured bool
+	buckets    map[string][]bucketConfig
+
+	cache map[uint64]*aggregate
+}
+
+// config is the configuration of the buckets of the fields of a measurement.
+// It applies to all the numeric fields when Fields is empty.
+type config struct {
+	Metric  string    `toml:"measurement_name"`
+	Fields  []string  `toml:"fields"`
+	Buckets []float64 `toml:"buckets"`
+}
+
+// bucketConfig holds the sorted bounds of the buckets of a configuration.
+type bucketConfig struct {
+	fields map[string]bool
+	bounds []float64
+}
+
+func NewHistogramAggregator() *HistogramAggregator {
+	h := &HistogramAggregator{
+		SeriesTimeout: internal.Duration{Duration: 10 * time.Minute},
+	}
+	h.cache = make(map[uint64]*aggregate)
+	return h
+}
+
+type aggregate struct {
+	fields   map[string]*histogram
+	name     string
+	tags     map[string]string
+	lastSeen time.Time
+}
+
+// histogram counts the values of a field. counts[i] is the number of values
+// in the bucket of bounds[i], the last one counting the values greater than
+// every bound.
+type histogram struct {
+	config *bucketConfig
+	counts []int64
+	sum    float64
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## If true, the bucket counts are reset at each period. By default they
+  ## accumulate for as long as telegraf runs, like the counters of a
+  ## Prometheus histogram.
+  # reset = false
+
+  ## Series not updated within the timeout are forgotten, when the counts
+  ## accumulate.
+  # series_timeout = "10m"
+
+  ## The buckets of the fields of a measurement, given by their upper bounds.
+  ## The buckets apply to all the numeric fields of the measurement when no
+  ## fields are given. Values above the last bound count in the +Inf bucket.
+  [[aggregators.histogram.config]]
+    ## The upper bounds of the buckets, as floats.
+    buckets = [0.0, 15.6, 34.5, 49.1, 71.5, 80.5, 94.5, 100.0]
+    ## The name of the measurement.
+    measurement_name = "cpu"
+    ## The fields of the measurement.
+    # fields = ["usage_idle"]
+`
+
+func (h *HistogramAggregator) SampleConfig() string {
+	return sampleConfig
+}
+
+func (h *HistogramAggregator) Description() string {
+	return "Keep the aggregate histogram of each metric passing through."
+}
+
+// configure sorts the bounds of the configurations, which are logged and
+// ignored when they have no buckets.
+func (h *HistogramAggregator) configure() {
+	h.configured = true
+	h.buckets = make(map[string][]bucketConfig)
+	for _, c := range h.Configs {
+		if len(c.Buckets) == 0 {
+			log.Printf("E! histogram: no buckets for measurement %q", c.Metric)
+			continue
+		}
+
+		bounds := make([]float64, 0, len(c.Buckets))
+		for _, b := range c.Buckets {
+			if !math.IsNaN(b) {
+				bounds = append(bounds, b)
+			}
+		}
+		sort.Float64s(bounds)
+		var bc bucketConfig
+		for _, b := range bounds {
+			if n := len(bc.bounds); n > 0 && bc.bounds[n-1] == b {
+				continue
+			}
+			bc.bounds = append(bc.bounds, b)
+		}
+		if len(c.Fields) > 0 {
+			bc.fields = make(map[string]bool, len(c.Fields))
+			for _, f := range c.Fields {
+				bc.fields[f] = true
+			}
+		}
+		h.buckets[c.Metric] = append(h.buckets[c.Metric], bc)
+	}
+}
+
+// bucketConfig returns the configuration of the buckets of a field, the
+// first configuration of the measurement matching it.
+func (h *HistogramAggregator) bucketConfig(name, field string) *bucketConfig {
+	configs := h.buckets[name]
+	for i := range configs {
+		if configs[i].fields == nil || configs[i].fields[field] {
+			return &configs[i]
+		}
+	}
+	return nil
+}
+
+func (h *HistogramAggregator) Add(in telegraf.Metric) {
+	if !h.configured {
+		h.configure()
+	}
+	if _, ok := h.buckets[in.Name()]; !ok {
+		return
+	}
+
+	id := in.HashID()
+	a, ok := h.cache[id]
+	if !ok {
+		// hit an uncached metric, create caches for first time:
+		a = &aggregate{
+			name:   in.Name(),
+			tags:   in.Tags(),
+			fields: make(map[string]*histogram),
+		}
+		h.cache[id] = a
+	}
+	a.lastSeen = time.Now()
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok || math.IsNaN(fv) {
+			continue
+		}
+		hist, ok := a.fields[k]
+		if !ok {
+			// hit an uncached field of a cached metric
+			c := h.bucketConfig(in.Name(), k)
+			if c == nil {
+				continue
+			}
+			hist = &histogram{
+				config: c,
+				counts: make([]int64, len(c.bounds)+1),
+			}
+			a.fields[k] = hist
+		}
+		hist.add(fv)
+	}
+}
+
+func (hist *histogram) add(v float64) {
+	i := sort.SearchFloat64s(hist.config.bounds, v)
+	hist.counts[i]++
+	hist.sum += v
+}
+
+// Push emits a Histogram metric per field of each series, named after the
+// measurement and the field, holding the cumulative counts of the buckets and
+// the sum and count of the values. Outputs such as prometheus_client expose
+// them as Prometheus histograms.
+func (h *HistogramAggregator) Push(acc telegraf.Accumulator) {
+	for _, aggregate := range h.cache {
+		for k, hist := range aggregate.fields {
+			buckets := make([]metric.Bucket, 0, len(hist.counts))
+			var count uint64
+			for i, n := range hist.counts {
+				count += uint64(n)
+				bound := math.Inf(1)
+				if i < len(hist.config.bounds) {
+					bound = hist.config.bounds[i]
+				}
+				buckets = append(buckets, metric.Bucket{UpperBound: bound, Count: count})
+			}
+
+			m, err := metric.NewHistogram(aggregate.name+"_"+k, aggregate.tags,
+				buckets, count, hist.sum, time.Now())
+			if err != nil {
+				acc.AddError(err)
+				continue
+			}
+			acc.AddHistogram(m.Name(), m.Fields(), m.Tags(), m.Time())
+		}
+	}
+}
+
+// Reset clears the counts if they are reset at each period, and otherwise
+// forgets the series that timed out.
+func (h *HistogramAggregator) Reset() {
+	if h.ResetBuckets {
+		h.cache = make(map[uint64]*aggregate)
+		return
+	}
+	for id, a := range h.cache {
+		if time.Since(a.lastSeen) > h.SeriesTimeout.Duration {
+			delete(h.cache, id)
+		}
+	}
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("histogram", func() telegraf.Aggregator {
+		return NewHistogramAggregator()
+	})
+}
@@ -0,0 +1,118 @@
+package histogram
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetric(name string, fields map[string]interface{}) telegraf.Metric {
+	m, _ := metric.New(name,
+		map[string]string{"foo": "bar"},
+		fields,
+		time.Now(),
+	)
+	return m
+}
+
+var m1 = newMetric("latency", map[string]interface{}{
+	"a":        int64(5),
+	"b":        float64(20),
+	"ignoreme": "string",
+})
+var m2 = newMetric("latency", map[string]interface{}{
+	"a": int64(10),
+	"b": float64(200),
+})
+
+func newTestHistogram(reset bool) *HistogramAggregator {
+	h := NewHistogramAggregator()
+	h.ResetBuckets = reset
+	h.Configs = []config{
+		{Metric: "latency", Fields: []string{"a"}, Buckets: []float64{10, 1, 10}},
+		{Metric: "latency", Buckets: []float64{50, 100}},
+	}
+	return h
+}
+
+// histograms returns the fields of the histograms pushed to acc, by name.
+func histograms(t *testing.T, acc *testutil.Accumulator) map[string]map[string]interface{} {
+	h := make(map[string]map[string]interface{})
+	for _, m := range acc.Metrics {
+		assert.Equal(t, telegraf.Histogram, m.Type)
+		assert.Equal(t, map[string]string{"foo": "bar"}, m.Tags)
+		h[m.Measurement] = m.Fields
+	}
+	return h
+}
+
+func TestHistogram(t *testing.T) {
+	acc := testutil.Accumulator{}
+	h := newTestHistogram(false)
+
+	h.Add(m1)
+	h.Add(m2)
+	h.Push(&acc)
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"latency_a": {
+			"1": float64(0), "10": float64(2), "+Inf": float64(2),
+			"count": float64(2), "sum": float64(15),
+		},
+		"latency_b": {
+			"50": float64(1), "100": float64(1), "+Inf": float64(2),
+			"count": float64(2), "sum": float64(220),
+		},
+	}, histograms(t, &acc))
+}
+
+// Test the counts accumulating across periods unless they are reset.
+func TestHistogramPeriods(t *testing.T) {
+	for _, reset := range []bool{false, true} {
+		acc := testutil.Accumulator{}
+		h := newTestHistogram(reset)
+
+		h.Add(m1)
+		h.Push(&acc)
+		h.Reset()
+		acc.ClearMetrics()
+
+		h.Add(m2)
+		h.Push(&acc)
+		b := histograms(t, &acc)["latency_b"]
+		if reset {
+			assert.Equal(t, float64(0), b["50"])
+			assert.Equal(t, float64(1), b["+Inf"])
+		} else {
+			assert.Equal(t, float64(1), b["50"])
+			assert.Equal(t, float64(2), b["+Inf"])
+		}
+	}
+}
+
+func TestHistogramSeriesTimeout(t *testing.T) {
+	h := newTestHistogram(false)
+	h.Add(m1)
+	h.Reset()
+	assert.Len(t, h.cache, 1)
+
+	h.SeriesTimeout.Duration = 0
+	h.Reset()
+	assert.Empty(t, h.cache)
+}
+
+func TestHistogramUnconfigured(t *testing.T) {
+	acc := testutil.Accumulator{}
+	h := NewHistogramAggregator()
+	h.Configs = []config{{Metric: "latency"}}
+
+	h.Add(m1)
+	h.Add(newMetric("cpu", map[string]interface{}{"a": int64(1)}))
+	h.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
@@ -0,0 +1,285 @@
+package rate
+
+import (
+	"log"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	modeRate  = "rate"
+	modeDelta = "delta"
+)
+
+type Rate struct {
+	Fields          []string
+	ConvertCounters bool `toml:"convert_counters"`
+	Mode            string
+	CounterBits     int               `toml:"counter_bits"`
+	SeriesTimeout   internal.Duration `toml:"series_timeout"`
+
+	configured bool
+	filter     filter.Filter
+
+	cache map[uint64]*series
+}
+
+func NewRate() *Rate {
+	return &Rate{
+		ConvertCounters: true,
+		Mode:            modeRate,
+		SeriesTimeout:   internal.Duration{Duration: 10 * time.Minute},
+		cache:           make(map[uint64]*series),
+	}
+}
+
+// series holds the counters of a series. Their state is kept across periods,
+// the first delta of a period being computed from the last value of the
+// previous one.
+type series struct {
+	name     string
+	tags     map[string]string
+	fields   map[string]*counter
+	lastSeen time.Time
+}
+
+// counter holds the last value of a counter and the sum of its deltas over
+// the period, along with the time they span. Integer counters are kept as
+// integers, so that large counters do not lose precision.
+type counter struct {
+	integer bool
+	value   int64
+	delta   int64
+	fvalue  float64
+	fdelta  float64
+	time    time.Time
+	elapsed time.Duration
+	updated bool
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## The fields converted, globs supported. They are emitted as
+  ## <field>_rate or <field>_delta, depending on the mode.
+  # fields = ["bytes_*", "packets_*"]
+
+  ## If true, all the fields of the metrics reported as counters by their
+  ## input are converted as well.
+  # convert_counters = true
+
+  ## "rate" emits the per-second rate of the counters over the period,
+  ## "delta" emits their increase over the period.
+  # mode = "rate"
+
+  ## Set to 32 for 32 bits counters: an integer counter lower than its
+  ## previous value is then assumed to have wrapped around rather than been
+  ## reset, if the wrapped increase is less than 2^31. When 0, a lower value
+  ## is always a reset and does not count.
+  # counter_bits = 0
+
+  ## Series whose counters are not updated within the timeout are forgotten.
+  # series_timeout = "10m"
+`
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Convert monotonic counters into rates or deltas."
+}
+
+// configure compiles the fields filter. Invalid settings are logged and
+// replaced by their defaults.
+func (r *Rate) configure() {
+	r.configured = true
+
+	var err error
+	if r.filter, err = filter.Compile(r.Fields); err != nil {
+		log.Printf("E! rate: invalid fields %v: %s", r.Fields, err)
+	}
+	if r.Mode != modeRate && r.Mode != modeDelta {
+		log.Printf("E! rate: invalid mode %q, using %q", r.Mode, modeRate)
+		r.Mode = modeRate
+	}
+	// 64 bits counters cannot wrap around in the int64 fields of the
+	// metrics.
+	if r.CounterBits != 0 && r.CounterBits != 32 {
+		log.Printf("E! rate: invalid counter_bits %d, using 0", r.CounterBits)
+		r.CounterBits = 0
+	}
+}
+
+func (r *Rate) converts(in telegraf.Metric, field string) bool {
+	if r.ConvertCounters && in.Type() == telegraf.Counter {
+		return true
+	}
+	return r.filter != nil && r.filter.Match(field)
+}
+
+func (r *Rate) Add(in telegraf.Metric) {
+	if !r.configured {
+		r.configure()
+	}
+
+	id := in.HashID()
+	s := r.cache[id]
+	for k, v := range in.Fields() {
+		if !numeric(v) || !r.converts(in, k) {
+			continue
+		}
+		if s == nil {
+			// hit an uncached metric, create caches for first time:
+			s = &series{
+				name:   in.Name(),
+				tags:   in.Tags(),
+				fields: make(map[string]*counter),
+			}
+			r.cache[id] = s
+		}
+		s.lastSeen = time.Now()
+
+		c, ok := s.fields[k]
+		if !ok {
+			// hit an uncached field, its first value is the baseline
+			c = &counter{}
+			c.set(v, in.Time())
+			s.fields[k] = c
+			continue
+		}
+		r.update(c, v, in.Time())
+	}
+}
+
+// set makes v the last value of the counter.
+func (c *counter) set(v interface{}, t time.Time) {
+	switch v := v.(type) {
+	case int64:
+		c.integer, c.value = true, v
+	case float64:
+		c.integer, c.fvalue = false, v
+	}
+	c.time = t
+}
+
+// update adds the increase of a counter since its last value to the period.
+func (r *Rate) update(c *counter, v interface{}, t time.Time) {
+	elapsed := t.Sub(c.time)
+	if elapsed <= 0 {
+		// an older or duplicate value
+		return
+	}
+
+	var reset bool
+	switch v := v.(type) {
+	case int64:
+		if !c.integer {
+			reset = true
+			break
+		}
+		delta, ok := r.delta(c.value, v)
+		if !ok {
+			reset = true
+			break
+		}
+		c.delta += delta
+	case float64:
+		if c.integer || v < c.fvalue {
+			reset = true
+			break
+		}
+		c.fdelta += v - c.fvalue
+	}
+	c.set(v, t)
+	if reset {
+		// a reset, or a change of type, the value is the new baseline
+		return
+	}
+	c.elapsed += elapsed
+	c.updated = true
+}
+
+// delta returns the increase of an integer counter from prev to v. It
+// returns false if the counter was reset.
+func (r *Rate) delta(prev, v int64) (int64, bool) {
+	if v >= prev {
+		return v - prev, true
+	}
+	if r.CounterBits == 0 {
+		return 0, false
+	}
+	// the counter wrapped around, unless it was reset
+	max := int64(1) << uint(r.CounterBits)
+	if prev >= max || v < 0 {
+		return 0, false
+	}
+	delta := max - prev + v
+	if delta >= max/2 {
+		return 0, false
+	}
+	return delta, true
+}
+
+func (r *Rate) Push(acc telegraf.Accumulator) {
+	suffix := "_" + r.Mode
+	for _, s := range r.cache {
+		fields := map[string]interface{}{}
+		for k, c := range s.fields {
+			if !c.updated {
+				continue
+			}
+			switch {
+			case r.Mode == modeDelta && c.integer:
+				fields[k+suffix] = c.delta
+			case r.Mode == modeDelta:
+				fields[k+suffix] = c.fdelta
+			case c.integer:
+				fields[k+suffix] = float64(c.delta) / c.elapsed.Seconds()
+			default:
+				fields[k+suffix] = c.fdelta / c.elapsed.Seconds()
+			}
+		}
+		if len(fields) > 0 {
+			acc.AddGauge(s.name, fields, s.tags)
+		}
+	}
+}
+
+// Reset clears the deltas of the period, keeping the last values of the
+// counters for the next period, and forgets the series that timed out.
+func (r *Rate) Reset() {
+	for id, s := range r.cache {
+		if time.Since(s.lastSeen) > r.SeriesTimeout.Duration {
+			delete(r.cache, id)
+			continue
+		}
+		for _, c := range s.fields {
+			c.delta, c.fdelta, c.elapsed, c.updated = 0, 0, 0, false
+		}
+	}
+}
+
+func numeric(in interface{}) bool {
+	switch in.(type) {
+	case float64, int64:
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	aggregators.Add("rate", func() telegraf.Aggregator {
+		return NewRate()
+	})
+}
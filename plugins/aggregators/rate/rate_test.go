@@ -0,0 +1,150 @@
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Unix(1500000000, 0)
+
+func counters(seconds int, fields map[string]interface{}, mType ...telegraf.ValueType) telegraf.Metric {
+	m, _ := metric.New("net",
+		map[string]string{"interface": "eth0"},
+		fields,
+		start.Add(time.Duration(seconds)*time.Second),
+		mType...,
+	)
+	return m
+}
+
+func TestRate(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+	r.Fields = []string{"bytes_*"}
+
+	r.Add(counters(0, map[string]interface{}{"bytes_recv": int64(100), "err_in": int64(1)}))
+	r.Add(counters(10, map[string]interface{}{"bytes_recv": int64(300), "err_in": int64(2)}))
+	r.Add(counters(20, map[string]interface{}{"bytes_recv": int64(400), "err_in": int64(3)}))
+	r.Push(&acc)
+
+	acc.AssertContainsTaggedFields(t, "net",
+		map[string]interface{}{"bytes_recv_rate": float64(15)},
+		map[string]string{"interface": "eth0"})
+}
+
+// Test the deltas across periods, from the last value of the previous one.
+func TestRateDeltaPeriods(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+	r.Mode = "delta"
+	r.Fields = []string{"bytes_recv"}
+
+	r.Add(counters(0, map[string]interface{}{"bytes_recv": int64(100)}))
+	r.Push(&acc)
+	// the first value is only the baseline
+	assert.Empty(t, acc.Metrics)
+	r.Reset()
+
+	r.Add(counters(10, map[string]interface{}{"bytes_recv": int64(300)}))
+	r.Push(&acc)
+	r.Reset()
+	r.Push(&acc)
+	r.Reset()
+	r.Add(counters(30, map[string]interface{}{"bytes_recv": int64(350)}))
+	r.Push(&acc)
+
+	assert.Len(t, acc.Metrics, 2)
+	assert.Equal(t, int64(200), acc.Metrics[0].Fields["bytes_recv_delta"])
+	assert.Equal(t, int64(50), acc.Metrics[1].Fields["bytes_recv_delta"])
+}
+
+func TestRateCounterType(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+
+	r.Add(counters(0, map[string]interface{}{"packets": int64(10)}, telegraf.Counter))
+	r.Add(counters(5, map[string]interface{}{"packets": int64(20)}, telegraf.Counter))
+	r.Add(counters(0, map[string]interface{}{"errors": int64(10)}))
+	r.Add(counters(5, map[string]interface{}{"errors": int64(20)}))
+	r.Push(&acc)
+
+	assert.Len(t, acc.Metrics, 1)
+	assert.Equal(t, float64(2), acc.Metrics[0].Fields["packets_rate"])
+}
+
+func TestRateResetAndWrap(t *testing.T) {
+	tests := []struct {
+		bits  int
+		prev  int64
+		value int64
+		delta int64
+	}{
+		// a reset only sets the baseline
+		{0, 1000, 10, 50},
+		// a 32 bits wraparound
+		{32, math.MaxUint32 - 9, 10, 70},
+		// too large an increase to be a wraparound
+		{32, 1000, 10, 50},
+		// 64 bits counters are not supported, a lower value is a reset
+		{64, math.MaxInt64 - 9, 10, 50},
+		// large counters are exact
+		{0, 1<<62 + 1, 1<<62 + 4, 53},
+	}
+
+	for _, tt := range tests {
+		acc := testutil.Accumulator{}
+		r := NewRate()
+		r.Mode = "delta"
+		r.Fields = []string{"c"}
+		r.CounterBits = tt.bits
+
+		r.Add(counters(0, map[string]interface{}{"c": tt.prev}))
+		r.Add(counters(10, map[string]interface{}{"c": tt.value}))
+		r.Add(counters(20, map[string]interface{}{"c": tt.value + 50}))
+		r.Push(&acc)
+
+		m, ok := acc.Get("net")
+		if assert.True(t, ok) {
+			assert.Equal(t, tt.delta, m.Fields["c_delta"], "%+v", tt)
+		}
+	}
+}
+
+func TestRateFloatCounters(t *testing.T) {
+	acc := testutil.Accumulator{}
+	r := NewRate()
+	r.Mode = "delta"
+	r.Fields = []string{"c"}
+	r.CounterBits = 32
+
+	r.Add(counters(0, map[string]interface{}{"c": float64(1.5)}))
+	r.Add(counters(10, map[string]interface{}{"c": float64(4)}))
+	// float counters do not wrap around, a lower value is a reset
+	r.Add(counters(20, map[string]interface{}{"c": float64(1)}))
+	r.Add(counters(30, map[string]interface{}{"c": float64(2)}))
+	r.Push(&acc)
+
+	m, ok := acc.Get("net")
+	if assert.True(t, ok) {
+		assert.Equal(t, float64(3.5), m.Fields["c_delta"])
+	}
+}
+
+func TestRateSeriesTimeout(t *testing.T) {
+	r := NewRate()
+	r.Fields = []string{"c"}
+	r.Add(counters(0, map[string]interface{}{"c": int64(1)}))
+	r.Reset()
+	assert.Len(t, r.cache, 1)
+
+	r.SeriesTimeout.Duration = 0
+	r.Reset()
+	assert.Empty(t, r.cache)
+}
@@ -0,0 +1,321 @@
+package topk
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+)
+
+const (
+	aggMean = "mean"
+	aggSum  = "sum"
+	aggMin  = "min"
+	aggMax  = "max"
+	aggLast = "last"
+)
+
+type TopK struct {
+	K           int
+	Field       string
+	GroupBy     []string `toml:"group_by"`
+	Aggregation string
+	AddOther    bool   `toml:"add_other"`
+	OtherValue  string `toml:"other_value"`
+	OtherTag    string `toml:"other_tag"`
+
+	configured bool
+
+	// cache holds the groups of each measurement, by name and group key.
+	cache map[string]map[string]*group
+}
+
+func NewTopK() *TopK {
+	return &TopK{
+		K:           10,
+		Aggregation: aggMean,
+		OtherValue:  "other",
+		OtherTag:    "topk",
+		cache:       make(map[string]map[string]*group),
+	}
+}
+
+// group holds the aggregates of the fields of each series of a group, by the
+// HashID of the series.
+type group struct {
+	tags   map[string]string
+	series map[uint64]map[string]*aggregate
+}
+
+type aggregate struct {
+	count int64
+	sum   float64
+	min   float64
+	max   float64
+	last  float64
+}
+
+var sampleConfig = `
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = true
+
+  ## The number of series kept for each measurement.
+  k = 10
+
+  ## The field the series are ranked by, in descending order.
+  field = "cpu_usage"
+
+  ## The tags the series are grouped by, all of them by default. The fields
+  ## of the series of a group are summed, and only the tags of the group are
+  ## kept.
+  # group_by = ["process_name"]
+
+  ## How the values of the fields are aggregated over the period, one of
+  ## "mean", "sum", "min", "max" or "last".
+  # aggregation = "mean"
+
+  ## If true, the fields of the series not in the top k are summed into an
+  ## "other" series. Its group_by tags are set to other_value, and it keeps
+  ## the other tags common to all those series. Without group_by, it is
+  ## marked by the other_tag tag, set to other_value.
+  # add_other = false
+  # other_value = "other"
+  # other_tag = "topk"
+`
+
+func (t *TopK) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *TopK) Description() string {
+	return "Keep the top k series of each measurement, ranked by a field."
+}
+
+// configure checks the settings. Invalid settings are logged and replaced by
+// their defaults.
+func (t *TopK) configure() {
+	t.configured = true
+
+	switch t.Aggregation {
+	case aggMean, aggSum, aggMin, aggMax, aggLast:
+	default:
+		log.Printf("E! topk: invalid aggregation %q, using %q", t.Aggregation, aggMean)
+		t.Aggregation = aggMean
+	}
+	if t.K < 1 {
+		log.Printf("E! topk: invalid k %d, using 10", t.K)
+		t.K = 10
+	}
+	if t.Field == "" {
+		log.Printf("E! topk: no field to rank the series by")
+	}
+}
+
+// groupTags returns the tags identifying the group of a metric.
+func (t *TopK) groupTags(in telegraf.Metric) map[string]string {
+	tags := in.Tags()
+	if len(t.GroupBy) == 0 {
+		return tags
+	}
+	grouped := make(map[string]string, len(t.GroupBy))
+	for _, k := range t.GroupBy {
+		if v, ok := tags[k]; ok {
+			grouped[k] = v
+		}
+	}
+	return grouped
+}
+
+func groupKey(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		keys = append(keys, k+"="+v)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (t *TopK) Add(in telegraf.Metric) {
+	if !t.configured {
+		t.configure()
+	}
+
+	groups, ok := t.cache[in.Name()]
+	if !ok {
+		groups = make(map[string]*group)
+		t.cache[in.Name()] = groups
+	}
+
+	tags := t.groupTags(in)
+	key := groupKey(tags)
+	g, ok := groups[key]
+	if !ok {
+		// hit an uncached group, create caches for first time:
+		g = &group{tags: tags, series: make(map[uint64]map[string]*aggregate)}
+		groups[key] = g
+	}
+
+	// the values are aggregated over time for each series, the series of the
+	// group are only summed when pushed.
+	id := in.HashID()
+	fields, ok := g.series[id]
+	if !ok {
+		fields = make(map[string]*aggregate)
+		g.series[id] = fields
+	}
+
+	for k, v := range in.Fields() {
+		fv, ok := convert(v)
+		if !ok {
+			continue
+		}
+		a, ok := fields[k]
+		if !ok {
+			fields[k] = &aggregate{count: 1, sum: fv, min: fv, max: fv, last: fv}
+			continue
+		}
+		a.count++
+		a.sum += fv
+		a.last = fv
+		if fv < a.min {
+			a.min = fv
+		}
+		if fv > a.max {
+			a.max = fv
+		}
+	}
+}
+
+func (t *TopK) value(a *aggregate) float64 {
+	switch t.Aggregation {
+	case aggSum:
+		return a.sum
+	case aggMin:
+		return a.min
+	case aggMax:
+		return a.max
+	case aggLast:
+		return a.last
+	default:
+		return a.sum / float64(a.count)
+	}
+}
+
+// ranked is a group with its aggregated fields, and whether it has the field
+// the groups are ranked by.
+type ranked struct {
+	key    string
+	tags   map[string]string
+	fields map[string]interface{}
+	rank   float64
+	ok     bool
+}
+
+// byRank sorts the groups in descending order of their rank, the groups
+// without the field last. Ties are broken by the keys of the groups.
+type byRank []ranked
+
+func (r byRank) Len() int      { return len(r) }
+func (r byRank) Swap(i, j int) { r[i], r[j] = r[j], r[i] }
+func (r byRank) Less(i, j int) bool {
+	if r[i].ok != r[j].ok {
+		return r[i].ok
+	}
+	if r[i].rank != r[j].rank {
+		return r[i].rank > r[j].rank
+	}
+	return r[i].key < r[j].key
+}
+
+func (t *TopK) Push(acc telegraf.Accumulator) {
+	for name, groups := range t.cache {
+		list := make([]ranked, 0, len(groups))
+		for key, g := range groups {
+			r := ranked{
+				key:    key,
+				tags:   g.tags,
+				fields: make(map[string]interface{}),
+			}
+			for _, fields := range g.series {
+				for k, a := range fields {
+					sum, _ := r.fields[k].(float64)
+					r.fields[k] = sum + t.value(a)
+				}
+			}
+			if v, ok := r.fields[t.Field]; ok {
+				r.rank, r.ok = v.(float64), true
+			}
+			list = append(list, r)
+		}
+		sort.Sort(byRank(list))
+
+		for i, r := range list {
+			if i == t.K || !r.ok {
+				if t.AddOther {
+					t.pushOther(acc, name, list[i:])
+				}
+				break
+			}
+			acc.AddFields(name, r.fields, r.tags)
+		}
+	}
+}
+
+// pushOther emits the sum of the fields of the groups which are not in the
+// top k.
+func (t *TopK) pushOther(acc telegraf.Accumulator, name string, others []ranked) {
+	fields := make(map[string]interface{})
+	tags := make(map[string]string)
+	for k, v := range others[0].tags {
+		tags[k] = v
+	}
+	for _, r := range others {
+		for k, v := range r.fields {
+			sum, _ := fields[k].(float64)
+			fields[k] = sum + v.(float64)
+		}
+		for k, v := range tags {
+			if r.tags[k] != v {
+				delete(tags, k)
+			}
+		}
+	}
+	for _, k := range t.GroupBy {
+		tags[k] = t.OtherValue
+	}
+	// without group_by tags, the series would not be told apart from the
+	// series it sums.
+	if len(t.GroupBy) == 0 {
+		tags[t.OtherTag] = t.OtherValue
+	}
+	if len(fields) > 0 {
+		acc.AddFields(name, fields, tags)
+	}
+}
+
+func (t *TopK) Reset() {
+	t.cache = make(map[string]map[string]*group)
+}
+
+func convert(in interface{}) (float64, bool) {
+	switch v := in.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func init() {
+	aggregators.Add("topk", func() telegraf.Aggregator {
+		return NewTopK()
+	})
+}
@@ -0,0 +1,143 @@
+package topk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func procstat(process, pid string, cpu float64, rss int64) telegraf.Metric {
+	m, _ := metric.New("procstat",
+		map[string]string{"host": "web-01", "process_name": process, "pid": pid},
+		map[string]interface{}{"cpu_usage": cpu, "memory_rss": rss},
+		time.Now(),
+	)
+	return m
+}
+
+func add(t *TopK) {
+	t.Add(procstat("nginx", "10", 4, 100))
+	t.Add(procstat("nginx", "11", 6, 100))
+	t.Add(procstat("java", "20", 50, 1000))
+	t.Add(procstat("java", "20", 30, 3000))
+	t.Add(procstat("sshd", "30", 1, 10))
+	t.Add(procstat("cron", "40", 2, 20))
+}
+
+// fields returns the fields pushed to acc, keyed by the value of a tag.
+func fields(acc *testutil.Accumulator, tag string) map[string]map[string]interface{} {
+	f := make(map[string]map[string]interface{})
+	for _, m := range acc.Metrics {
+		f[m.Tags[tag]] = m.Fields
+	}
+	return f
+}
+
+func TestTopK(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.K = 2
+	tk.Field = "cpu_usage"
+	add(tk)
+	tk.Push(&acc)
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"20": {"cpu_usage": float64(40), "memory_rss": float64(2000)},
+		"11": {"cpu_usage": float64(6), "memory_rss": float64(100)},
+	}, fields(&acc, "pid"))
+	acc.AssertContainsTaggedFields(t, "procstat",
+		map[string]interface{}{"cpu_usage": float64(40), "memory_rss": float64(2000)},
+		map[string]string{"host": "web-01", "process_name": "java", "pid": "20"})
+}
+
+func TestTopKGroupByOther(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.K = 2
+	tk.Field = "memory_rss"
+	tk.GroupBy = []string{"process_name"}
+	tk.Aggregation = "sum"
+	tk.AddOther = true
+	add(tk)
+	tk.Push(&acc)
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"java":  {"cpu_usage": float64(80), "memory_rss": float64(4000)},
+		"nginx": {"cpu_usage": float64(10), "memory_rss": float64(200)},
+		"other": {"cpu_usage": float64(3), "memory_rss": float64(30)},
+	}, fields(&acc, "process_name"))
+	acc.AssertContainsTaggedFields(t, "procstat",
+		map[string]interface{}{"cpu_usage": float64(3), "memory_rss": float64(30)},
+		map[string]string{"process_name": "other"})
+}
+
+// Verify that the series of a group are aggregated over time on their own,
+// then summed.
+func TestTopKGroupByMean(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.K = 2
+	tk.Field = "cpu_usage"
+	tk.GroupBy = []string{"process_name"}
+	add(tk)
+	tk.Push(&acc)
+
+	assert.Equal(t, map[string]map[string]interface{}{
+		"java":  {"cpu_usage": float64(40), "memory_rss": float64(2000)},
+		"nginx": {"cpu_usage": float64(10), "memory_rss": float64(200)},
+	}, fields(&acc, "process_name"))
+}
+
+func TestTopKOtherCommonTags(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.K = 1
+	tk.Field = "cpu_usage"
+	tk.Aggregation = "max"
+	tk.AddOther = true
+	add(tk)
+	tk.Push(&acc)
+
+	assert.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "procstat",
+		map[string]interface{}{"cpu_usage": float64(13), "memory_rss": float64(230)},
+		map[string]string{"host": "web-01", "topk": "other"})
+}
+
+func TestTopKOtherTag(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.K = 3
+	tk.Field = "cpu_usage"
+	tk.AddOther = true
+	tk.OtherValue = "rest"
+	tk.OtherTag = "rank"
+	add(tk)
+	tk.Push(&acc)
+
+	// the "other" series is told apart from the series it sums
+	assert.Len(t, acc.Metrics, 4)
+	acc.AssertContainsTaggedFields(t, "procstat",
+		map[string]interface{}{"cpu_usage": float64(3), "memory_rss": float64(30)},
+		map[string]string{"host": "web-01", "rank": "rest"})
+	for _, m := range acc.Metrics {
+		if m.Tags["rank"] != "rest" {
+			assert.NotContains(t, m.Tags, "rank")
+		}
+	}
+}
+
+func TestTopKReset(t *testing.T) {
+	acc := testutil.Accumulator{}
+	tk := NewTopK()
+	tk.Field = "cpu_usage"
+	add(tk)
+	tk.Reset()
+	tk.Push(&acc)
+	assert.Empty(t, acc.Metrics)
+}
@@ -29,6 +29,10 @@ type AMQPConsumer struct {
 	// for consumers before receiving delivery acks.
 	PrefetchCount int
 
+	// Maximum number of messages whose metrics have not been written by the
+	// outputs yet, and which are therefore not acknowledged.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// AMQP Auth method
 	AuthMethod string
 	// Path to CA file
@@ -43,6 +47,8 @@ type AMQPConsumer struct {
 	parser parsers.Parser
 	conn   *amqp.Connection
 	wg     *sync.WaitGroup
+	acc    telegraf.TrackingAccumulator
+	done   chan struct{}
 }
 
 type externalAuth struct{}
@@ -55,8 +61,9 @@ func (a *externalAuth) Response() string {
 }
 
 const (
-	DefaultAuthMethod    = "PLAIN"
-	DefaultPrefetchCount = 50
+	DefaultAuthMethod             = "PLAIN"
+	DefaultPrefetchCount          = 50
+	DefaultMaxUndeliveredMessages = 1000
 )
 
 func (a *AMQPConsumer) SampleConfig() string {
@@ -73,6 +80,12 @@ func (a *AMQPConsumer) SampleConfig() string {
   ## Maximum number of messages server should give to the worker.
   prefetch_count = 50
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. Messages are only acknowledged once their metrics are written,
+  ## so the server does not send more than prefetch_count unacknowledged
+  ## messages either.
+  # max_undelivered_messages = 1000
+
   ## Auth method. PLAIN and EXTERNAL are supported
   ## Using EXTERNAL requires enabling the rabbitmq_auth_mechanism_ssl plugin as
   ## described here: https://www.rabbitmq.com/plugins.html
@@ -140,12 +153,18 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	if a.MaxUndeliveredMessages <= 0 {
+		a.MaxUndeliveredMessages = DefaultMaxUndeliveredMessages
+	}
+	a.acc = acc.WithTracking(a.MaxUndeliveredMessages)
+	a.done = make(chan struct{})
+
 	a.wg = &sync.WaitGroup{}
 	a.wg.Add(1)
-	go a.process(msgs, acc)
+	go a.process(msgs, a.conn.NotifyClose(make(chan *amqp.Error, 1)))
 
 	go func() {
-		err := <-a.conn.NotifyClose(make(chan *amqp.Error))
+		err := <-a.conn.NotifyClose(make(chan *amqp.Error, 1))
 		if err == nil {
 			return
 		}
@@ -155,12 +174,16 @@ func (a *AMQPConsumer) Start(acc telegraf.Accumulator) error {
 			msgs, err := a.connect(amqpConf)
 			if err != nil {
 				log.Printf("E! AMQP connection failed: %s", err)
-				time.Sleep(10 * time.Second)
+				select {
+				case <-a.done:
+					return
+				case <-time.After(10 * time.Second):
+				}
 				continue
 			}
 
 			a.wg.Add(1)
-			go a.process(msgs, acc)
+			go a.process(msgs, a.conn.NotifyClose(make(chan *amqp.Error, 1)))
 			break
 		}
 	}()
@@ -242,25 +265,74 @@ func (a *AMQPConsumer) connect(amqpConf *amqp.Config) (<-chan amqp.Delivery, err
 	return msgs, err
 }
 
-// Read messages from queue and add them to the Accumulator
-func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, acc telegraf.Accumulator) {
+// Read messages from queue and add them to the Accumulator. Messages are
+// acknowledged once their metrics are delivered; messages left undelivered
+// when the connection closes are redelivered by the server.
+//
+// The connection closing, or the consumer stopping, ends the processing even
+// while reading is suspended by too many undelivered messages.
+func (a *AMQPConsumer) process(msgs <-chan amqp.Delivery, closed <-chan *amqp.Error) {
 	defer a.wg.Done()
-	for d := range msgs {
-		metrics, err := a.parser.Parse(d.Body)
-		if err != nil {
-			log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
-		} else {
-			for _, m := range metrics {
-				acc.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+	undelivered := make(map[telegraf.TrackingID]amqp.Delivery)
+	for {
+		// stop reading messages while too many are undelivered
+		in := msgs
+		if len(undelivered) >= a.MaxUndeliveredMessages {
+			in = nil
+		}
+
+		select {
+		case <-a.done:
+			return
+		case <-closed:
+			log.Printf("I! AMQP consumer connection closed")
+			return
+		case info := <-a.acc.Delivered():
+			a.onDelivery(info, undelivered)
+		case d, ok := <-in:
+			if !ok {
+				log.Printf("I! AMQP consumer queue closed")
+				return
 			}
+			metrics, err := a.parser.Parse(d.Body)
+			if err != nil {
+				log.Printf("E! %v: error parsing metric - %v", err, string(d.Body))
+				d.Ack(false)
+				continue
+			}
+			id := a.acc.AddTrackingMetricGroup(metrics)
+			undelivered[id] = d
 		}
+	}
+}
 
-		d.Ack(false)
+func (a *AMQPConsumer) onDelivery(
+	info telegraf.DeliveryInfo,
+	undelivered map[telegraf.TrackingID]amqp.Delivery,
+) {
+	d, ok := undelivered[info.ID()]
+	if !ok {
+		// the message was read on a previous connection, the server
+		// redelivers it.
+		return
+	}
+	delete(undelivered, info.ID())
+
+	var err error
+	if info.Delivered() {
+		err = d.Ack(false)
+	} else {
+		// an output dropped some of the metrics, let the server route the
+		// message to a dead letter exchange if one is configured.
+		err = d.Reject(false)
+	}
+	if err != nil {
+		log.Printf("E! Unable to acknowledge AMQP message: %s", err)
 	}
-	log.Printf("I! AMQP consumer queue closed")
 }
 
 func (a *AMQPConsumer) Stop() {
+	close(a.done)
 	err := a.conn.Close()
 	if err != nil && err != amqp.ErrClosed {
 		log.Printf("E! Error closing AMQP connection: %s", err)
@@ -273,8 +345,9 @@ func (a *AMQPConsumer) Stop() {
 func init() {
 	inputs.Add("amqp_consumer", func() telegraf.Input {
 		return &AMQPConsumer{
-			AuthMethod:    DefaultAuthMethod,
-			PrefetchCount: DefaultPrefetchCount,
+			AuthMethod:             DefaultAuthMethod,
+			PrefetchCount:          DefaultPrefetchCount,
+			MaxUndeliveredMessages: DefaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -0,0 +1,87 @@
+package amqp_consumer
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/streadway/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Verify that a consumer suspended by too many undelivered messages stops.
+func TestProcessStopWhileThrottled(t *testing.T) {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	a := &AMQPConsumer{
+		MaxUndeliveredMessages: 1,
+		parser:                 parser,
+		acc:                    &testutil.UndeliveredAccumulator{Accumulator: &acc},
+		wg:                     &sync.WaitGroup{},
+		done:                   make(chan struct{}),
+	}
+
+	msgs := make(chan amqp.Delivery, 2)
+	msgs <- amqp.Delivery{Body: []byte("cpu value=1\n")}
+	msgs <- amqp.Delivery{Body: []byte("cpu value=2\n")}
+
+	a.wg.Add(1)
+	go a.process(msgs, make(chan *amqp.Error))
+	acc.Wait(1)
+
+	close(a.done)
+	stopped := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("process did not return")
+	}
+	// the second message is not read while the first one is undelivered
+	assert.Len(t, msgs, 1)
+}
+
+// Verify that a consumer suspended by too many undelivered messages returns
+// when its connection closes.
+func TestProcessConnectionClosedWhileThrottled(t *testing.T) {
+	parser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	var acc testutil.Accumulator
+	a := &AMQPConsumer{
+		MaxUndeliveredMessages: 1,
+		parser:                 parser,
+		acc:                    &testutil.UndeliveredAccumulator{Accumulator: &acc},
+		wg:                     &sync.WaitGroup{},
+		done:                   make(chan struct{}),
+	}
+
+	msgs := make(chan amqp.Delivery, 1)
+	msgs <- amqp.Delivery{Body: []byte("cpu value=1\n")}
+	closed := make(chan *amqp.Error)
+
+	a.wg.Add(1)
+	go a.process(msgs, closed)
+	acc.Wait(1)
+
+	close(closed)
+	stopped := make(chan struct{})
+	go func() {
+		a.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("process did not return")
+	}
+}
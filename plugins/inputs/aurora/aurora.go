@@ -15,7 +15,9 @@ import (
 )
 
 type Aurora struct {
-	Timeout    int    `toml:"timeout"`
+	Timeout    int      `toml:"timeout"`
+	Schedulers []string `toml:"schedulers"`
+	// Master is the single scheduler of the older configurations.
 	Master     string `toml:"master"`
 	HttpPrefix string `toml:"prefix"`
 	Numeric    bool   `toml:"numeric"`
@@ -23,12 +25,14 @@ type Aurora struct {
 
 var sampleConfig = `
   ## Timeout, in ms.
-  timeout = 100
-  ## Aurora Master
-  master = "localhost:8081"
+  timeout = 1000
+  ## Aurora schedulers. Only the metrics of the leading scheduler are
+  ## gathered.
+  schedulers = ["localhost:8081"]
   ## Http Prefix
   prefix = "http"
-  ## Numeric values only
+  ## Numeric values only. If false, the non-numeric vars are gathered as
+  ## string fields.
   numeric = true
 `
 
@@ -39,38 +43,45 @@ func (a *Aurora) SampleConfig() string {
 
 // Description just returns a short description of the Mesos plugin
 func (a *Aurora) Description() string {
-	return "Telegraf plugin for gathering metrics from N Apache Aurora Masters"
+	return "Gather metrics from the leader of Apache Aurora schedulers"
 }
 
 func (a *Aurora) SetDefaults() {
 	if a.Timeout == 0 {
 		log.Println("I! [aurora] Missing timeout value, setting default value (1000ms)")
 		a.Timeout = 1000
-	} else if a.HttpPrefix == "" {
+	}
+	if a.HttpPrefix == "" {
 		log.Println("I! [aurora] Missing http prefix value, setting default value (http)")
 		a.HttpPrefix = "http"
 	}
+	if len(a.Schedulers) == 0 && a.Master != "" {
+		a.Schedulers = []string{a.Master}
+	}
 }
 
-
-// Converts string values taken from aurora vars to numeric values for wavefront 
+// Converts string values taken from aurora vars to numeric values for wavefront
 func convertToNumeric(value string) (interface{}, bool) {
-	var err error
-	var val interface{}
-	if val, err = strconv.ParseFloat(value, 64); err == nil {
+	if val, err := strconv.ParseFloat(value, 64); err == nil {
 		return val, true
 	}
-	if val, err = strconv.ParseBool(value); err != nil {
-		return val.(bool), false
+	if val, err := strconv.ParseBool(value); err == nil {
+		return val, true
 	}
-	return val, true
+	return value, false
 }
 
+// Regex for matching job specific metrics like sla_role2/prod2/jobname2_job_uptime_50.00_sec
+var jobMetricRe = regexp.MustCompile(`^sla_([^/]+)/([^/]+)/(.+)$`)
+
+// Regex for matching the SLA metrics at the end of a job metric, as job
+// names may contain underscores.
+var slaMetricRe = regexp.MustCompile(
+	`_(job_uptime_(\d+(?:\.\d+)?)_sec|platform_uptime_percent|mtt[a-z]_(?:nonprod_)?ms)$`)
+
 // Matches job keys like sla_role2/prod2/jobname2_job_uptime_50.00_sec
 func isJobMetric(key string) bool {
-	// Regex for matching job specific tasks
-	re := regexp.MustCompile("^sla_(.*?)/(.*?)/.*")
-	return re.MatchString(key)
+	return jobMetricRe.MatchString(key)
 }
 
 // Checks if the job key starts with task_store indicating it's a task store metric
@@ -78,41 +89,45 @@ func isTaskStore(key string) bool {
 	return strings.HasPrefix(key, "task_store_")
 }
 
-// Checks if the key is a framework_registered which is used to determine leader election
-func isFramework(key string) bool {
-	return strings.HasPrefix(key, "framework_registered")
-}
-
 // This function parses a job metric key like sla_role2/prod2/jobname2_job_uptime_50.00_sec
-// It returns the fields and the tags associated with those fields
+// It returns the fields and the tags associated with those fields. The
+// percentile of the job uptime metrics is a tag.
 func parseJobSpecificMetric(key string, value interface{}) (map[string]interface{}, map[string]string) {
-	// cut off the sla_
-	key = key[4:]
-	// We have previous checked if this is a job metric using isJobMetric so we know there will be 2 slashes
-	slashSplit := strings.Split(key, "/")
-	role := slashSplit[0]
-	env := slashSplit[1]
-	underscoreIdx := strings.Index(slashSplit[2], "_")
-	job := slashSplit[2][:underscoreIdx]
-	metric := slashSplit[2][underscoreIdx+1:]
+	match := jobMetricRe.FindStringSubmatch(key)
+	role, env, rest := match[1], match[2], match[3]
 
-	fields := make(map[string]interface{})
-	fields[metric] = value
+	tags := map[string]string{
+		"role": role,
+		"env":  env,
+	}
 
-	tags := make(map[string]string)
-	tags["role"] = role
-	tags["env"] = env
+	var job, metric string
+	if sla := slaMetricRe.FindStringSubmatchIndex(rest); sla != nil {
+		job = rest[:sla[0]]
+		metric = rest[sla[2]:sla[3]]
+		if sla[4] != -1 {
+			percentile, _ := strconv.ParseFloat(rest[sla[4]:sla[5]], 64)
+			tags["percentile"] = strconv.FormatFloat(percentile, 'f', -1, 64)
+			metric = "job_uptime_sec"
+		}
+	} else if underscoreIdx := strings.Index(rest, "_"); underscoreIdx != -1 {
+		job = rest[:underscoreIdx]
+		metric = rest[underscoreIdx+1:]
+	} else {
+		job = rest
+		metric = "value"
+	}
 	tags["job"] = job
+
+	fields := map[string]interface{}{
+		metric: value,
+	}
 	return fields, tags
 }
 
 // This function takes a metric like task_store_DRAINED and generates aurora.task.store.DRAINED
-func parseTaskStore(key string, value interface{}) (map[string]interface{}, map[string]string) {
-	metric := "task.store." + strings.Replace(key[len("task_store_"):], "_", ".", -1)
-	fields := make(map[string]interface{})
-	fields[metric] = value
-	tags := make(map[string]string)
-	return fields, tags
+func parseTaskStore(key string) string {
+	return "task.store." + strings.Replace(key[len("task_store_"):], "_", ".", -1)
 }
 
 // This method parses the value out of the variable line which is always in the last place
@@ -132,57 +147,106 @@ func (a *Aurora) parseMetric(line string) (string, interface{}, error) {
 	return key, numeric, nil
 }
 
-// Gather() metrics from given list of Aurora Masters
+// findLeader returns the leading scheduler, whose /leaderhealth endpoint is
+// the only one to succeed.
+func (a *Aurora) findLeader(client *http.Client) (string, error) {
+	var errs []string
+	for _, scheduler := range a.Schedulers {
+		url := fmt.Sprintf("%s://%s/leaderhealth", a.HttpPrefix, scheduler)
+		resp, err := client.Get(url)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return scheduler, nil
+		}
+	}
+	if len(errs) > 0 {
+		return "", fmt.Errorf("no leader among the schedulers %s: %s",
+			strings.Join(a.Schedulers, ", "), strings.Join(errs, ", "))
+	}
+	return "", fmt.Errorf("no leader among the schedulers %s",
+		strings.Join(a.Schedulers, ", "))
+}
+
+// Gather() metrics from the leader of the given list of Aurora schedulers
 func (a *Aurora) Gather(acc telegraf.Accumulator) error {
 	a.SetDefaults()
 
-	client := http.Client{
-		Timeout: time.Duration(a.Timeout) * time.Second,
+	client := &http.Client{
+		Timeout: time.Duration(a.Timeout) * time.Millisecond,
+	}
+	leader, err := a.findLeader(client)
+	if err != nil {
+		return err
 	}
-	url := fmt.Sprintf("%s://%s/vars", a.HttpPrefix, a.Master)
+
+	url := fmt.Sprintf("%s://%s/vars", a.HttpPrefix, leader)
 	resp, err := client.Get(url)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	data, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+
+	// The job metrics are grouped by their tags, the other vars are the
+	// fields of a single metric.
+	fields := make(map[string]interface{})
+	jobs := make(map[string]*job)
+	var order []string
 
 	lines := strings.Split(string(data), "\n")
 	for _, line := range lines {
-		if isJobMetric(line) {
-			key, value, err := a.parseMetric(line)
-			if err != nil {
-				continue
-			}
-			fields, tags := parseJobSpecificMetric(key, value)
-			// Per job there are different tags so need to add a field per line
-			acc.AddFields("aurora", fields, tags)
-		} else if isTaskStore(line) {
-			key, value, err := a.parseMetric(line)
-			if err != nil {
-				continue
-			}
-			fields, tags := parseTaskStore(key, value)
-			acc.AddFields("aurora", fields, tags)
-		} else if isFramework(line) {
-			key, value, err := a.parseMetric(line)
-			if err != nil {
-				continue
+		key, value, err := a.parseMetric(line)
+		if err != nil {
+			continue
+		}
+		switch {
+		case isJobMetric(key):
+			jobFields, tags := parseJobSpecificMetric(key, value)
+			id := tags["role"] + "/" + tags["env"] + "/" + tags["job"] + "/" +
+				tags["percentile"]
+			j, ok := jobs[id]
+			if !ok {
+				j = &job{tags: tags, fields: make(map[string]interface{})}
+				jobs[id] = j
+				order = append(order, id)
 			}
-			fields := map[string]interface{}{
-				key: value,
+			for k, v := range jobFields {
+				j.fields[k] = v
 			}
-			tags := make(map[string]string)
-			acc.AddFields("aurora", fields, tags)
+		case isTaskStore(key):
+			fields[parseTaskStore(key)] = value
+		default:
+			fields[key] = value
 		}
 	}
+
+	now := time.Now()
+	if len(fields) > 0 {
+		acc.AddFields("aurora", fields, map[string]string{}, now)
+	}
+	for _, id := range order {
+		acc.AddFields("aurora", jobs[id].fields, jobs[id].tags, now)
+	}
 	return nil
 }
 
+// job holds the SLA metrics of a job.
+type job struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
 func init() {
 	inputs.Add("aurora", func() telegraf.Input {
 		return &Aurora{}
@@ -9,9 +9,11 @@ import (
 
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var masterServer *httptest.Server
+var followerServer *httptest.Server
 
 func getRawMetrics() string {
 	return `assigner_launch_failures 0
@@ -20,7 +22,10 @@ sla_cluster_mtta_ms 18
 sla_disk_small_mttr_ms 1029
 sla_cpu_small_mtta_ms 17
 jvm_prop_java.endorsed.dirs /usr/lib/jvm/java-8-openjdk-amd64/jre/lib/endorsed
-sla_role2/prod2/jobname2_job_uptime_50.00_sec 25`
+sla_role2/prod2/jobname2_job_uptime_50.00_sec 25
+sla_role2/prod2/jobname2_job_uptime_99.00_sec 12
+sla_role2/prod2/jobname2_platform_uptime_percent 99.5
+task_store_DRAINED 3`
 }
 
 func TestMain(m *testing.M) {
@@ -29,13 +34,27 @@ func TestMain(m *testing.M) {
 	masterRouter := http.NewServeMux()
 	masterRouter.HandleFunc("/vars", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, metrics)
+		fmt.Fprint(w, metrics)
+	})
+	masterRouter.HandleFunc("/leaderhealth", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
 	})
 	masterServer = httptest.NewServer(masterRouter)
 
+	followerRouter := http.NewServeMux()
+	followerRouter.HandleFunc("/vars", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, "sla_role2/prod2/jobname2_job_uptime_50.00_sec 0")
+	})
+	followerRouter.HandleFunc("/leaderhealth", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	followerServer = httptest.NewServer(followerRouter)
+
 	rc := m.Run()
 
 	masterServer.Close()
+	followerServer.Close()
 	os.Exit(rc)
 }
 
@@ -88,12 +107,13 @@ func TestIsJobMetric(t *testing.T) {
 
 func TestParseJobSpecificMetric(t *testing.T) {
 	var expectedFields = map[string]interface{}{
-		"job_uptime_50.00_sec": 0,
+		"job_uptime_sec": 0,
 	}
 	var expectedTags = map[string]string{
-		"role": "role2",
-		"env":  "prod2",
-		"job":  "jobname2",
+		"role":       "role2",
+		"env":        "prod2",
+		"job":        "jobname2",
+		"percentile": "50",
 	}
 	key := "sla_role2/prod2/jobname2_job_uptime_50.00_sec"
 	value := 0
@@ -102,12 +122,31 @@ func TestParseJobSpecificMetric(t *testing.T) {
 	assert.Equal(t, tags, expectedTags)
 }
 
+func TestParseJobSpecificMetricUnderscores(t *testing.T) {
+	fields, tags := parseJobSpecificMetric("sla_www_data/prod/hello_world_mttr_ms", 5)
+	assert.Equal(t, map[string]interface{}{"mttr_ms": 5}, fields)
+	assert.Equal(t, map[string]string{
+		"role": "www_data",
+		"env":  "prod",
+		"job":  "hello_world",
+	}, tags)
+
+	fields, tags = parseJobSpecificMetric("sla_www_data/prod/hello_world_job_uptime_99.90_sec", 5)
+	assert.Equal(t, map[string]interface{}{"job_uptime_sec": 5}, fields)
+	assert.Equal(t, map[string]string{
+		"role":       "www_data",
+		"env":        "prod",
+		"job":        "hello_world",
+		"percentile": "99.9",
+	}, tags)
+}
+
 func TestAuroraMaster(t *testing.T) {
 	var acc testutil.Accumulator
 
 	m := Aurora{
 		Master:     masterServer.Listener.Addr().String(),
-		Timeout:    10,
+		Timeout:    1000,
 		HttpPrefix: "http",
 		Numeric:    true,
 	}
@@ -117,8 +156,81 @@ func TestAuroraMaster(t *testing.T) {
 		t.Error(err)
 	}
 
-	var referenceMetrics = map[string]interface{}{
-		"job_uptime_50.00_sec": 25.0,
+	acc.AssertContainsTaggedFields(t, "aurora",
+		map[string]interface{}{
+			"job_uptime_sec": 25.0,
+		},
+		map[string]string{
+			"role":       "role2",
+			"env":        "prod2",
+			"job":        "jobname2",
+			"percentile": "50",
+		})
+	acc.AssertContainsTaggedFields(t, "aurora",
+		map[string]interface{}{
+			"platform_uptime_percent": 99.5,
+		},
+		map[string]string{
+			"role": "role2",
+			"env":  "prod2",
+			"job":  "jobname2",
+		})
+	acc.AssertContainsTaggedFields(t, "aurora",
+		map[string]interface{}{
+			"assigner_launch_failures": 0.0,
+			"cron_job_triggers":        240.0,
+			"sla_cluster_mtta_ms":      18.0,
+			"sla_disk_small_mttr_ms":   1029.0,
+			"sla_cpu_small_mtta_ms":    17.0,
+			"task.store.DRAINED":       3.0,
+		},
+		map[string]string{})
+}
+
+func TestAuroraNonNumeric(t *testing.T) {
+	var acc testutil.Accumulator
+
+	m := Aurora{
+		Schedulers: []string{masterServer.Listener.Addr().String()},
+		Timeout:    1000,
+		HttpPrefix: "http",
+	}
+	require.NoError(t, m.Gather(&acc))
+
+	fields, ok := acc.Get("aurora")
+	require.True(t, ok)
+	assert.Equal(t, "/usr/lib/jvm/java-8-openjdk-amd64/jre/lib/endorsed",
+		fields.Fields["jvm_prop_java.endorsed.dirs"])
+}
+
+func TestAuroraLeader(t *testing.T) {
+	var acc testutil.Accumulator
+
+	m := Aurora{
+		Schedulers: []string{
+			followerServer.Listener.Addr().String(),
+			masterServer.Listener.Addr().String(),
+		},
+		Timeout:    1000,
+		HttpPrefix: "http",
+		Numeric:    true,
 	}
-	acc.AssertContainsFields(t, "aurora", referenceMetrics)
+	require.NoError(t, m.Gather(&acc))
+	acc.AssertContainsTaggedFields(t, "aurora",
+		map[string]interface{}{
+			"job_uptime_sec": 25.0,
+		},
+		map[string]string{
+			"role":       "role2",
+			"env":        "prod2",
+			"job":        "jobname2",
+			"percentile": "50",
+		})
+
+	m.Schedulers = m.Schedulers[:1]
+	acc = testutil.Accumulator{}
+	err := m.Gather(&acc)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no leader")
+	assert.Empty(t, acc.Metrics)
 }
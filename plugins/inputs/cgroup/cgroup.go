@@ -1,13 +1,20 @@
 package cgroup
 
 import (
+	"fmt"
+	"regexp"
+
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 type CGroup struct {
-	Paths []string `toml:"paths"`
-	Files []string `toml:"files"`
+	Paths        []string `toml:"paths"`
+	Files        []string `toml:"files"`
+	UnifiedPaths []string `toml:"unified_paths"`
+	TagPatterns  []string `toml:"tag_patterns"`
+
+	tagRegexps []*regexp.Regexp
 }
 
 var sampleConfig = `
@@ -23,6 +30,22 @@ var sampleConfig = `
   ## cgroup stat fields, as file names, globs are supported.
   ## these file names are appended to each path from above.
   # files = ["memory.*usage*", "memory.limit_in_bytes"]
+
+  ## Directories of the cgroup v2 unified hierarchy, globs are supported.
+  ## Their cpu.stat, memory.stat, memory.current, io.stat, pids.current and
+  ## pressure stall information files are gathered.
+  # unified_paths = [
+  #   "/sys/fs/cgroup/system.slice/*.service",
+  #   "/sys/fs/cgroup/system.slice/docker-*.scope",
+  # ]
+
+  ## Regular expressions matched against the cgroup paths, their named
+  ## groups are added as tags.
+  # tag_patterns = [
+  #   "/system[.]slice/(?P<systemd_unit>[^/]+)$",
+  #   "docker-(?P<container_id>[0-9a-f]{64})[.]scope$",
+  #   "/mesos/(?P<mesos_container_id>[0-9a-f-]{36})$",
+  # ]
 `
 
 func (g *CGroup) SampleConfig() string {
@@ -33,6 +56,41 @@ func (g *CGroup) Description() string {
 	return "Read specific statistics per cgroup"
 }
 
+// compileTagPatterns compiles the tag patterns, once.
+func (g *CGroup) compileTagPatterns() error {
+	if g.tagRegexps != nil {
+		return nil
+	}
+	regexps := make([]*regexp.Regexp, 0, len(g.TagPatterns))
+	for _, pattern := range g.TagPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid tag pattern %q: %s", pattern, err)
+		}
+		regexps = append(regexps, re)
+	}
+	g.tagRegexps = regexps
+	return nil
+}
+
+// pathTags returns the tags of a cgroup path: the path itself, and the named
+// groups of the tag patterns matching it.
+func (g *CGroup) pathTags(dir string) map[string]string {
+	tags := map[string]string{"path": dir}
+	for _, re := range g.tagRegexps {
+		match := re.FindStringSubmatch(dir)
+		if match == nil {
+			continue
+		}
+		for i, name := range re.SubexpNames() {
+			if name != "" && match[i] != "" {
+				tags[name] = match[i]
+			}
+		}
+	}
+	return tags
+}
+
 func init() {
 	inputs.Add("cgroup", func() telegraf.Input { return &CGroup{} })
 }
@@ -17,8 +17,12 @@ import (
 const metricName = "cgroup"
 
 func (g *CGroup) Gather(acc telegraf.Accumulator) error {
+	if err := g.compileTagPatterns(); err != nil {
+		return err
+	}
+
 	list := make(chan pathInfo)
-	go g.generateDirs(list)
+	go generateDirs(g.Paths, list)
 
 	for dir := range list {
 		if dir.err != nil {
@@ -30,6 +34,19 @@ func (g *CGroup) Gather(acc telegraf.Accumulator) error {
 		}
 	}
 
+	list = make(chan pathInfo)
+	go generateDirs(g.UnifiedPaths, list)
+
+	for dir := range list {
+		if dir.err != nil {
+			acc.AddError(dir.err)
+			continue
+		}
+		if err := g.gatherUnifiedDir(dir.path, acc); err != nil {
+			acc.AddError(err)
+		}
+	}
+
 	return nil
 }
 
@@ -58,9 +75,7 @@ func (g *CGroup) gatherDir(dir string, acc telegraf.Accumulator) error {
 		}
 	}
 
-	tags := map[string]string{"path": dir}
-
-	acc.AddFields(metricName, fields, tags)
+	acc.AddFields(metricName, fields, g.pathTags(dir))
 
 	return nil
 }
@@ -80,8 +95,10 @@ func isDir(path string) (bool, error) {
 	return result.IsDir(), nil
 }
 
-func (g *CGroup) generateDirs(list chan<- pathInfo) {
-	for _, dir := range g.Paths {
+func generateDirs(paths []string, list chan<- pathInfo) {
+	defer close(list)
+
+	for _, dir := range paths {
 		// getting all dirs that match the pattern 'dir'
 		items, err := filepath.Glob(dir)
 		if err != nil {
@@ -101,10 +118,11 @@ func (g *CGroup) generateDirs(list chan<- pathInfo) {
 			}
 		}
 	}
-	close(list)
 }
 
 func (g *CGroup) generateFiles(dir string, list chan<- pathInfo) {
+	defer close(list)
+
 	for _, file := range g.Files {
 		// getting all file paths that match the pattern 'dir + file'
 		// path.Base make sure that file variable does not contains part of path
@@ -126,7 +144,6 @@ func (g *CGroup) generateFiles(dir string, list chan<- pathInfo) {
 			}
 		}
 	}
-	close(list)
 }
 
 // ======================================================================
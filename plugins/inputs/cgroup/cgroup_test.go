@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -180,3 +181,122 @@ func TestCgroupStatistics_6(t *testing.T) {
 	}
 	acc.AssertContainsTaggedFields(t, "cgroup", fields, tags)
 }
+
+// ======================================================================
+
+const dockerID = "4f2a8c7e6b1d3f5a9c0e2b4d6f8a1c3e5b7d9f0a2c4e6b8d0f1a3c5e7b9d2f4a"
+
+var cg7 = &CGroup{
+	UnifiedPaths: []string{"testdata/unified/system.slice/*"},
+	TagPatterns: []string{
+		"/system[.]slice/(?P<systemd_unit>[^/]+[.]service)$",
+		"docker-(?P<container_id>[0-9a-f]{64})[.]scope$",
+	},
+}
+
+func TestCgroupUnified(t *testing.T) {
+	var acc testutil.Accumulator
+
+	err := acc.GatherError(cg7.Gather)
+	require.NoError(t, err)
+
+	dockerPath := "testdata/unified/system.slice/docker-" + dockerID + ".scope"
+	tags := map[string]string{
+		"path":         dockerPath,
+		"container_id": dockerID,
+	}
+	fields := map[string]interface{}{
+		"cpu.stat.usage_usec":      int64(8211372),
+		"cpu.stat.user_usec":       int64(5123410),
+		"cpu.stat.system_usec":     int64(3087962),
+		"cpu.stat.nr_periods":      int64(1200),
+		"cpu.stat.nr_throttled":    int64(35),
+		"cpu.stat.throttled_usec":  int64(912345),
+		"memory.stat.anon":         int64(104857600),
+		"memory.stat.file":         int64(52428800),
+		"memory.stat.kernel_stack": int64(294912),
+		"memory.stat.sock":         int64(0),
+		"memory.stat.shmem":        int64(0),
+		"memory.stat.file_mapped":  int64(20971520),
+		"memory.stat.file_dirty":   int64(4096),
+		"memory.stat.pgfault":      int64(183422),
+		"memory.stat.pgmajfault":   int64(12),
+		"memory.current":           int64(163840000),
+		"pids.current":             int64(17),
+	}
+	acc.AssertContainsTaggedFields(t, "cgroup", fields, tags)
+
+	tags = map[string]string{
+		"path":         dockerPath,
+		"container_id": dockerID,
+		"device":       "8:0",
+	}
+	fields = map[string]interface{}{
+		"rbytes": int64(1459200),
+		"wbytes": int64(314773504),
+		"rios":   int64(192),
+		"wios":   int64(353),
+		"dbytes": int64(0),
+		"dios":   int64(0),
+	}
+	acc.AssertContainsTaggedFields(t, "cgroup_io", fields, tags)
+	tags["device"] = "253:0"
+	acc.AssertContainsTaggedFields(t, "cgroup_io", fields, tags)
+
+	tags = map[string]string{
+		"path":         dockerPath,
+		"container_id": dockerID,
+		"resource":     "cpu",
+		"type":         "some",
+	}
+	fields = map[string]interface{}{
+		"avg10":  1.25,
+		"avg60":  0.5,
+		"avg300": 0.1,
+		"total":  int64(2837491),
+	}
+	acc.AssertContainsTaggedFields(t, "cgroup_pressure", fields, tags)
+
+	tags = map[string]string{
+		"path":         dockerPath,
+		"container_id": dockerID,
+		"resource":     "memory",
+		"type":         "full",
+	}
+	fields = map[string]interface{}{
+		"avg10":  0.0,
+		"avg60":  0.08,
+		"avg300": 0.02,
+		"total":  int64(98211),
+	}
+	acc.AssertContainsTaggedFields(t, "cgroup_pressure", fields, tags)
+
+	// the missing files of the controllers not enabled are skipped
+	tags = map[string]string{
+		"path":         "testdata/unified/system.slice/sshd.service",
+		"systemd_unit": "sshd.service",
+	}
+	fields = map[string]interface{}{
+		"cpu.stat.usage_usec":  int64(271094),
+		"cpu.stat.user_usec":   int64(120333),
+		"cpu.stat.system_usec": int64(150761),
+		"memory.current":       int64(4329472),
+		"pids.current":         int64(1),
+	}
+	acc.AssertContainsTaggedFields(t, "cgroup", fields, tags)
+
+	assert.Len(t, acc.Metrics, 10)
+}
+
+func TestCgroupInvalidTagPattern(t *testing.T) {
+	var acc testutil.Accumulator
+
+	cg := &CGroup{
+		Paths:       []string{"testdata/memory"},
+		Files:       []string{"memory.limit_in_bytes"},
+		TagPatterns: []string{"(?P<unit>"},
+	}
+	err := acc.GatherError(cg.Gather)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid tag pattern")
+}
@@ -0,0 +1,164 @@
+// +build linux
+
+package cgroup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+// The files of the cgroup v2 unified hierarchy gathered as the fields of the
+// cgroup metric, either flat keyed, "KEY VAL" per line, or single valued.
+var (
+	unifiedKeyedFiles  = []string{"cpu.stat", "memory.stat"}
+	unifiedSingleFiles = []string{"memory.current", "pids.current"}
+)
+
+// The resources of the pressure stall information files, <resource>.pressure.
+var pressureResources = []string{"cpu", "memory", "io"}
+
+func (g *CGroup) gatherUnifiedDir(dir string, acc telegraf.Accumulator) error {
+	tags := g.pathTags(dir)
+	now := time.Now()
+
+	fields := make(map[string]interface{})
+	for _, name := range unifiedKeyedFiles {
+		lines, err := readUnifiedFile(dir, name)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			kv := strings.Fields(line)
+			if len(kv) != 2 {
+				return fmt.Errorf("%s: invalid line %q", filepath.Join(dir, name), line)
+			}
+			value, err := parseUnifiedValue(kv[1])
+			if err != nil {
+				return fmt.Errorf("%s: %s", filepath.Join(dir, name), err)
+			}
+			fields[name+"."+kv[0]] = value
+		}
+	}
+	for _, name := range unifiedSingleFiles {
+		lines, err := readUnifiedFile(dir, name)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		value, err := parseUnifiedValue(lines[0])
+		if err != nil {
+			return fmt.Errorf("%s: %s", filepath.Join(dir, name), err)
+		}
+		fields[name] = value
+	}
+	if len(fields) > 0 {
+		acc.AddFields(metricName, fields, tags, now)
+	}
+
+	// io.stat, nested keyed by device: "MAJ:MIN KEY=VAL ..."
+	lines, err := readUnifiedFile(dir, "io.stat")
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		device, fields, err := parseNestedKeyed(line)
+		if err != nil {
+			return fmt.Errorf("%s: %s", filepath.Join(dir, "io.stat"), err)
+		}
+		ioTags := copyTags(tags)
+		ioTags["device"] = device
+		acc.AddFields(metricName+"_io", fields, ioTags, now)
+	}
+
+	// pressure stall information: "some|full avg10=VAL avg60=VAL avg300=VAL total=VAL"
+	for _, resource := range pressureResources {
+		name := resource + ".pressure"
+		lines, err := readUnifiedFile(dir, name)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			kind, fields, err := parseNestedKeyed(line)
+			if err != nil {
+				return fmt.Errorf("%s: %s", filepath.Join(dir, name), err)
+			}
+			pressureTags := copyTags(tags)
+			pressureTags["resource"] = resource
+			pressureTags["type"] = kind
+			acc.AddFields(metricName+"_pressure", fields, pressureTags, now)
+		}
+	}
+
+	return nil
+}
+
+// readUnifiedFile returns the non empty lines of a file of a cgroup. A missing
+// file, as for the controllers not enabled in the cgroup or the pressure
+// stall information disabled in the kernel, has no lines.
+func readUnifiedFile(dir, name string) ([]string, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EOPNOTSUPP {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// parseNestedKeyed parses a nested keyed line, "KEY SUBKEY0=VAL0 ...".
+func parseNestedKeyed(line string) (string, map[string]interface{}, error) {
+	items := strings.Fields(line)
+	fields := make(map[string]interface{}, len(items)-1)
+	for _, item := range items[1:] {
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("invalid line %q", line)
+		}
+		value, err := parseUnifiedValue(kv[1])
+		if err != nil {
+			return "", nil, err
+		}
+		fields[kv[0]] = value
+	}
+	return items[0], fields, nil
+}
+
+// parseUnifiedValue parses the integer, or else float, values of the files.
+func parseUnifiedValue(s string) (interface{}, error) {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", s)
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	c := make(map[string]string, len(tags)+2)
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/wvanbergen/kafka/consumergroup"
 )
 
+const defaultMaxUndeliveredMessages = 1000
+
 type Kafka struct {
 	ConsumerGroup   string
 	Topics          []string
@@ -30,6 +32,10 @@ type Kafka struct {
 	Offset string
 	parser parsers.Parser
 
+	// MaxUndeliveredMessages is the maximum number of messages read from
+	// kafka whose metrics have not been written by the outputs yet.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	sync.Mutex
 
 	// channel for all incoming kafka messages
@@ -39,7 +45,9 @@ type Kafka struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
+	// messages whose metrics are not delivered yet
+	undelivered *undeliveredMessages
 
 	// doNotCommitMsgs tells the parser not to call CommitUpTo on the consumer
 	// this is mostly for test purposes, but there may be a use-case for it later.
@@ -67,6 +75,12 @@ var sampleConfig = `
   ## Maximum length of a message to consume, in bytes (default 0/unlimited);
   ## larger messages are dropped
   max_message_len = 65536
+
+  ## Maximum number of messages read from kafka whose metrics are not yet
+  ## written by the outputs. Offsets are only committed once the metrics of
+  ## a message are written, so this also bounds how many messages are read
+  ## again after a restart.
+  # max_undelivered_messages = 1000
 `
 
 func (k *Kafka) SampleConfig() string {
@@ -86,7 +100,10 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 	defer k.Unlock()
 	var consumerErr error
 
-	k.acc = acc
+	if k.MaxUndeliveredMessages <= 0 {
+		k.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 
 	config := consumergroup.NewConfig()
 	config.Zookeeper.Chroot = k.ZookeeperChroot
@@ -127,9 +144,17 @@ func (k *Kafka) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. The offset of a message is committed once its
+// metrics are delivered.
 func (k *Kafka) receiver() {
+	k.undelivered = newUndeliveredMessages()
 	for {
+		// stop reading messages while too many are undelivered
+		in := k.in
+		if k.undelivered.Len() >= k.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-k.done:
 			return
@@ -137,32 +162,41 @@ func (k *Kafka) receiver() {
 			if err != nil {
 				k.acc.AddError(fmt.Errorf("Consumer Error: %s\n", err))
 			}
-		case msg := <-k.in:
+		case info := <-k.acc.Delivered():
+			if msg := k.undelivered.Delivered(info.ID()); msg != nil {
+				k.commit(msg)
+			}
+		case msg := <-in:
+			var metrics []telegraf.Metric
 			if k.MaxMessageLen != 0 && len(msg.Value) > k.MaxMessageLen {
 				k.acc.AddError(fmt.Errorf("Message longer than max_message_len (%d > %d)",
 					len(msg.Value), k.MaxMessageLen))
 			} else {
-				metrics, err := k.parser.Parse(msg.Value)
+				var err error
+				metrics, err = k.parser.Parse(msg.Value)
 				if err != nil {
 					k.acc.AddError(fmt.Errorf("Message Parse Error\nmessage: %s\nerror: %s",
 						string(msg.Value), err.Error()))
 				}
-				for _, metric := range metrics {
-					k.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-				}
-			}
-
-			if !k.doNotCommitMsgs {
-				// TODO(cam) this locking can be removed if this PR gets merged:
-				// https://github.com/wvanbergen/kafka/pull/84
-				k.Lock()
-				k.Consumer.CommitUpto(msg)
-				k.Unlock()
 			}
+			// messages without metrics are tracked too, so that their offset
+			// is only committed after the messages read before them.
+			k.undelivered.Add(k.acc.AddTrackingMetricGroup(metrics), msg)
 		}
 	}
 }
 
+func (k *Kafka) commit(msg *sarama.ConsumerMessage) {
+	if k.doNotCommitMsgs {
+		return
+	}
+	// TODO(cam) this locking can be removed if this PR gets merged:
+	// https://github.com/wvanbergen/kafka/pull/84
+	k.Lock()
+	k.Consumer.CommitUpto(msg)
+	k.Unlock()
+}
+
 func (k *Kafka) Stop() {
 	k.Lock()
 	defer k.Unlock()
@@ -178,6 +212,8 @@ func (k *Kafka) Gather(acc telegraf.Accumulator) error {
 
 func init() {
 	inputs.Add("kafka_consumer", func() telegraf.Input {
-		return &Kafka{}
+		return &Kafka{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
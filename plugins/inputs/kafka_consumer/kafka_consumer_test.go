@@ -29,6 +29,8 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 		doNotCommitMsgs: true,
 		errs:            make(chan error, 1000),
 		done:            make(chan struct{}),
+
+		MaxUndeliveredMessages: 1000,
 	}
 	return &k, in
 }
@@ -37,7 +39,7 @@ func newTestKafka() (*Kafka, chan *sarama.ConsumerMessage) {
 func TestRunParser(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -52,7 +54,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -69,7 +71,7 @@ func TestDropOverlongMsg(t *testing.T) {
 	k, in := newTestKafka()
 	k.MaxMessageLen = maxMessageLen
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 	overlongMsg := strings.Repeat("v", maxMessageLen+1)
 
@@ -84,7 +86,7 @@ func TestDropOverlongMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewInfluxParser()
@@ -103,7 +105,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -122,7 +124,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	k, in := newTestKafka()
 	acc := testutil.Accumulator{}
-	k.acc = &acc
+	k.acc = acc.WithTracking(k.MaxUndeliveredMessages)
 	defer close(k.done)
 
 	k.parser, _ = parsers.NewJSONParser("kafka_json_test", []string{}, nil)
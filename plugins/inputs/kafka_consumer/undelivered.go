@@ -0,0 +1,72 @@
+package kafka_consumer
+
+import (
+	"github.com/influxdata/telegraf"
+
+	"github.com/Shopify/sarama"
+)
+
+type partition struct {
+	topic string
+	id    int32
+}
+
+type pendingMessage struct {
+	msg       *sarama.ConsumerMessage
+	delivered bool
+}
+
+// undeliveredMessages keeps track of the messages whose metrics are not
+// delivered yet. Since committing an offset commits every message before it
+// in the partition, a message can only be committed once all the messages
+// read before it from the same partition are delivered.
+type undeliveredMessages struct {
+	byID        map[telegraf.TrackingID]*pendingMessage
+	byPartition map[partition][]*pendingMessage
+}
+
+func newUndeliveredMessages() *undeliveredMessages {
+	return &undeliveredMessages{
+		byID:        make(map[telegraf.TrackingID]*pendingMessage),
+		byPartition: make(map[partition][]*pendingMessage),
+	}
+}
+
+// Len returns the number of undelivered messages.
+func (u *undeliveredMessages) Len() int {
+	return len(u.byID)
+}
+
+// Add adds a message whose metrics are tracked with the given id.
+func (u *undeliveredMessages) Add(id telegraf.TrackingID, msg *sarama.ConsumerMessage) {
+	p := &pendingMessage{msg: msg}
+	key := partition{topic: msg.Topic, id: msg.Partition}
+	u.byID[id] = p
+	u.byPartition[key] = append(u.byPartition[key], p)
+}
+
+// Delivered marks the message tracked with the given id as delivered. It
+// returns the last message of its partition that can be committed, or nil if
+// earlier messages of the partition are still undelivered.
+func (u *undeliveredMessages) Delivered(id telegraf.TrackingID) *sarama.ConsumerMessage {
+	p, ok := u.byID[id]
+	if !ok {
+		return nil
+	}
+	delete(u.byID, id)
+	p.delivered = true
+
+	key := partition{topic: p.msg.Topic, id: p.msg.Partition}
+	queue := u.byPartition[key]
+	var commit *sarama.ConsumerMessage
+	for len(queue) > 0 && queue[0].delivered {
+		commit = queue[0].msg
+		queue = queue[1:]
+	}
+	if len(queue) == 0 {
+		delete(u.byPartition, key)
+	} else {
+		u.byPartition[key] = queue
+	}
+	return commit
+}
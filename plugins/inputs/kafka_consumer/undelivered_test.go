@@ -0,0 +1,61 @@
+package kafka_consumer
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/assert"
+)
+
+func partitionMsg(partition int32, offset int64) *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic:     "telegraf",
+		Partition: partition,
+		Offset:    offset,
+	}
+}
+
+func TestUndeliveredInOrder(t *testing.T) {
+	u := newUndeliveredMessages()
+	msg1, msg2 := partitionMsg(0, 1), partitionMsg(0, 2)
+	u.Add(telegraf.TrackingID(1), msg1)
+	u.Add(telegraf.TrackingID(2), msg2)
+	assert.Equal(t, 2, u.Len())
+
+	assert.Equal(t, msg1, u.Delivered(telegraf.TrackingID(1)))
+	assert.Equal(t, msg2, u.Delivered(telegraf.TrackingID(2)))
+	assert.Equal(t, 0, u.Len())
+}
+
+// Test that a message is not committed before the earlier messages of its
+// partition are delivered.
+func TestUndeliveredOutOfOrder(t *testing.T) {
+	u := newUndeliveredMessages()
+	msg1, msg2, msg3 := partitionMsg(0, 1), partitionMsg(0, 2), partitionMsg(0, 3)
+	u.Add(telegraf.TrackingID(1), msg1)
+	u.Add(telegraf.TrackingID(2), msg2)
+	u.Add(telegraf.TrackingID(3), msg3)
+
+	assert.Nil(t, u.Delivered(telegraf.TrackingID(3)))
+	assert.Nil(t, u.Delivered(telegraf.TrackingID(2)))
+	assert.Equal(t, 1, u.Len())
+	assert.Equal(t, msg3, u.Delivered(telegraf.TrackingID(1)))
+	assert.Equal(t, 0, u.Len())
+}
+
+func TestUndeliveredPartitionsAreIndependent(t *testing.T) {
+	u := newUndeliveredMessages()
+	msg1, msg2 := partitionMsg(0, 1), partitionMsg(1, 1)
+	u.Add(telegraf.TrackingID(1), msg1)
+	u.Add(telegraf.TrackingID(2), msg2)
+
+	assert.Equal(t, msg2, u.Delivered(telegraf.TrackingID(2)))
+	assert.Equal(t, msg1, u.Delivered(telegraf.TrackingID(1)))
+}
+
+func TestUndeliveredUnknownID(t *testing.T) {
+	u := newUndeliveredMessages()
+	assert.Nil(t, u.Delivered(telegraf.TrackingID(1)))
+}
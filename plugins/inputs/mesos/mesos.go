@@ -4,11 +4,14 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,9 +33,9 @@ type Mesos struct {
 	MasterCols []string `toml:"master_collections"`
 	Slaves     []string
 	SlaveCols  []string `toml:"slave_collections"`
-	//SlaveTasks bool
-	SSL      bool `toml:"ssl"`
-	Insecure bool `toml:"insecure"`
+	SlaveTasks bool     `toml:"slave_tasks"`
+	SSL        bool     `toml:"ssl"`
+	Insecure   bool     `toml:"insecure"`
 }
 
 var allMetrics = map[Role][]string{
@@ -68,6 +71,9 @@ var sampleConfig = `
   #   "tasks",
   #   "messages",
   # ]
+  ## Gather the resource usage of the tasks of the slaves, tagged with their
+  ## framework, task name, container and Aurora job.
+  # slave_tasks = false
   # enable SSL
   # ssl = false
 `
@@ -128,16 +134,16 @@ func (m *Mesos) Gather(acc telegraf.Accumulator) error {
 			return
 		}(v)
 
-		// if !m.SlaveTasks {
-		// 	continue
-		// }
+		if !m.SlaveTasks {
+			continue
+		}
 
-		// wg.Add(1)
-		// go func(c string) {
-		// 	acc.AddError(m.gatherSlaveTaskMetrics(c, ":5051", acc))
-		// 	wg.Done()
-		// 	return
-		// }(v)
+		wg.Add(1)
+		go func(c string) {
+			acc.AddError(m.gatherSlaveTaskMetrics(c, ":5051", acc))
+			wg.Done()
+			return
+		}(v)
 	}
 
 	wg.Wait()
@@ -417,6 +423,33 @@ type TaskStats struct {
 	Statistics  map[string]interface{} `json:"statistics"`
 }
 
+// AgentState struct for JSON API output /state of the slaves, limited to
+// the tasks running on the slave.
+type AgentState struct {
+	Frameworks []struct {
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		Executors []struct {
+			ID        string      `json:"id"`
+			Container string      `json:"container"`
+			Tasks     []AgentTask `json:"tasks"`
+		} `json:"executors"`
+	} `json:"frameworks"`
+}
+
+// AgentTask is a task of the /state of a slave.
+type AgentTask struct {
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Resources map[string]interface{} `json:"resources"`
+}
+
+// taskInfo is the metadata of the task run by an executor.
+type taskInfo struct {
+	tags   map[string]string
+	fields map[string]interface{}
+}
+
 func (m *Mesos) getHTTPPrefix() string {
 	if m.SSL {
 		return "https://"
@@ -424,6 +457,100 @@ func (m *Mesos) getHTTPPrefix() string {
 	return "http://"
 }
 
+func (m *Mesos) getJSON(url string, v interface{}) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", url, resp.Status)
+	}
+
+	if err = json.Unmarshal(data, v); err != nil {
+		return errors.New("Error decoding JSON response")
+	}
+	return nil
+}
+
+// auroraTaskID matches the ids of the Aurora tasks:
+// <timestamp>-<role>-<env>-<job>-<instance>-<uuid>
+var auroraTaskID = regexp.MustCompile(
+	`^\d+-(.+)-(\d+)-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+// parseAuroraTaskID returns the role, environment, job and instance of an
+// Aurora task. Aurora replaces the slashes of the job key by dashes in the
+// task id, so the job key is split on the slashes of the task name when it
+// is the job key, or else on the dashes when there are exactly three parts.
+func parseAuroraTaskID(id, name string) (map[string]string, bool) {
+	match := auroraTaskID.FindStringSubmatch(id)
+	if match == nil {
+		return nil, false
+	}
+
+	var key []string
+	if parts := strings.Split(name, "/"); len(parts) == 3 &&
+		strings.Join(parts, "-") == match[1] {
+		key = parts
+	} else if parts := strings.Split(match[1], "-"); len(parts) == 3 {
+		key = parts
+	} else {
+		return nil, false
+	}
+
+	return map[string]string{
+		"aurora_role":        key[0],
+		"aurora_environment": key[1],
+		"aurora_job":         key[2],
+		"aurora_instance":    match[2],
+	}, true
+}
+
+// getTaskInfos returns the metadata of the tasks of a slave, by framework
+// and executor id.
+func (m *Mesos) getTaskInfos(address string) (map[string]taskInfo, error) {
+	var state AgentState
+	if err := m.getJSON(m.getHTTPPrefix()+address+"/state", &state); err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]taskInfo)
+	for _, framework := range state.Frameworks {
+		for _, executor := range framework.Executors {
+			info := taskInfo{
+				tags:   map[string]string{"framework_name": framework.Name},
+				fields: make(map[string]interface{}),
+			}
+			if executor.Container != "" {
+				info.tags["container_id"] = executor.Container
+			}
+
+			// executors run a single task, but for custom executors
+			if len(executor.Tasks) > 0 {
+				task := executor.Tasks[0]
+				info.tags["task_name"] = task.Name
+				if aurora, ok := parseAuroraTaskID(task.ID, task.Name); ok {
+					for k, v := range aurora {
+						info.tags[k] = v
+					}
+				}
+				for k, v := range task.Resources {
+					if f, ok := v.(float64); ok {
+						info.fields["resources_"+k] = f
+					}
+				}
+			}
+			infos[framework.ID+"/"+executor.ID] = info
+		}
+	}
+	return infos, nil
+}
+
 func (m *Mesos) gatherSlaveTaskMetrics(address string, defaultPort string, acc telegraf.Accumulator) error {
 	var metrics []TaskStats
 
@@ -433,29 +560,25 @@ func (m *Mesos) gatherSlaveTaskMetrics(address string, defaultPort string, acc t
 		address = address + defaultPort
 	}
 
-	tags := map[string]string{
-		"server": host,
-	}
-
 	ts := strconv.Itoa(m.Timeout) + "ms"
 
-	resp, err := client.Get(m.getHTTPPrefix() + address + "/monitor/statistics?timeout=" + ts)
+	err = m.getJSON(m.getHTTPPrefix()+address+"/monitor/statistics?timeout="+ts, &metrics)
 	if err != nil {
 		return err
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
+	// the metrics are still gathered without the metadata of the tasks
+	infos, err := m.getTaskInfos(address)
 	if err != nil {
-		return err
-	}
-
-	if err = json.Unmarshal([]byte(data), &metrics); err != nil {
-		return errors.New("Error decoding JSON response")
+		acc.AddError(err)
 	}
 
 	for _, task := range metrics {
-		tags["framework_id"] = task.FrameworkID
+		tags := map[string]string{
+			"server":       host,
+			"framework_id": task.FrameworkID,
+			"executor_id":  task.ExecutorID,
+		}
 
 		jf := jsonparser.JSONFlattener{}
 		err = jf.FlattenJSON("", task.Statistics)
@@ -464,8 +587,19 @@ func (m *Mesos) gatherSlaveTaskMetrics(address string, defaultPort string, acc t
 			return err
 		}
 
-		timestamp := time.Unix(int64(jf.Fields["timestamp"].(float64)), 0)
-		jf.Fields["executor_id"] = task.ExecutorID
+		timestamp := time.Now()
+		if t, ok := jf.Fields["timestamp"].(float64); ok {
+			timestamp = time.Unix(int64(t), 0)
+		}
+
+		if info, ok := infos[task.FrameworkID+"/"+task.ExecutorID]; ok {
+			for k, v := range info.tags {
+				tags[k] = v
+			}
+			for k, v := range info.fields {
+				jf.Fields[k] = v
+			}
+		}
 
 		acc.AddFields("mesos_tasks", jf.Fields, tags, timestamp)
 	}
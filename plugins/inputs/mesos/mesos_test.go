@@ -18,7 +18,8 @@ var masterMetrics map[string]interface{}
 var masterTestServer *httptest.Server
 var slaveMetrics map[string]interface{}
 
-// var slaveTaskMetrics map[string]interface{}
+var slaveTaskMetrics map[string]interface{}
+var slaveState map[string]interface{}
 var slaveTestServer *httptest.Server
 
 func randUUID() string {
@@ -27,6 +28,8 @@ func randUUID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
+const auroraTaskId = "1500000000000-www-data-prod-hello-3-0f8e3b5c-1d2a-4e6f-8a9b-7c6d5e4f3a2b"
+
 func generateMetrics() {
 	masterMetrics = make(map[string]interface{})
 
@@ -218,31 +221,58 @@ func generateMetrics() {
 		slaveMetrics[k] = rand.Float64()
 	}
 
-	// slaveTaskMetrics = map[string]interface{}{
-	// 	"executor_id":   fmt.Sprintf("task_name.%s", randUUID()),
-	// 	"executor_name": "Some task description",
-	// 	"framework_id":  randUUID(),
-	// 	"source":        fmt.Sprintf("task_source.%s", randUUID()),
-	// 	"statistics": map[string]interface{}{
-	// 		"cpus_limit":                    rand.Float64(),
-	// 		"cpus_system_time_secs":         rand.Float64(),
-	// 		"cpus_user_time_secs":           rand.Float64(),
-	// 		"mem_anon_bytes":                float64(rand.Int63()),
-	// 		"mem_cache_bytes":               float64(rand.Int63()),
-	// 		"mem_critical_pressure_counter": float64(rand.Int63()),
-	// 		"mem_file_bytes":                float64(rand.Int63()),
-	// 		"mem_limit_bytes":               float64(rand.Int63()),
-	// 		"mem_low_pressure_counter":      float64(rand.Int63()),
-	// 		"mem_mapped_file_bytes":         float64(rand.Int63()),
-	// 		"mem_medium_pressure_counter":   float64(rand.Int63()),
-	// 		"mem_rss_bytes":                 float64(rand.Int63()),
-	// 		"mem_swap_bytes":                float64(rand.Int63()),
-	// 		"mem_total_bytes":               float64(rand.Int63()),
-	// 		"mem_total_memsw_bytes":         float64(rand.Int63()),
-	// 		"mem_unevictable_bytes":         float64(rand.Int63()),
-	// 		"timestamp":                     rand.Float64(),
-	// 	},
-	// }
+	slaveTaskMetrics = map[string]interface{}{
+		"executor_id":   "thermos-" + auroraTaskId,
+		"executor_name": "Some task description",
+		"framework_id":  randUUID(),
+		"source":        fmt.Sprintf("task_source.%s", randUUID()),
+		"statistics": map[string]interface{}{
+			"cpus_limit":                    rand.Float64(),
+			"cpus_system_time_secs":         rand.Float64(),
+			"cpus_user_time_secs":           rand.Float64(),
+			"mem_anon_bytes":                float64(rand.Int63()),
+			"mem_cache_bytes":               float64(rand.Int63()),
+			"mem_critical_pressure_counter": float64(rand.Int63()),
+			"mem_file_bytes":                float64(rand.Int63()),
+			"mem_limit_bytes":               float64(rand.Int63()),
+			"mem_low_pressure_counter":      float64(rand.Int63()),
+			"mem_mapped_file_bytes":         float64(rand.Int63()),
+			"mem_medium_pressure_counter":   float64(rand.Int63()),
+			"mem_rss_bytes":                 float64(rand.Int63()),
+			"mem_swap_bytes":                float64(rand.Int63()),
+			"mem_total_bytes":               float64(rand.Int63()),
+			"mem_total_memsw_bytes":         float64(rand.Int63()),
+			"mem_unevictable_bytes":         float64(rand.Int63()),
+			"timestamp":                     rand.Float64(),
+		},
+	}
+
+	slaveState = map[string]interface{}{
+		"frameworks": []map[string]interface{}{
+			{
+				"id":   slaveTaskMetrics["framework_id"],
+				"name": "aurora",
+				"executors": []map[string]interface{}{
+					{
+						"id":        slaveTaskMetrics["executor_id"],
+						"container": "c8a7b2e1-3f1d-4a5e-9b6c-0d2e4f6a8b0c",
+						"tasks": []map[string]interface{}{
+							{
+								"id":   auroraTaskId,
+								"name": "www-data/prod/hello",
+								"resources": map[string]interface{}{
+									"cpus":  0.5,
+									"mem":   128.0,
+									"disk":  256.0,
+									"ports": "[31000-31000]",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
 }
 
 func TestMain(m *testing.M) {
@@ -262,11 +292,16 @@ func TestMain(m *testing.M) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(slaveMetrics)
 	})
-	// slaveRouter.HandleFunc("/monitor/statistics", func(w http.ResponseWriter, r *http.Request) {
-	// 	w.WriteHeader(http.StatusOK)
-	// 	w.Header().Set("Content-Type", "application/json")
-	// 	json.NewEncoder(w).Encode([]map[string]interface{}{slaveTaskMetrics})
-	// })
+	slaveRouter.HandleFunc("/monitor/statistics", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode([]map[string]interface{}{slaveTaskMetrics})
+	})
+	slaveRouter.HandleFunc("/state", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slaveState)
+	})
 	slaveTestServer = httptest.NewServer(slaveRouter)
 
 	rc := m.Run()
@@ -326,10 +361,10 @@ func TestMesosSlave(t *testing.T) {
 	var acc testutil.Accumulator
 
 	m := Mesos{
-		Masters: []string{},
-		Slaves:  []string{slaveTestServer.Listener.Addr().String()},
-		// SlaveTasks: true,
-		Timeout: 10,
+		Masters:    []string{},
+		Slaves:     []string{slaveTestServer.Listener.Addr().String()},
+		SlaveTasks: true,
+		Timeout:    10,
 	}
 
 	err := acc.GatherError(m.Gather)
@@ -340,17 +375,96 @@ func TestMesosSlave(t *testing.T) {
 
 	acc.AssertContainsFields(t, "mesos", slaveMetrics)
 
-	// expectedFields := make(map[string]interface{}, len(slaveTaskMetrics["statistics"].(map[string]interface{}))+1)
-	// for k, v := range slaveTaskMetrics["statistics"].(map[string]interface{}) {
-	// 	expectedFields[k] = v
-	// }
-	// expectedFields["executor_id"] = slaveTaskMetrics["executor_id"]
-
-	// acc.AssertContainsTaggedFields(
-	// 	t,
-	// 	"mesos_tasks",
-	// 	expectedFields,
-	// 	map[string]string{"server": "127.0.0.1", "framework_id": slaveTaskMetrics["framework_id"].(string)})
+	expectedFields := make(map[string]interface{}, len(slaveTaskMetrics["statistics"].(map[string]interface{})))
+	for k, v := range slaveTaskMetrics["statistics"].(map[string]interface{}) {
+		expectedFields[k] = v
+	}
+	expectedFields["resources_cpus"] = 0.5
+	expectedFields["resources_mem"] = 128.0
+	expectedFields["resources_disk"] = 256.0
+
+	acc.AssertContainsTaggedFields(
+		t,
+		"mesos_tasks",
+		expectedFields,
+		map[string]string{
+			"server":             "127.0.0.1",
+			"framework_id":       slaveTaskMetrics["framework_id"].(string),
+			"executor_id":        slaveTaskMetrics["executor_id"].(string),
+			"framework_name":     "aurora",
+			"task_name":          "www-data/prod/hello",
+			"container_id":       "c8a7b2e1-3f1d-4a5e-9b6c-0d2e4f6a8b0c",
+			"aurora_role":        "www-data",
+			"aurora_environment": "prod",
+			"aurora_job":         "hello",
+			"aurora_instance":    "3",
+		})
+}
+
+func TestMesosSlaveTasksWithoutState(t *testing.T) {
+	var acc testutil.Accumulator
+
+	router := http.NewServeMux()
+	router.HandleFunc("/monitor/statistics", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{slaveTaskMetrics})
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	m := Mesos{Timeout: 10}
+	m.SetDefaults()
+	err := m.gatherSlaveTaskMetrics(ts.Listener.Addr().String(), ":5051", &acc)
+	assert.NoError(t, err)
+	assert.Len(t, acc.Errors, 1)
+
+	assert.True(t, acc.HasTag("mesos_tasks", "framework_id"))
+	assert.False(t, acc.HasTag("mesos_tasks", "framework_name"))
+	assert.True(t, acc.HasTag("mesos_tasks", "executor_id"))
+	assert.False(t, acc.HasField("mesos_tasks", "executor_id"))
+}
+
+func TestParseAuroraTaskID(t *testing.T) {
+	tests := []struct {
+		id   string
+		name string
+		tags map[string]string
+	}{
+		{
+			id:   auroraTaskId,
+			name: "www-data/prod/hello",
+			tags: map[string]string{
+				"aurora_role":        "www-data",
+				"aurora_environment": "prod",
+				"aurora_job":         "hello",
+				"aurora_instance":    "3",
+			},
+		},
+		{
+			id:   "1500000000000-mesos-devel-web_api-12-0f8e3b5c-1d2a-4e6f-8a9b-7c6d5e4f3a2b",
+			name: "web_api",
+			tags: map[string]string{
+				"aurora_role":        "mesos",
+				"aurora_environment": "devel",
+				"aurora_job":         "web_api",
+				"aurora_instance":    "12",
+			},
+		},
+		{
+			// ambiguous job key
+			id:   auroraTaskId,
+			name: "hello",
+		},
+		{
+			id:   "hello.4f5e6d7c-8b9a-0b1c-2d3e-4f5a6b7c8d9e",
+			name: "hello",
+		},
+	}
+
+	for _, tt := range tests {
+		tags, ok := parseAuroraTaskID(tt.id, tt.name)
+		assert.Equal(t, tt.tags != nil, ok, tt.id)
+		assert.Equal(t, tt.tags, tags, tt.id)
+	}
 }
 
 func TestSlaveFilter(t *testing.T) {
@@ -22,6 +22,10 @@ type MQTTConsumer struct {
 	Password string
 	QoS      int `toml:"qos"`
 
+	// Maximum number of messages whose metrics have not been written by the
+	// outputs yet.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	parser parsers.Parser
 
 	// Legacy metric buffer support
@@ -46,16 +50,22 @@ type MQTTConsumer struct {
 	done chan struct{}
 
 	// keep the accumulator internally:
-	acc telegraf.Accumulator
+	acc telegraf.TrackingAccumulator
 
 	started bool
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   servers = ["localhost:1883"]
   ## MQTT QoS, must be 0, 1, or 2
   qos = 0
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. Reading stops while this many messages are in flight.
+  # max_undelivered_messages = 1000
+
   ## Topics to subscribe to
   topics = [
     "telegraf/host01/cpu",
@@ -110,7 +120,10 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 			" = true, you MUST also set client_id")
 	}
 
-	m.acc = acc
+	if m.MaxUndeliveredMessages <= 0 {
+		m.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	m.acc = acc.WithTracking(m.MaxUndeliveredMessages)
 	if m.QoS > 2 || m.QoS < 0 {
 		return fmt.Errorf("MQTT Consumer, invalid QoS value: %d", m.QoS)
 	}
@@ -120,14 +133,14 @@ func (m *MQTTConsumer) Start(acc telegraf.Accumulator) error {
 		return err
 	}
 
+	m.in = make(chan mqtt.Message, 1000)
+	m.done = make(chan struct{})
+
 	m.client = mqtt.NewClient(opts)
 	if token := m.client.Connect(); token.Wait() && token.Error() != nil {
 		return token.Error()
 	}
 
-	m.in = make(chan mqtt.Message, 1000)
-	m.done = make(chan struct{})
-
 	go m.receiver()
 
 	return nil
@@ -156,13 +169,23 @@ func (m *MQTTConsumer) onConnectionLost(c mqtt.Client, err error) {
 }
 
 // receiver() reads all incoming messages from the consumer, and parses them into
-// influxdb metric points.
+// influxdb metric points. The client acknowledges the messages as they are
+// received, reading stops while too many messages are undelivered so that the
+// client stops reading from the server.
 func (m *MQTTConsumer) receiver() {
+	undelivered := 0
 	for {
+		in := m.in
+		if undelivered >= m.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-m.done:
 			return
-		case msg := <-m.in:
+		case <-m.acc.Delivered():
+			undelivered--
+		case msg := <-in:
 			topic := msg.Topic()
 			metrics, err := m.parser.Parse(msg.Payload())
 			if err != nil {
@@ -171,16 +194,19 @@ func (m *MQTTConsumer) receiver() {
 			}
 
 			for _, metric := range metrics {
-				tags := metric.Tags()
-				tags["topic"] = topic
-				m.acc.AddFields(metric.Name(), metric.Fields(), tags, metric.Time())
+				metric.AddTag("topic", topic)
 			}
+			m.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
 
 func (m *MQTTConsumer) recvMessage(_ mqtt.Client, msg mqtt.Message) {
-	m.in <- msg
+	select {
+	case m.in <- msg:
+	case <-m.done:
+	}
 }
 
 func (m *MQTTConsumer) Stop() {
@@ -243,6 +269,8 @@ func (m *MQTTConsumer) createOpts() (*mqtt.ClientOptions, error) {
 
 func init() {
 	inputs.Add("mqtt_consumer", func() telegraf.Input {
-		return &MQTTConsumer{}
+		return &MQTTConsumer{
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
+		}
 	})
 }
@@ -22,10 +22,11 @@ const (
 func newTestMQTTConsumer() (*MQTTConsumer, chan mqtt.Message) {
 	in := make(chan mqtt.Message, 100)
 	n := &MQTTConsumer{
-		Topics:  []string{"telegraf"},
-		Servers: []string{"localhost:1883"},
-		in:      in,
-		done:    make(chan struct{}),
+		Topics:                 []string{"telegraf"},
+		Servers:                []string{"localhost:1883"},
+		MaxUndeliveredMessages: 100,
+		in:                     in,
+		done:                   make(chan struct{}),
 	}
 	return n, in
 }
@@ -79,7 +80,7 @@ func TestPersistentClientIDFail(t *testing.T) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -95,7 +96,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserNegativeNumber(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -112,7 +113,7 @@ func TestRunParserNegativeNumber(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -130,7 +131,7 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -148,7 +149,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -166,7 +167,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestMQTTConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
@@ -184,6 +185,26 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that no message is read while too many are undelivered
+func TestRunParserUndelivered(t *testing.T) {
+	n, in := newTestMQTTConsumer()
+	n.MaxUndeliveredMessages = 1
+	acc := testutil.Accumulator{}
+	n.acc = &testutil.UndeliveredAccumulator{Accumulator: &acc}
+	defer close(n.done)
+
+	n.parser, _ = parsers.NewInfluxParser()
+	go n.receiver()
+	in <- mqttMsg(testMsg)
+	in <- mqttMsg(testMsg)
+	acc.Wait(1)
+
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(23422)},
+		map[string]string{"host": "server01", "topic": "telegraf/unit_test"})
+	assert.Len(t, in, 1)
+}
+
 func mqttMsg(val string) mqtt.Message {
 	return &message{
 		topic:   "telegraf/unit_test",
@@ -32,6 +32,10 @@ type natsConsumer struct {
 	PendingMessageLimit int
 	PendingBytesLimit   int
 
+	// Maximum number of messages whose metrics have not been written by the
+	// outputs yet.
+	MaxUndeliveredMessages int `toml:"max_undelivered_messages"`
+
 	// Legacy metric buffer support
 	MetricBuffer int
 
@@ -47,9 +51,11 @@ type natsConsumer struct {
 	// channel for all NATS read errors
 	errs chan error
 	done chan struct{}
-	acc  telegraf.Accumulator
+	acc  telegraf.TrackingAccumulator
 }
 
+const defaultMaxUndeliveredMessages = 1000
+
 var sampleConfig = `
   ## urls of NATS servers
   # servers = ["nats://localhost:4222"]
@@ -65,6 +71,11 @@ var sampleConfig = `
   # pending_message_limit = 65536
   # pending_bytes_limit = 67108864
 
+  ## Maximum number of messages whose metrics are not yet written by the
+  ## outputs. Reading stops while this many messages are in flight, until
+  ## the pending limits above are reached.
+  # max_undelivered_messages = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -97,7 +108,11 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 	n.Lock()
 	defer n.Unlock()
 
-	n.acc = acc
+	if n.MaxUndeliveredMessages <= 0 {
+		n.MaxUndeliveredMessages = defaultMaxUndeliveredMessages
+	}
+	n.acc = acc.WithTracking(n.MaxUndeliveredMessages)
+	n.done = make(chan struct{})
 
 	var connectErr error
 
@@ -123,9 +138,13 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 		n.Conn.SetErrorHandler(n.natsErrHandler)
 
 		n.in = make(chan *nats.Msg, 1000)
+		done := n.done
 		for _, subj := range n.Subjects {
 			sub, err := n.Conn.QueueSubscribe(subj, n.QueueGroup, func(m *nats.Msg) {
-				n.in <- m
+				select {
+				case n.in <- m:
+				case <-done:
+				}
 			})
 			if err != nil {
 				return err
@@ -142,8 +161,6 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 		}
 	}
 
-	n.done = make(chan struct{})
-
 	// Start the message reader
 	n.wg.Add(1)
 	go n.receiver()
@@ -154,24 +171,33 @@ func (n *natsConsumer) Start(acc telegraf.Accumulator) error {
 }
 
 // receiver() reads all incoming messages from NATS, and parses them into
-// telegraf metrics.
+// telegraf metrics. NATS messages are not acknowledged, reading stops while
+// too many messages are undelivered so that the subscriptions apply their
+// pending limits.
 func (n *natsConsumer) receiver() {
 	defer n.wg.Done()
+	undelivered := 0
 	for {
+		in := n.in
+		if undelivered >= n.MaxUndeliveredMessages {
+			in = nil
+		}
+
 		select {
 		case <-n.done:
 			return
+		case <-n.acc.Delivered():
+			undelivered--
 		case err := <-n.errs:
 			n.acc.AddError(fmt.Errorf("E! error reading from %s\n", err.Error()))
-		case msg := <-n.in:
+		case msg := <-in:
 			metrics, err := n.parser.Parse(msg.Data)
 			if err != nil {
 				n.acc.AddError(fmt.Errorf("E! subject: %s, error: %s", msg.Subject, err.Error()))
 			}
 
-			for _, metric := range metrics {
-				n.acc.AddFields(metric.Name(), metric.Fields(), metric.Tags(), metric.Time())
-			}
+			n.acc.AddTrackingMetricGroup(metrics)
+			undelivered++
 		}
 	}
 }
@@ -204,12 +230,13 @@ func (n *natsConsumer) Gather(acc telegraf.Accumulator) error {
 func init() {
 	inputs.Add("nats_consumer", func() telegraf.Input {
 		return &natsConsumer{
-			Servers:             []string{"nats://localhost:4222"},
-			Secure:              false,
-			Subjects:            []string{"telegraf"},
-			QueueGroup:          "telegraf_consumers",
-			PendingBytesLimit:   nats.DefaultSubPendingBytesLimit,
-			PendingMessageLimit: nats.DefaultSubPendingMsgsLimit,
+			Servers:                []string{"nats://localhost:4222"},
+			Secure:                 false,
+			Subjects:               []string{"telegraf"},
+			QueueGroup:             "telegraf_consumers",
+			PendingBytesLimit:      nats.DefaultSubPendingBytesLimit,
+			PendingMessageLimit:    nats.DefaultSubPendingMsgsLimit,
+			MaxUndeliveredMessages: defaultMaxUndeliveredMessages,
 		}
 	})
 }
@@ -20,13 +20,14 @@ const (
 func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 	in := make(chan *nats.Msg, metricBuffer)
 	n := &natsConsumer{
-		QueueGroup: "test",
-		Subjects:   []string{"telegraf"},
-		Servers:    []string{"nats://localhost:4222"},
-		Secure:     false,
-		in:         in,
-		errs:       make(chan error, metricBuffer),
-		done:       make(chan struct{}),
+		QueueGroup:             "test",
+		Subjects:               []string{"telegraf"},
+		Servers:                []string{"nats://localhost:4222"},
+		Secure:                 false,
+		MaxUndeliveredMessages: metricBuffer,
+		in:                     in,
+		errs:                   make(chan error, metricBuffer),
+		done:                   make(chan struct{}),
 	}
 	return n, in
 }
@@ -35,7 +36,7 @@ func newTestNatsConsumer() (*natsConsumer, chan *nats.Msg) {
 func TestRunParser(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(metricBuffer)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -50,7 +51,7 @@ func TestRunParser(t *testing.T) {
 func TestRunParserInvalidMsg(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(metricBuffer)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -67,7 +68,7 @@ func TestRunParserInvalidMsg(t *testing.T) {
 func TestRunParserAndGather(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(metricBuffer)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewInfluxParser()
@@ -86,7 +87,7 @@ func TestRunParserAndGather(t *testing.T) {
 func TestRunParserAndGatherGraphite(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(metricBuffer)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewGraphiteParser("_", []string{}, nil)
@@ -105,7 +106,7 @@ func TestRunParserAndGatherGraphite(t *testing.T) {
 func TestRunParserAndGatherJSON(t *testing.T) {
 	n, in := newTestNatsConsumer()
 	acc := testutil.Accumulator{}
-	n.acc = &acc
+	n.acc = acc.WithTracking(metricBuffer)
 	defer close(n.done)
 
 	n.parser, _ = parsers.NewJSONParser("nats_json_test", []string{}, nil)
@@ -123,6 +124,25 @@ func TestRunParserAndGatherJSON(t *testing.T) {
 		})
 }
 
+// Test that no message is read while too many are undelivered
+func TestRunParserUndelivered(t *testing.T) {
+	n, in := newTestNatsConsumer()
+	n.MaxUndeliveredMessages = 1
+	acc := testutil.Accumulator{}
+	n.acc = &testutil.UndeliveredAccumulator{Accumulator: &acc}
+
+	n.parser, _ = parsers.NewInfluxParser()
+	n.wg.Add(1)
+	go n.receiver()
+	in <- natsMsg(testMsg)
+	in <- natsMsg(testMsg)
+
+	acc.Wait(1)
+	close(n.done)
+	n.wg.Wait()
+	assert.Len(t, in, 1)
+}
+
 func natsMsg(val string) *nats.Msg {
 	return &nats.Msg{
 		Subject: "telegraf",
@@ -5,19 +5,23 @@
 package netscaler
 
 import (
-	"crypto/tls"
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+	"net/url"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
-
 // Configuration sample for this plugin.
 var sampleConfig = `
   ## IP address of netscaler LB
@@ -30,25 +34,57 @@ var sampleConfig = `
   # https = false
   ## Path of the Nitro API "stat" endpoint
   # ApiPath = "/nitro/v1/stat"
-`
 
-var tr = &http.Transport{
-	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-}
+  ## The stat resources to gather, among "lbvserver", "servicegroup",
+  ## "service", "system", "ns", "ssl", "csvserver", "gslbvserver" and
+  ## "interface".
+  # resources = ["lbvserver"]
 
-var httpClient = &http.Client{
-	Timeout:   time.Second * 3,
-	Transport: tr,
-}
+  ## Log in once and reuse the session for the following requests, instead
+  ## of sending the credentials with each request. The session expires on
+  ## the Netscaler after being idle for session_timeout.
+  # use_session = true
+  # session_timeout = "15m"
+
+  ## Optional SSL Config
+  # ssl_ca = "/etc/telegraf/ca.pem"
+  # ssl_cert = "/etc/telegraf/cert.pem"
+  # ssl_key = "/etc/telegraf/key.pem"
+  ## Use SSL but skip chain & host verification
+  # insecure_skip_verify = true
+
+  ## Timeout of the requests of each resource, and of specific resources.
+  # response_timeout = "5s"
+  # [inputs.netscaler.resource_timeouts]
+  #   servicegroup = "30s"
+`
 
 /*
  * This interface is satisfied by every struct resulting from a
  * request to Netscaler's API.
  */
 type NSResponse interface {
+	check() error
 	publishMetrics(acc telegraf.Accumulator)
 }
 
+/*
+ * The stat resources, by name, with their path and their response.
+ * Service groups are gathered on their own, by gatherServiceGroups.
+ */
+var resources = map[string]struct {
+	path        string
+	newResponse func() NSResponse
+}{
+	"lbvserver":   {"/lbvserver/", func() NSResponse { return new(LBVResponse) }},
+	"service":     {"/service/", func() NSResponse { return new(ServiceResponse) }},
+	"system":      {"/system/", func() NSResponse { return new(SystemResponse) }},
+	"ns":          {"/ns/", func() NSResponse { return new(NSStatsResponse) }},
+	"ssl":         {"/ssl/", func() NSResponse { return new(SSLResponse) }},
+	"csvserver":   {"/csvserver/", func() NSResponse { return new(CSVServerResponse) }},
+	"gslbvserver": {"/gslbvserver/", func() NSResponse { return new(GSLBVServerResponse) }},
+	"interface":   {"/Interface/", func() NSResponse { return new(InterfaceResponse) }},
+}
 
 // Main struct used by Telegraf for this plugin.
 type Netscaler struct {
@@ -57,6 +93,25 @@ type Netscaler struct {
 	Password string
 	Https    bool
 	ApiPath  string
+
+	Resources        []string
+	UseSession       bool
+	SessionTimeout   internal.Duration
+	ResponseTimeout  internal.Duration
+	ResourceTimeouts map[string]internal.Duration
+
+	// Path to CA file
+	SSLCA string `toml:"ssl_ca"`
+	// Path to host cert file
+	SSLCert string `toml:"ssl_cert"`
+	// Path to cert key file
+	SSLKey string `toml:"ssl_key"`
+	// Use SSL but skip chain & host verification
+	InsecureSkipVerify bool
+
+	client *http.Client
+	// Id of the current session, empty when not logged in.
+	session string
 }
 
 func (n *Netscaler) Description() string {
@@ -67,67 +122,212 @@ func (n *Netscaler) SampleConfig() string {
 	return sampleConfig
 }
 
+func (n *Netscaler) createHttpClient() (*http.Client, error) {
+	tlsCfg, err := internal.GetTLSConfig(
+		n.SSLCert, n.SSLKey, n.SSLCA, n.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsCfg},
+	}, nil
+}
+
+func (n *Netscaler) baseUrl() string {
+	if n.Https {
+		return "https://" + n.Host
+	}
+	return "http://" + n.Host
+}
+
+/*
+ * Returns the timeout of the requests of a resource.
+ */
+func (n *Netscaler) timeout(resource string) time.Duration {
+	if d, ok := n.ResourceTimeouts[resource]; ok && d.Duration > 0 {
+		return d.Duration
+	}
+	return n.ResponseTimeout.Duration
+}
+
+/*
+ * Sends a request to Netscaler within the given timeout, and returns the
+ * status and the body of the response.
+ */
+func (n *Netscaler) do(req *http.Request, timeout time.Duration) (int, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	resp, err := n.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, body, err
+}
+
+/*
+ * Logs in to Nitro and keeps the id of the new session.
+ */
+func (n *Netscaler) login() error {
+	login := map[string]interface{}{
+		"login": map[string]interface{}{
+			"username": n.Username,
+			"password": n.Password,
+			"timeout":  int64(n.SessionTimeout.Duration.Seconds()),
+		},
+	}
+	payload, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+
+	loginPath := strings.TrimSuffix(n.ApiPath, "/stat") + "/config/login"
+	req, err := http.NewRequest("POST", n.baseUrl()+loginPath,
+		bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	status, body, err := n.do(req, n.ResponseTimeout.Duration)
+	if err != nil {
+		return fmt.Errorf("could not log in: %s", err)
+	}
+	var resp struct {
+		BaseJsonResponse
+		SessionId string `json:"sessionid"`
+	}
+	if err := json.Unmarshal(body, &resp); err == nil {
+		if err := resp.check(); err != nil {
+			return fmt.Errorf("could not log in: %s", err)
+		}
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return fmt.Errorf("could not log in: HTTP status %d", status)
+	}
+	if resp.SessionId == "" {
+		return fmt.Errorf("could not log in: no session id")
+	}
+	n.session = resp.SessionId
+	return nil
+}
+
 /*
  * Connects to a Netscaler and fetches the data.
  * It receives the path to the resource, constructs the URL and
- * does the connection.
+ * does the connection. An expired session is renewed once.
  */
-func (n *Netscaler) fetchResource(url_path string) ([]byte, error) {
-	var http_prefix string
-	var body []byte
-	var err error = nil
+func (n *Netscaler) fetchResource(resource, url_path string) ([]byte, error) {
+	status, body, err := n.get(resource, url_path)
+	if err == nil && status == http.StatusUnauthorized && n.UseSession {
+		n.session = ""
+		status, body, err = n.get(resource, url_path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		var resp BaseJsonResponse
+		if json.Unmarshal(body, &resp) == nil && resp.check() != nil {
+			return nil, fmt.Errorf("HTTP status %d, %s", status, resp.check())
+		}
+		return nil, fmt.Errorf("HTTP status %d", status)
+	}
+	return body, nil
+}
 
-	if n.Https {
-		http_prefix = "https://"
-	} else {
-		http_prefix = "http://"
+func (n *Netscaler) get(resource, url_path string) (int, []byte, error) {
+	if n.UseSession && n.session == "" {
+		if err := n.login(); err != nil {
+			return 0, nil, err
+		}
 	}
-	url := http_prefix + n.Host + n.ApiPath + url_path
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest("GET", n.baseUrl()+n.ApiPath+url_path, nil)
 	if err != nil {
-		return body, err
+		return 0, nil, err
 	}
 	// Headers required by Netscaler's API interface
-	req.Header.Add("X-NITRO-USER", n.Username)
-	req.Header.Add("X-NITRO-PASS", n.Password)
+	if n.UseSession {
+		req.Header.Add("Cookie", "NITRO_AUTH_TOKEN="+n.session)
+	} else {
+		req.Header.Add("X-NITRO-USER", n.Username)
+		req.Header.Add("X-NITRO-PASS", n.Password)
+	}
 	req.Header.Add("Content-type", "application/x-www-form-urlencoded")
 
-	resp, err := httpClient.Do(req)
+	return n.do(req, n.timeout(resource))
+}
+
+/*
+ * Fetches a resource and parses it into the given response.
+ */
+func (n *Netscaler) fetchResponse(resource, url_path string, resp NSResponse) error {
+	body, err := n.fetchResource(resource, url_path)
 	if err != nil {
-		log.Fatal("Could not connect to the host:", err)
-		return body, err
+		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return body, err
+	if err := json.Unmarshal(body, resp); err != nil {
+		return fmt.Errorf("could not parse json: %s", err)
 	}
+	return resp.check()
+}
 
-	body, err = ioutil.ReadAll(resp.Body)
-	return body, err
+/*
+ * Gathers the members of every service group. Nitro only lists the members
+ * of a group when it is requested on its own.
+ */
+func (n *Netscaler) gatherServiceGroups(acc telegraf.Accumulator) error {
+	groups := new(ServiceGroupResponse)
+	if err := n.fetchResponse("servicegroup", "/servicegroup/", groups); err != nil {
+		return err
+	}
+	for _, group := range groups.ServiceGroups {
+		members := new(ServiceGroupResponse)
+		url_path := "/servicegroup/" + url.PathEscape(group.Name) +
+			"?statbindings=yes"
+		if err := n.fetchResponse("servicegroup", url_path, members); err != nil {
+			acc.AddError(fmt.Errorf("netscaler: service group %s: %s",
+				group.Name, err))
+			continue
+		}
+		members.publishMetrics(acc)
+	}
+	return nil
 }
 
 // Dispatches metrics to Telegraf
 func (n *Netscaler) Gather(acc telegraf.Accumulator) error {
-	resources := make(map[string]NSResponse)
-	resources["/lbvserver/"] = new(LBVResponse)
-
-	for resource, resStruct := range resources {
-		// Connect to Netscaler and gather the result from the resource
-		body_raw, err := n.fetchResource(resource)
+	if n.client == nil {
+		client, err := n.createHttpClient()
 		if err != nil {
-			log.Fatalf("Coud not get results from resource %s: %v",
-				resource, err)
 			return err
 		}
-		if err := json.Unmarshal(body_raw, resStruct); err != nil {
-			log.Fatalf("Could not parse json from resource %s: %v",
-				resource, err)
-			return err
+		n.client = client
+	}
+
+	for _, name := range n.Resources {
+		if name == "servicegroup" {
+			acc.AddError(n.gatherServiceGroups(acc))
+			continue
+		}
+		resource, ok := resources[name]
+		if !ok {
+			acc.AddError(fmt.Errorf("netscaler: unknown resource %q", name))
+			continue
+		}
+		// Connect to Netscaler and gather the result from the resource
+		resp := resource.newResponse()
+		if err := n.fetchResponse(name, resource.path, resp); err != nil {
+			acc.AddError(fmt.Errorf("netscaler: resource %s: %s", name, err))
+			continue
 		}
 		// Call the implementation of PublishMetrics in the interface.
 		// Here is where metrics are actually dispatched.
-		resStruct.publishMetrics(acc)
+		resp.publishMetrics(acc)
 	}
 	return nil
 }
@@ -148,8 +348,12 @@ func getTags(v reflect.Value) map[string]string {
 		if ctag, ok := v.Type().Field(i).Tag.Lookup("typeofmetric"); ok {
 			if ctag == tag {
 				fieldName := v.Type().Field(i).Name
-				fieldValue := reflect.Indirect(v).Field(i).Interface().(string)
-				tags[fieldName] = fieldValue
+				switch fieldValue := reflect.Indirect(v).Field(i).Interface().(type) {
+				case string:
+					tags[fieldName] = fieldValue
+				case number:
+					tags[fieldName] = strconv.FormatFloat(float64(fieldValue), 'f', -1, 64)
+				}
 			}
 		}
 	}
@@ -173,6 +377,9 @@ func getMetrics(v reflect.Value, metricTagType string) map[string]interface{} {
 			if ctag == metricTagType {
 				fieldName := v.Type().Field(i).Name
 				fieldValue := reflect.Indirect(v).Field(i).Interface()
+				if n, ok := fieldValue.(number); ok {
+					fieldValue = float64(n)
+				}
 				m[fieldName] = fieldValue
 			}
 		}
@@ -184,11 +391,16 @@ func getMetrics(v reflect.Value, metricTagType string) map[string]interface{} {
 func init() {
 	inputs.Add("netscaler", func() telegraf.Input {
 		return &Netscaler{
-			Host:     "127.0.0.1",
-			Username: "admin",
-			Password: "admin",
-			Https:    false,
-			ApiPath:  "/nitro/v1/stat",
+			Host:               "127.0.0.1",
+			Username:           "admin",
+			Password:           "admin",
+			Https:              false,
+			ApiPath:            "/nitro/v1/stat",
+			Resources:          []string{"lbvserver"},
+			UseSession:         true,
+			SessionTimeout:     internal.Duration{Duration: 15 * time.Minute},
+			ResponseTimeout:    internal.Duration{Duration: 5 * time.Second},
+			InsecureSkipVerify: true,
 		}
 	})
 }
@@ -2,10 +2,14 @@ package netscaler
 
 import (
 	"encoding/json"
-	"github.com/influxdata/telegraf/testutil"
 	"io/ioutil"
-	"log"
 	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLBVServerPublishMetrics(t *testing.T) {
@@ -13,13 +17,20 @@ func TestLBVServerPublishMetrics(t *testing.T) {
 	acc := new(testutil.Accumulator)
 
 	json_source, err := ioutil.ReadFile("netscaler_lbvserver_response.json")
-	if err != nil {
-		log.Fatal("Could not find the mock json response file: %s", err.Error())
-	}
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(json_source, &nsreseponse))
 
-	if err := json.Unmarshal(json_source, &nsreseponse); err != nil {
-		log.Fatal("Could not parse json from resource: %s", err.Error())
-	}
+	nsreseponse.publishMetrics(acc)
+	assert.Len(t, acc.Metrics, 2*len(nsreseponse.LBVservers))
 
-	nsreseponse.PublishMetrics(acc)
+	tags := map[string]string{"Name": "opstest-fe.example.com", "State": "UP"}
+	for _, m := range acc.Metrics[2:4] {
+		assert.Equal(t, "netscaler.lbvserver", m.Measurement)
+		assert.Equal(t, tags, m.Tags)
+	}
+	assert.Equal(t, telegraf.Counter, acc.Metrics[2].Type)
+	assert.Equal(t, float64(0), acc.Metrics[2].Fields["TotalRequests"])
+	assert.Equal(t, telegraf.Gauge, acc.Metrics[3].Type)
+	assert.Equal(t, float64(100), acc.Metrics[3].Fields["VSLBHealth"])
+	assert.Equal(t, float64(1), acc.Metrics[3].Fields["ActSvcs"])
 }
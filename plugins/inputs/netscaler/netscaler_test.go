@@ -0,0 +1,295 @@
+package netscaler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const serviceGroupsResponse = `
+{
+  "errorcode": 0, "message": "Done", "severity": "NONE",
+  "servicegroup": [
+    {"servicegroupname": "web", "state": "ENABLED", "servicetype": "HTTP"}
+  ]
+}`
+
+const serviceGroupResponse = `
+{
+  "errorcode": 0, "message": "Done", "severity": "NONE",
+  "servicegroup": [
+    {
+      "servicegroupname": "web", "state": "ENABLED", "servicetype": "HTTP",
+      "servicegroupmember": [
+        {
+          "servicegroupname": "web?10.0.0.1?80",
+          "primaryipaddress": "10.0.0.1", "primaryport": 80,
+          "servicetype": "HTTP", "state": "UP",
+          "avgsvrttfb": "3", "totalrequests": "120", "requestsrate": 0,
+          "totalresponses": "118", "totalrequestbytes": "4096",
+          "totalresponsebytes": "65536", "curclntconnections": "2",
+          "cursrvrconnections": "1", "surgecount": "0",
+          "svrestablishedconn": "1", "curreusepool": "4", "maxclients": "0"
+        }
+      ]
+    }
+  ]
+}`
+
+const systemResponse = `
+{
+  "errorcode": 0, "message": "Done", "severity": "NONE",
+  "system": {
+    "numcpus": "2", "cpuusagepcnt": 1.5, "mgmtcpuusagepcnt": 0.7,
+    "pktcpuusagepcnt": 1.2, "rescpuusagepcnt": 2.8, "memusagepcnt": 15.25,
+    "memuseinmb": "312", "disk0perusage": 12, "disk1perusage": 40,
+    "disk0avail": 1402, "disk1avail": 8930
+  }
+}`
+
+const sslResponse = `
+{
+  "errorcode": 0, "message": "Done", "severity": "NONE",
+  "ssl": {
+    "ssltottransactions": "1500", "ssltotsslv3transactions": "0",
+    "ssltottlsv1transactions": "10", "ssltottlsv11transactions": "0",
+    "ssltottlsv12transactions": "1490", "ssltotsessions": "700",
+    "ssltotnewsessions": "400", "ssltotsessionhits": "300",
+    "ssltotsessionmiss": "12", "ssltotdecbytes": "2048",
+    "ssltotencbytes": "8192", "sslcursessions": "25", "sslnumcardsup": 0
+  }
+}`
+
+const interfaceResponse = `
+{
+  "errorcode": 0, "message": "Done", "severity": "NONE",
+  "Interface": [
+    {
+      "id": "0/1", "curintfstate": "UP", "curlinkuptime": 86400,
+      "totrxbytes": "1000", "tottxbytes": "2000", "totrxpkts": "10",
+      "tottxpkts": "20", "errpktrx": "1"
+    }
+  ]
+}`
+
+// nitro is a fake Nitro API serving stat resources to the clients of a
+// session.
+type nitro struct {
+	sync.Mutex
+	responses map[string]string
+	logins    int
+	sessions  map[string]bool
+	requests  []*http.Request
+}
+
+func (n *nitro) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.Lock()
+	defer n.Unlock()
+	n.requests = append(n.requests, r)
+	if r.URL.Path == "/nitro/v1/config/login" {
+		var body struct {
+			Login struct {
+				Username string `json:"username"`
+				Password string `json:"password"`
+				Timeout  int    `json:"timeout"`
+			} `json:"login"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Login.Username != "user" || body.Login.Password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"errorcode": 354, "message": "Invalid username or password", "severity": "ERROR"}`)
+			return
+		}
+		n.logins++
+		session := fmt.Sprintf("##session%d", n.logins)
+		n.sessions[session] = true
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"errorcode": 0, "message": "Done", "severity": "NONE", "sessionid": "%s"}`, session)
+		return
+	}
+
+	cookie, err := r.Cookie("NITRO_AUTH_TOKEN")
+	authorized := err == nil && n.sessions[cookie.Value]
+	if r.Header.Get("X-NITRO-USER") == "user" && r.Header.Get("X-NITRO-PASS") == "pass" {
+		authorized = true
+	}
+	if !authorized {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"errorcode": 444, "message": "Session expired or killed. Please login again", "severity": "ERROR"}`)
+		return
+	}
+
+	resp, ok := n.responses[strings.TrimPrefix(r.URL.RequestURI(), "/nitro/v1/stat")]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"errorcode": 258, "message": "No such resource", "severity": "ERROR"}`)
+		return
+	}
+	fmt.Fprint(w, resp)
+}
+
+func newNitro() *nitro {
+	return &nitro{
+		responses: map[string]string{
+			"/servicegroup/":                     serviceGroupsResponse,
+			"/servicegroup/web?statbindings=yes": serviceGroupResponse,
+			"/system/":                           systemResponse,
+			"/ssl/":                              sslResponse,
+			"/Interface/":                        interfaceResponse,
+		},
+		sessions: make(map[string]bool),
+	}
+}
+
+func newNetscaler(ts *httptest.Server) *Netscaler {
+	return &Netscaler{
+		Host:            strings.TrimPrefix(ts.URL, "http://"),
+		Username:        "user",
+		Password:        "pass",
+		ApiPath:         "/nitro/v1/stat",
+		UseSession:      true,
+		SessionTimeout:  internal.Duration{Duration: 15 * time.Minute},
+		ResponseTimeout: internal.Duration{Duration: 5 * time.Second},
+	}
+}
+
+func TestGatherResources(t *testing.T) {
+	api := newNitro()
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	n := newNetscaler(ts)
+	n.Resources = []string{"servicegroup", "system", "ssl", "interface"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(n.Gather))
+
+	acc.AssertContainsTaggedFields(t, "netscaler.servicegroupmember",
+		map[string]interface{}{
+			"TotalRequests":      float64(120),
+			"TotalResponses":     float64(118),
+			"TotalRequestBytes":  float64(4096),
+			"TotalResponseBytes": float64(65536),
+		},
+		map[string]string{
+			"ServiceGroupName": "web",
+			"IP":               "10.0.0.1",
+			"Port":             "80",
+			"ServiceType":      "HTTP",
+			"State":            "UP",
+		})
+	acc.AssertContainsFields(t, "netscaler.system",
+		map[string]interface{}{
+			"NumCPUs":         float64(2),
+			"CPUUsagePct":     1.5,
+			"MgmtCPUUsagePct": 0.7,
+			"PktCPUUsagePct":  1.2,
+			"ResCPUUsagePct":  2.8,
+			"MemUsagePct":     15.25,
+			"MemUseInMB":      float64(312),
+			"Disk0PerUsage":   float64(12),
+			"Disk1PerUsage":   float64(40),
+			"Disk0Avail":      float64(1402),
+			"Disk1Avail":      float64(8930),
+		})
+	assert.True(t, acc.HasMeasurement("netscaler.ssl"))
+	assert.True(t, acc.HasTag("netscaler.interface", "ID"))
+	assert.Equal(t, "UP", acc.TagValue("netscaler.interface", "State"))
+
+	// the session is reused across the requests
+	assert.Equal(t, 1, api.logins)
+	assert.Len(t, api.requests, 6)
+}
+
+func TestGatherSessionExpired(t *testing.T) {
+	api := newNitro()
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	n := newNetscaler(ts)
+	n.Resources = []string{"system"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(n.Gather))
+	api.sessions = make(map[string]bool)
+	require.NoError(t, acc.GatherError(n.Gather))
+
+	assert.Equal(t, 2, api.logins)
+	assert.Len(t, acc.Metrics, 2)
+}
+
+func TestGatherWithoutSession(t *testing.T) {
+	api := newNitro()
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	n := newNetscaler(ts)
+	n.UseSession = false
+	n.Resources = []string{"system"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, acc.GatherError(n.Gather))
+	assert.Equal(t, 0, api.logins)
+	assert.True(t, acc.HasMeasurement("netscaler.system"))
+}
+
+func TestGatherErrors(t *testing.T) {
+	api := newNitro()
+	ts := httptest.NewServer(api)
+	defer ts.Close()
+
+	n := newNetscaler(ts)
+	n.Resources = []string{"lbvserver", "unknown", "system"}
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Errors, 2)
+	assert.Contains(t, acc.Errors[0].Error(), "No such resource")
+	assert.Contains(t, acc.Errors[1].Error(), `unknown resource "unknown"`)
+	// the other resources are still gathered
+	assert.True(t, acc.HasMeasurement("netscaler.system"))
+
+	n = newNetscaler(ts)
+	n.Password = "wrong"
+	n.Resources = []string{"system"}
+	acc = testutil.Accumulator{}
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "Invalid username or password")
+}
+
+func TestGatherResourceTimeout(t *testing.T) {
+	api := newNitro()
+	ts := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/ssl/") {
+				time.Sleep(200 * time.Millisecond)
+			}
+			api.ServeHTTP(w, r)
+		}))
+	defer ts.Close()
+
+	n := newNetscaler(ts)
+	n.Resources = []string{"ssl", "system"}
+	n.ResourceTimeouts = map[string]internal.Duration{
+		"ssl": {Duration: 50 * time.Millisecond},
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, n.Gather(&acc))
+	require.Len(t, acc.Errors, 1)
+	assert.Contains(t, acc.Errors[0].Error(), "resource ssl")
+	assert.False(t, acc.HasMeasurement("netscaler.ssl"))
+	assert.True(t, acc.HasMeasurement("netscaler.system"))
+}
@@ -1,14 +1,17 @@
 package netscaler
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/telegraf"
 )
 
 /*
- * This response is included on every reply from Netscaler's stat API.
+ * This response is included on every reply from Netscaler's API.
  */
 type BaseJsonResponse struct {
 	ErrorCode float64 `json:"errorcode"`
@@ -16,36 +19,87 @@ type BaseJsonResponse struct {
 	Severity  string  `json:"severity"`
 }
 
+/*
+ * Returns the error reported by Netscaler, if any.
+ */
+func (b *BaseJsonResponse) check() error {
+	if b.ErrorCode != 0 {
+		return fmt.Errorf("error %v: %s", b.ErrorCode, b.Message)
+	}
+	return nil
+}
+
+/*
+ * A numeric value of a response. Nitro sends most of them as strings, and
+ * some of them as numbers.
+ */
+type number float64
+
+func (n *number) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", b)
+	}
+	*n = number(f)
+	return nil
+}
+
+/*
+ * Publishes the counters and the gauges of a struct as two metrics.
+ */
+func publish(acc telegraf.Accumulator, measurement string, v reflect.Value,
+	extraTags map[string]string) {
+	counters := getMetrics(v, "counter")
+	gauges := getMetrics(v, "gauge")
+	tags := getTags(v)
+	for k, t := range extraTags {
+		tags[k] = t
+	}
+
+	now := time.Now()
+	if len(counters) > 0 {
+		acc.AddCounter(measurement, counters, tags, now)
+	}
+	if len(gauges) > 0 {
+		acc.AddGauge(measurement, gauges, tags, now)
+	}
+}
+
 /*
  * Strcuture of each lbvserver response from '/lbvserver/' endpoint.
  * Fields are marked with `typeofmetric` to identify the type of value
  * it holds.
  */
 type LBVServer struct {
-	Name                         string  `json:"name" typeofmetric:"tag"`
-	VSVRSurgeCount               float64 `json:"vsvrsurgecount,string" typeofmetric:"counter"`
-	EstablishedConn              float64 `json:"establishedconn,string" typeofmetric:"gauge"`
-	InactSvcs                    float64 `json:"inactsvcs,string" typeofmetric:"gauge"`
-	VSLBHealth                   float64 `json:"vslbhealth,string" typeofmetric:"gauge"`
-	State                        string  `json:"state" typeofmetric:"tag"`
-	ActSvcs                      float64 `json:"actsvcs,string" typeofmetric:"gauge"`
-	TottalHits                   float64 `json:"tothits,string" typeofmetric:"counter"`
-	TotalRequests                float64 `json:"totalrequests,string" typeofmetric:"counter"`
-	TotalResponses               float64 `json:"totalresponses,string" typeofmetric:"counter"`
-	TotalRequestBytes            float64 `json:"totalrequestbytes,string" typeofmetric:"counter"`
-	TotalResponseBytes           float64 `json:"totalresponsebytes,string" typeofmetric:"counter"`
-	TotalPktsRecvd               float64 `json:"totalpktsrecvd,string" typeofmetric:"counter"`
-	TotalPktsSent                float64 `json:"totalpktssent,string" typeofmetric:"counter"`
-	CurrentClientConnections     float64 `json:"curclntconnections,string" typeofmetric:"gauge"`
-	CurrentServerConnections     float64 `json:"cursrvrconnections,string" typeofmetric:"gauge"`
-	SurgeCount                   float64 `json:"surgecount,string" typeofmetric:"counter"`
-	SvcSurgeCount                float64 `json:"svcsurgecount,string" typeofmetric:"counter"`
-	SpillOverThreshold           float64 `json:"sothreshold,string" typeofmetric:"gauge"`
-	TotalSpillovers              float64 `json:"totspillovers,string" typeofmetric:"counter"`
-	DeferredReqests              float64 `json:"deferredreq,string" typeofmetric:"counter"`
-	InvalidRequestResponse       float64 `json:"invalidrequestresponse,string" typeofmetric:"counter"`
-	InvalidRequestReponseDropped float64 `json:"invalidrequestresponsedropped,string" typeofmetric:"counter"`
-	TotalVServerDownBackupHits   float64 `json:"totvserverdownbackuphits,string" typeofmetric:"counter"`
+	Name                         string `json:"name" typeofmetric:"tag"`
+	VSVRSurgeCount               number `json:"vsvrsurgecount" typeofmetric:"counter"`
+	EstablishedConn              number `json:"establishedconn" typeofmetric:"gauge"`
+	InactSvcs                    number `json:"inactsvcs" typeofmetric:"gauge"`
+	VSLBHealth                   number `json:"vslbhealth" typeofmetric:"gauge"`
+	State                        string `json:"state" typeofmetric:"tag"`
+	ActSvcs                      number `json:"actsvcs" typeofmetric:"gauge"`
+	TottalHits                   number `json:"tothits" typeofmetric:"counter"`
+	TotalRequests                number `json:"totalrequests" typeofmetric:"counter"`
+	TotalResponses               number `json:"totalresponses" typeofmetric:"counter"`
+	TotalRequestBytes            number `json:"totalrequestbytes" typeofmetric:"counter"`
+	TotalResponseBytes           number `json:"totalresponsebytes" typeofmetric:"counter"`
+	TotalPktsRecvd               number `json:"totalpktsrecvd" typeofmetric:"counter"`
+	TotalPktsSent                number `json:"totalpktssent" typeofmetric:"counter"`
+	CurrentClientConnections     number `json:"curclntconnections" typeofmetric:"gauge"`
+	CurrentServerConnections     number `json:"cursrvrconnections" typeofmetric:"gauge"`
+	SurgeCount                   number `json:"surgecount" typeofmetric:"counter"`
+	SvcSurgeCount                number `json:"svcsurgecount" typeofmetric:"counter"`
+	SpillOverThreshold           number `json:"sothreshold" typeofmetric:"gauge"`
+	TotalSpillovers              number `json:"totspillovers" typeofmetric:"counter"`
+	DeferredReqests              number `json:"deferredreq" typeofmetric:"counter"`
+	InvalidRequestResponse       number `json:"invalidrequestresponse" typeofmetric:"counter"`
+	InvalidRequestReponseDropped number `json:"invalidrequestresponsedropped" typeofmetric:"counter"`
+	TotalVServerDownBackupHits   number `json:"totvserverdownbackuphits" typeofmetric:"counter"`
 }
 
 /*
@@ -62,12 +116,281 @@ type LBVResponse struct {
  */
 func (l *LBVResponse) publishMetrics(acc telegraf.Accumulator) {
 	for _, lbvh := range l.LBVservers {
-		v := reflect.ValueOf(lbvh)
-		counters := getMetrics(v, "counter")
-		gauges := getMetrics(v, "gauge")
-		tags := getTags(v)
+		publish(acc, "netscaler.lbvserver", reflect.ValueOf(lbvh), nil)
+	}
+}
+
+/*
+ * Structure of each member of a service group, from the
+ * '/servicegroup/<name>?statbindings=yes' endpoint.
+ */
+type ServiceGroupMember struct {
+	IP                       string `json:"primaryipaddress" typeofmetric:"tag"`
+	Port                     number `json:"primaryport" typeofmetric:"tag"`
+	ServiceType              string `json:"servicetype" typeofmetric:"tag"`
+	State                    string `json:"state" typeofmetric:"tag"`
+	AvgSvrTTFB               number `json:"avgsvrttfb" typeofmetric:"gauge"`
+	TotalRequests            number `json:"totalrequests" typeofmetric:"counter"`
+	TotalResponses           number `json:"totalresponses" typeofmetric:"counter"`
+	TotalRequestBytes        number `json:"totalrequestbytes" typeofmetric:"counter"`
+	TotalResponseBytes       number `json:"totalresponsebytes" typeofmetric:"counter"`
+	CurrentClientConnections number `json:"curclntconnections" typeofmetric:"gauge"`
+	CurrentServerConnections number `json:"cursrvrconnections" typeofmetric:"gauge"`
+	SurgeCount               number `json:"surgecount" typeofmetric:"gauge"`
+	ServerEstablishedConn    number `json:"svrestablishedconn" typeofmetric:"gauge"`
+	CurrentReusePool         number `json:"curreusepool" typeofmetric:"gauge"`
+	MaxClients               number `json:"maxclients" typeofmetric:"gauge"`
+}
+
+type ServiceGroup struct {
+	Name    string               `json:"servicegroupname"`
+	Members []ServiceGroupMember `json:"servicegroupmember"`
+}
+
+/*
+ * Response from the '/servicegroup/' endpoint. The members of the groups
+ * are only listed when a single group is requested with its stat bindings.
+ */
+type ServiceGroupResponse struct {
+	BaseJsonResponse
+	ServiceGroups []ServiceGroup `json:"servicegroup"`
+}
+
+/*
+ * Publishes the metrics of every member of the service groups, tagged with
+ * the name of their group.
+ */
+func (s *ServiceGroupResponse) publishMetrics(acc telegraf.Accumulator) {
+	for _, group := range s.ServiceGroups {
+		for _, member := range group.Members {
+			publish(acc, "netscaler.servicegroupmember", reflect.ValueOf(member),
+				map[string]string{"ServiceGroupName": group.Name})
+		}
+	}
+}
+
+/*
+ * Structure of each service from the '/service/' endpoint.
+ */
+type Service struct {
+	Name                     string `json:"name" typeofmetric:"tag"`
+	IP                       string `json:"primaryipaddress" typeofmetric:"tag"`
+	Port                     number `json:"primaryport" typeofmetric:"tag"`
+	ServiceType              string `json:"servicetype" typeofmetric:"tag"`
+	State                    string `json:"state" typeofmetric:"tag"`
+	Throughput               number `json:"throughput" typeofmetric:"gauge"`
+	AvgSvrTTFB               number `json:"avgsvrttfb" typeofmetric:"gauge"`
+	TotalRequests            number `json:"totalrequests" typeofmetric:"counter"`
+	TotalResponses           number `json:"totalresponses" typeofmetric:"counter"`
+	TotalRequestBytes        number `json:"totalrequestbytes" typeofmetric:"counter"`
+	TotalResponseBytes       number `json:"totalresponsebytes" typeofmetric:"counter"`
+	CurrentClientConnections number `json:"curclntconnections" typeofmetric:"gauge"`
+	CurrentServerConnections number `json:"cursrvrconnections" typeofmetric:"gauge"`
+	SurgeCount               number `json:"surgecount" typeofmetric:"gauge"`
+	ServerEstablishedConn    number `json:"svrestablishedconn" typeofmetric:"gauge"`
+	CurrentReusePool         number `json:"curreusepool" typeofmetric:"gauge"`
+	MaxClients               number `json:"maxclients" typeofmetric:"gauge"`
+	CurrentLoad              number `json:"curload" typeofmetric:"gauge"`
+	VServerServiceHits       number `json:"vsvrservicehits" typeofmetric:"counter"`
+	ActiveTransactions       number `json:"activetransactions" typeofmetric:"gauge"`
+}
+
+/*
+ * Response from the '/service/' endpoint.
+ */
+type ServiceResponse struct {
+	BaseJsonResponse
+	Services []Service `json:"service"`
+}
+
+func (s *ServiceResponse) publishMetrics(acc telegraf.Accumulator) {
+	for _, service := range s.Services {
+		publish(acc, "netscaler.service", reflect.ValueOf(service), nil)
+	}
+}
+
+/*
+ * Structure of the response from the '/system/' endpoint.
+ */
+type System struct {
+	NumCPUs         number `json:"numcpus" typeofmetric:"gauge"`
+	CPUUsagePct     number `json:"cpuusagepcnt" typeofmetric:"gauge"`
+	MgmtCPUUsagePct number `json:"mgmtcpuusagepcnt" typeofmetric:"gauge"`
+	PktCPUUsagePct  number `json:"pktcpuusagepcnt" typeofmetric:"gauge"`
+	ResCPUUsagePct  number `json:"rescpuusagepcnt" typeofmetric:"gauge"`
+	MemUsagePct     number `json:"memusagepcnt" typeofmetric:"gauge"`
+	MemUseInMB      number `json:"memuseinmb" typeofmetric:"gauge"`
+	Disk0PerUsage   number `json:"disk0perusage" typeofmetric:"gauge"`
+	Disk1PerUsage   number `json:"disk1perusage" typeofmetric:"gauge"`
+	Disk0Avail      number `json:"disk0avail" typeofmetric:"gauge"`
+	Disk1Avail      number `json:"disk1avail" typeofmetric:"gauge"`
+}
+
+type SystemResponse struct {
+	BaseJsonResponse
+	System System `json:"system"`
+}
+
+func (s *SystemResponse) publishMetrics(acc telegraf.Accumulator) {
+	publish(acc, "netscaler.system", reflect.ValueOf(s.System), nil)
+}
+
+/*
+ * Structure of the response from the '/ns/' endpoint.
+ */
+type NSStats struct {
+	CPUUsagePct                     number `json:"cpuusagepcnt" typeofmetric:"gauge"`
+	MgmtCPUUsagePct                 number `json:"mgmtcpuusagepcnt" typeofmetric:"gauge"`
+	PktCPUUsagePct                  number `json:"pktcpuusagepcnt" typeofmetric:"gauge"`
+	ResCPUUsagePct                  number `json:"rescpuusagepcnt" typeofmetric:"gauge"`
+	MemUsagePct                     number `json:"memusagepcnt" typeofmetric:"gauge"`
+	TotalRxMbits                    number `json:"totrxmbits" typeofmetric:"counter"`
+	TotalTxMbits                    number `json:"tottxmbits" typeofmetric:"counter"`
+	HTTPTotalRequests               number `json:"httptotrequests" typeofmetric:"counter"`
+	HTTPTotalResponses              number `json:"httptotresponses" typeofmetric:"counter"`
+	TCPCurrentClientConn            number `json:"tcpcurclientconn" typeofmetric:"gauge"`
+	TCPCurrentServerConn            number `json:"tcpcurserverconn" typeofmetric:"gauge"`
+	TCPCurrentClientConnEstablished number `json:"tcpcurclientconnestablished" typeofmetric:"gauge"`
+	TCPCurrentServerConnEstablished number `json:"tcpcurserverconnestablished" typeofmetric:"gauge"`
+}
+
+type NSStatsResponse struct {
+	BaseJsonResponse
+	NS NSStats `json:"ns"`
+}
+
+func (s *NSStatsResponse) publishMetrics(acc telegraf.Accumulator) {
+	publish(acc, "netscaler.ns", reflect.ValueOf(s.NS), nil)
+}
+
+/*
+ * Structure of the response from the '/ssl/' endpoint.
+ */
+type SSL struct {
+	TotalTransactions       number `json:"ssltottransactions" typeofmetric:"counter"`
+	TotalSSLv3Transactions  number `json:"ssltotsslv3transactions" typeofmetric:"counter"`
+	TotalTLSv1Transactions  number `json:"ssltottlsv1transactions" typeofmetric:"counter"`
+	TotalTLSv11Transactions number `json:"ssltottlsv11transactions" typeofmetric:"counter"`
+	TotalTLSv12Transactions number `json:"ssltottlsv12transactions" typeofmetric:"counter"`
+	TotalSessions           number `json:"ssltotsessions" typeofmetric:"counter"`
+	TotalNewSessions        number `json:"ssltotnewsessions" typeofmetric:"counter"`
+	TotalSessionHits        number `json:"ssltotsessionhits" typeofmetric:"counter"`
+	TotalSessionMiss        number `json:"ssltotsessionmiss" typeofmetric:"counter"`
+	TotalDecBytes           number `json:"ssltotdecbytes" typeofmetric:"counter"`
+	TotalEncBytes           number `json:"ssltotencbytes" typeofmetric:"counter"`
+	CurrentSessions         number `json:"sslcursessions" typeofmetric:"gauge"`
+	NumCardsUp              number `json:"sslnumcardsup" typeofmetric:"gauge"`
+}
+
+type SSLResponse struct {
+	BaseJsonResponse
+	SSL SSL `json:"ssl"`
+}
+
+func (s *SSLResponse) publishMetrics(acc telegraf.Accumulator) {
+	publish(acc, "netscaler.ssl", reflect.ValueOf(s.SSL), nil)
+}
+
+/*
+ * Structure of each content switching vserver from the '/csvserver/'
+ * endpoint.
+ */
+type CSVServer struct {
+	Name                          string `json:"name" typeofmetric:"tag"`
+	IP                            string `json:"primaryipaddress" typeofmetric:"tag"`
+	Port                          number `json:"primaryport" typeofmetric:"tag"`
+	Type                          string `json:"type" typeofmetric:"tag"`
+	State                         string `json:"state" typeofmetric:"tag"`
+	TotalHits                     number `json:"tothits" typeofmetric:"counter"`
+	TotalRequests                 number `json:"totalrequests" typeofmetric:"counter"`
+	TotalResponses                number `json:"totalresponses" typeofmetric:"counter"`
+	TotalRequestBytes             number `json:"totalrequestbytes" typeofmetric:"counter"`
+	TotalResponseBytes            number `json:"totalresponsebytes" typeofmetric:"counter"`
+	TotalPktsRecvd                number `json:"totalpktsrecvd" typeofmetric:"counter"`
+	TotalPktsSent                 number `json:"totalpktssent" typeofmetric:"counter"`
+	CurrentClientConnections      number `json:"curclntconnections" typeofmetric:"gauge"`
+	CurrentServerConnections      number `json:"cursrvrconnections" typeofmetric:"gauge"`
+	EstablishedConn               number `json:"establishedconn" typeofmetric:"gauge"`
+	TotalSpillovers               number `json:"totspillovers" typeofmetric:"counter"`
+	DeferredRequests              number `json:"deferredreq" typeofmetric:"counter"`
+	InvalidRequestResponse        number `json:"invalidrequestresponse" typeofmetric:"counter"`
+	InvalidRequestResponseDropped number `json:"invalidrequestresponsedropped" typeofmetric:"counter"`
+	TotalVServerDownBackupHits    number `json:"totvserverdownbackuphits" typeofmetric:"counter"`
+}
+
+type CSVServerResponse struct {
+	BaseJsonResponse
+	CSVServers []CSVServer `json:"csvserver"`
+}
+
+func (s *CSVServerResponse) publishMetrics(acc telegraf.Accumulator) {
+	for _, vserver := range s.CSVServers {
+		publish(acc, "netscaler.csvserver", reflect.ValueOf(vserver), nil)
+	}
+}
+
+/*
+ * Structure of each GSLB vserver from the '/gslbvserver/' endpoint.
+ */
+type GSLBVServer struct {
+	Name                     string `json:"name" typeofmetric:"tag"`
+	Type                     string `json:"type" typeofmetric:"tag"`
+	State                    string `json:"state" typeofmetric:"tag"`
+	TotalHits                number `json:"tothits" typeofmetric:"counter"`
+	TotalRequests            number `json:"totalrequests" typeofmetric:"counter"`
+	TotalResponses           number `json:"totalresponses" typeofmetric:"counter"`
+	TotalRequestBytes        number `json:"totalrequestbytes" typeofmetric:"counter"`
+	TotalResponseBytes       number `json:"totalresponsebytes" typeofmetric:"counter"`
+	CurrentClientConnections number `json:"curclntconnections" typeofmetric:"gauge"`
+	CurrentServerConnections number `json:"cursrvrconnections" typeofmetric:"gauge"`
+	EstablishedConn          number `json:"establishedconn" typeofmetric:"gauge"`
+	VSLBHealth               number `json:"vslbhealth" typeofmetric:"gauge"`
+	SpillOverThreshold       number `json:"sothreshold" typeofmetric:"gauge"`
+	TotalSpillovers          number `json:"totspillovers" typeofmetric:"counter"`
+}
+
+type GSLBVServerResponse struct {
+	BaseJsonResponse
+	GSLBVServers []GSLBVServer `json:"gslbvserver"`
+}
+
+func (s *GSLBVServerResponse) publishMetrics(acc telegraf.Accumulator) {
+	for _, vserver := range s.GSLBVServers {
+		publish(acc, "netscaler.gslbvserver", reflect.ValueOf(vserver), nil)
+	}
+}
+
+/*
+ * Structure of each network interface from the '/Interface/' endpoint.
+ */
+type Interface struct {
+	ID                   string `json:"id" typeofmetric:"tag"`
+	State                string `json:"curintfstate" typeofmetric:"tag"`
+	LinkUptime           number `json:"curlinkuptime" typeofmetric:"gauge"`
+	TotalRxBytes         number `json:"totrxbytes" typeofmetric:"counter"`
+	TotalTxBytes         number `json:"tottxbytes" typeofmetric:"counter"`
+	TotalRxPkts          number `json:"totrxpkts" typeofmetric:"counter"`
+	TotalTxPkts          number `json:"tottxpkts" typeofmetric:"counter"`
+	JumboPktsReceived    number `json:"jumbopktsreceived" typeofmetric:"counter"`
+	JumboPktsTransmitted number `json:"jumbopktstransmitted" typeofmetric:"counter"`
+	TrunkPktsReceived    number `json:"trunkpktsreceived" typeofmetric:"counter"`
+	TrunkPktsTransmitted number `json:"trunkpktstransmitted" typeofmetric:"counter"`
+	NetScalerPkts        number `json:"netscalerpkts" typeofmetric:"counter"`
+	MacMoved             number `json:"totmacmoved" typeofmetric:"counter"`
+	ErrDroppedRxPkts     number `json:"errdroppedrxpkts" typeofmetric:"counter"`
+	ErrDroppedTxPkts     number `json:"errdroppedtxpkts" typeofmetric:"counter"`
+	ErrLinkHangs         number `json:"errlinkhangs" typeofmetric:"counter"`
+	ErrPktRx             number `json:"errpktrx" typeofmetric:"counter"`
+	ErrPktTx             number `json:"errpkttx" typeofmetric:"counter"`
+}
+
+type InterfaceResponse struct {
+	BaseJsonResponse
+	Interfaces []Interface `json:"Interface"`
+}
 
-		acc.AddCounter("netscaler.lbvserver", counters, tags, time.Now())
-		acc.AddGauge("netscaler.lbvserver", gauges, tags, time.Now())
+func (s *InterfaceResponse) publishMetrics(acc telegraf.Accumulator) {
+	for _, intf := range s.Interfaces {
+		publish(acc, "netscaler.interface", reflect.ValueOf(intf), nil)
 	}
 }
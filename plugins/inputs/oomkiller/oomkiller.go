@@ -1,22 +1,54 @@
 package oomkiller
 
 import (
+	"bufio"
+	"fmt"
+	"io"
 	"log"
+	"os"
 	"regexp"
-	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/influxdata/tail"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
+const (
+	sourceLogfile = "logfile"
+	sourceKmsg    = "kmsg"
+)
+
+// kmsgPollInterval is the interval the ring buffer is polled at while it has
+// no new record.
+var kmsgPollInterval = 250 * time.Millisecond
+
 type Oomkiller struct {
-	Logfile string `toml:"logfile"`
+	Source        string `toml:"source"`
+	Logfile       string `toml:"logfile"`
+	Kmsg          string `toml:"kmsg"`
+	FromBeginning bool   `toml:"from_beginning"`
+
+	acc    telegraf.Accumulator
+	tailer *tail.Tail
+	kmsg   *os.File
+	done   chan struct{}
+	wg     sync.WaitGroup
 }
 
 var sampleConfig = `
+  ## Source of the kernel messages, either "logfile" for a syslog file, or
+  ## "kmsg" for the kernel ring buffer.
+  source = "logfile"
   ## Logfile where oom killer is reflected
   logfile = "/var/log/messages"
+  ## Kernel ring buffer device
+  kmsg = "/dev/kmsg"
+  ## Read the logfile or the ring buffer from the beginning, instead of
+  ## the kills following the start of telegraf only.
+  from_beginning = false
 `
 
 // SampleConfig returns a sample configuration block
@@ -24,47 +56,182 @@ func (a *Oomkiller) SampleConfig() string {
 	return sampleConfig
 }
 
-// Description just returns a short description of the Mesos plugin
+// Description just returns a short description of the Oomkiller plugin
 func (a *Oomkiller) Description() string {
-	return "Telegraf plugin for gathering metrics from kernel Oomkiller"
+	return "Report the kills of the kernel OOM killer"
 }
 
 func (a *Oomkiller) SetDefaults() {
+	if a.Source == "" {
+		a.Source = sourceLogfile
+	}
 	if a.Logfile == "" {
 		log.Println("I! [oomkiller] Missing logfile value, setting default value (/var/log/messages)")
 		a.Logfile = "/var/log/messages"
 	}
+	if a.Kmsg == "" {
+		a.Kmsg = "/dev/kmsg"
+	}
 }
 
+var oomkillerEventRe = regexp.MustCompile("invoked oom-killer")
+
 func IsOomkillerEvent(line string) bool {
-	matched, _ := regexp.MatchString("invoked oom-killer", line)
-	return matched
+	return oomkillerEventRe.MatchString(line)
 }
 
-// Gather() metrics from Oomkiller
+// Gather does nothing, the kills are reported as they are read
 func (a *Oomkiller) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func (a *Oomkiller) Start(acc telegraf.Accumulator) error {
 	a.SetDefaults()
-	//This is to NOT start tailing log from the beginning
-	var seek *tail.SeekInfo
-	seek = &tail.SeekInfo{Whence: 2, Offset: 0}
-	t, err := tail.TailFile(a.Logfile, tail.Config{Follow: true, Location: seek, ReOpen: true})
-	if err != nil {
-		return err
+	a.acc = acc
+	a.done = make(chan struct{})
+
+	switch a.Source {
+	case sourceLogfile:
+		var seek *tail.SeekInfo
+		if !a.FromBeginning {
+			seek = &tail.SeekInfo{Whence: 2, Offset: 0}
+		}
+		t, err := tail.TailFile(a.Logfile,
+			tail.Config{Follow: true, Location: seek, ReOpen: true})
+		if err != nil {
+			return err
+		}
+		a.tailer = t
+		a.wg.Add(1)
+		go a.readLogfile(t)
+	case sourceKmsg:
+		// closing the device does not interrupt a blocking read, so it is
+		// read without blocking, letting the reader return once stopped.
+		f, err := os.OpenFile(a.Kmsg, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			return err
+		}
+		if !a.FromBeginning {
+			if _, err := f.Seek(0, io.SeekEnd); err != nil {
+				f.Close()
+				return err
+			}
+		}
+		a.kmsg = f
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.readKmsg(f)
+		}()
+	default:
+		return fmt.Errorf("unknown oomkiller source %q", a.Source)
 	}
+	return nil
+}
+
+func (a *Oomkiller) Stop() {
+	close(a.done)
+	if a.tailer != nil {
+		a.tailer.Stop()
+		a.tailer.Cleanup()
+	}
+	if a.kmsg != nil {
+		a.kmsg.Close()
+	}
+	a.wg.Wait()
+}
+
+// readLogfile reports the kills of the lines of a syslog file.
+func (a *Oomkiller) readLogfile(t *tail.Tail) {
+	defer a.wg.Done()
+
+	var parser Parser
 	for line := range t.Lines {
-		if IsOomkillerEvent(line.Text) && line.Text != "" {
-			s := strings.Split(line.Text, " ")
-			invokedBy := s[6]
-			var metric string = "event"
-			var value int = 1
-			data := make(map[string]interface{})
-			data[metric] = value
-			tags := make(map[string]string)
-			tags["invokedby"] = invokedBy
-			acc.AddFields("oomkiller", data, tags)
+		if line.Err != nil {
+			a.acc.AddError(fmt.Errorf("error tailing file %s: %s",
+				t.Filename, line.Err))
+			continue
+		}
+		if msg, ok := syslogMessage(line.Text); ok {
+			a.report(parser.Parse(msg))
 		}
 	}
-	return nil
+}
+
+// readKmsg reports the kills of the records of the kernel ring buffer, until
+// the plugin is stopped or the reader is closed.
+func (a *Oomkiller) readKmsg(r io.Reader) {
+	var parser Parser
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if msg, ok := kmsgMessage(line); ok {
+			a.report(parser.Parse(msg))
+		}
+		if err == nil {
+			continue
+		}
+		// the records overwritten before being read are skipped
+		if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EPIPE {
+			continue
+		}
+		// no new record yet
+		if pe, ok := err.(*os.PathError); ok && pe.Err == syscall.EAGAIN {
+			select {
+			case <-a.done:
+				return
+			case <-time.After(kmsgPollInterval):
+			}
+			continue
+		}
+		select {
+		case <-a.done:
+		default:
+			if err != io.EOF {
+				a.acc.AddError(fmt.Errorf("error reading %s: %s", a.Kmsg, err))
+			}
+		}
+		return
+	}
+}
+
+// report adds the event of a kill.
+func (a *Oomkiller) report(r *Report) {
+	if r == nil {
+		return
+	}
+
+	tags := map[string]string{
+		"process": r.Process,
+	}
+	if r.InvokedBy != "" {
+		tags["invokedby"] = r.InvokedBy
+	}
+	if r.Constraint != "" {
+		tags["constraint"] = r.Constraint
+	}
+	if r.Cgroup != "" {
+		tags["cgroup"] = r.Cgroup
+	}
+	if id := r.ContainerID(); id != "" {
+		tags["container_id"] = id
+	}
+
+	fields := map[string]interface{}{
+		"event":     1,
+		"pid":       r.Pid,
+		"total_vm":  r.TotalVM,
+		"anon_rss":  r.AnonRSS,
+		"file_rss":  r.FileRSS,
+		"shmem_rss": r.ShmemRSS,
+	}
+	if r.hasOomScoreAdj {
+		fields["oom_score_adj"] = r.OomScoreAdj
+	}
+	if r.Score != 0 {
+		fields["score"] = r.Score
+	}
+	a.acc.AddFields("oomkiller", fields, tags)
 }
 
 func init() {
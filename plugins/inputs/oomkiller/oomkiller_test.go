@@ -1,7 +1,15 @@
 package oomkiller
 
 import (
+	"os"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestIsOomkillerEvent(t *testing.T) {
@@ -29,4 +37,96 @@ func TestIsOomkillerEvent(t *testing.T) {
 	}
 }
 
+func TestOomkillerKmsg(t *testing.T) {
+	f, err := os.Open("testdata/kmsg.txt")
+	require.NoError(t, err)
+	defer f.Close()
+
+	var acc testutil.Accumulator
+	o := &Oomkiller{Kmsg: "testdata/kmsg.txt", acc: &acc, done: make(chan struct{})}
+	o.readKmsg(f)
+
+	require.Empty(t, acc.Errors)
+	require.Len(t, acc.Metrics, 2)
+	acc.AssertContainsTaggedFields(t, "oomkiller",
+		map[string]interface{}{
+			"event":         1,
+			"pid":           int64(3112),
+			"total_vm":      int64(266036 * 1024),
+			"anon_rss":      int64(261200 * 1024),
+			"file_rss":      int64(1560 * 1024),
+			"shmem_rss":     int64(0),
+			"oom_score_adj": int64(500),
+		},
+		map[string]string{
+			"process":      "stress",
+			"invokedby":    "stress",
+			"constraint":   "memcg",
+			"cgroup":       "/kubepods/burstable/pod5c0f8a3e-8a1b-4c5d-9e7f-0a1b2c3d4e5f/b9b8c1e1d6a8f0e2d4c6b8a0f1e3d5c7b9a1f0e2d4c6b8a0f1e3d5c7b9a1f0e2",
+			"container_id": "b9b8c1e1d6a8f0e2d4c6b8a0f1e3d5c7b9a1f0e2d4c6b8a0f1e3d5c7b9a1f0e2",
+		})
+}
+
+// Verify that stopping the plugin stops the kmsg reader waiting for a record.
+func TestOomkillerKmsgStop(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, syscall.SetNonblock(int(r.Fd()), true))
+
+	var acc testutil.Accumulator
+	o := &Oomkiller{Kmsg: "pipe", acc: &acc, kmsg: r, done: make(chan struct{})}
+	o.wg.Add(1)
+	go func() {
+		defer o.wg.Done()
+		o.readKmsg(r)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		o.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return")
+	}
+	assert.Empty(t, acc.Errors)
+}
+
+func TestOomkillerLogfile(t *testing.T) {
+	o := &Oomkiller{
+		Logfile:       "testdata/messages.log",
+		FromBeginning: true,
+	}
+
+	var acc testutil.Accumulator
+	require.NoError(t, o.Start(&acc))
+	defer o.Stop()
+
+	acc.Wait(2)
+	acc.AssertContainsTaggedFields(t, "oomkiller",
+		map[string]interface{}{
+			"event":         1,
+			"pid":           int64(4602),
+			"total_vm":      int64(5098100 * 1024),
+			"anon_rss":      int64(1869024 * 1024),
+			"file_rss":      int64(1164 * 1024),
+			"shmem_rss":     int64(0),
+			"oom_score_adj": int64(100),
+			"score":         int64(818),
+		},
+		map[string]string{
+			"process":    "oom-killer-sim",
+			"invokedby":  "oom-killer-sim",
+			"constraint": "none",
+		})
+}
 
+func TestOomkillerUnknownSource(t *testing.T) {
+	o := &Oomkiller{Source: "journald"}
+	var acc testutil.Accumulator
+	assert.Error(t, o.Start(&acc))
+}
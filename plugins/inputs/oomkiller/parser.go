@@ -0,0 +1,185 @@
+package oomkiller
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Report is the OOM report of the kernel for a killed process.
+type Report struct {
+	// InvokedBy is the process that invoked the OOM killer.
+	InvokedBy string
+	// Constraint is the constraint of the OOM: none for the global OOMs,
+	// memcg, cpuset or memory_policy.
+	Constraint string
+	// Cgroup is the memory cgroup of the killed process, if known.
+	Cgroup string
+
+	Pid     int64
+	Process string
+	// Score is the OOM score of the killed process, printed by the older
+	// kernels only.
+	Score int64
+	// The memory of the killed process, in bytes.
+	TotalVM  int64
+	AnonRSS  int64
+	FileRSS  int64
+	ShmemRSS int64
+
+	OomScoreAdj    int64
+	hasOomScoreAdj bool
+
+	// oomScoreAdjs is the oom_score_adj of the dumped tasks, by pid.
+	oomScoreAdjs map[int64]int64
+}
+
+// ContainerID returns the id of the Docker or Mesos container of the cgroup
+// of the killed process, or "" if it is not in a container.
+func (r *Report) ContainerID() string {
+	if match := containerIDRe.FindStringSubmatch(r.Cgroup); match != nil {
+		return match[1] + match[2]
+	}
+	return ""
+}
+
+var (
+	// the first line of the reports: "<comm> invoked oom-killer: gfp_mask=..."
+	invokedRe = regexp.MustCompile(`^(.*) invoked oom-killer:`)
+	// a row of the dump of the tasks, the oom_score_adj is the column before
+	// the name in all the kernel versions:
+	// "[ pid ]   uid  tgid total_vm      rss ... oom_score_adj name"
+	taskRe = regexp.MustCompile(`^\[\s*(\d+)\]\s+.*\s(-?\d+)\s+\S.*$`)
+	// the constraint of the newer kernels, with the cgroup of the task
+	constraintRe = regexp.MustCompile(`^oom-kill:constraint=CONSTRAINT_(\w+)`)
+	taskMemcgRe  = regexp.MustCompile(`[:,]task_memcg=([^,]*)`)
+	// the cgroup of the task in the older kernels
+	taskInRe = regexp.MustCompile(`^Task in (\S+) killed as a result of limit of`)
+	// the choice of the victim in the older kernels
+	killRe = regexp.MustCompile(`Kill process (\d+) \(.*\) score (\d+)`)
+	// the last line of the reports
+	killedRe = regexp.MustCompile(`Killed process (\d+) \((.*)\),? (total-vm:.*)$`)
+	// the memory and oom_score_adj of the killed process
+	killedValueRe = regexp.MustCompile(`([\w-]+):(-?\d+)(kB)?`)
+
+	containerIDRe = regexp.MustCompile(`(?:/mesos/([0-9a-f-]{36})|([0-9a-f]{64}))`)
+)
+
+// Parser assembles the reports of the OOM killer from the messages of the
+// kernel log. The messages are expected without their syslog or kmsg prefix.
+type Parser struct {
+	report *Report
+}
+
+// Parse parses a message of the kernel log, and returns the report of the
+// OOM killer when the message completes one, or nil.
+func (p *Parser) Parse(msg string) *Report {
+	msg = strings.TrimSpace(msg)
+
+	if match := invokedRe.FindStringSubmatch(msg); match != nil {
+		p.report = &Report{
+			InvokedBy:    match[1],
+			oomScoreAdjs: make(map[int64]int64),
+		}
+		return nil
+	}
+
+	match := killedRe.FindStringSubmatch(msg)
+	if p.report == nil && match == nil {
+		return nil
+	}
+	if p.report == nil {
+		// the beginning of the report was missed
+		p.report = &Report{oomScoreAdjs: make(map[int64]int64)}
+	}
+	r := p.report
+
+	switch {
+	case match != nil:
+		p.report = nil
+		r.Pid, _ = strconv.ParseInt(match[1], 10, 64)
+		r.Process = match[2]
+		for _, value := range killedValueRe.FindAllStringSubmatch(match[3], -1) {
+			v, _ := strconv.ParseInt(value[2], 10, 64)
+			if value[3] == "kB" {
+				v *= 1024
+			}
+			switch value[1] {
+			case "total-vm":
+				r.TotalVM = v
+			case "anon-rss":
+				r.AnonRSS = v
+			case "file-rss":
+				r.FileRSS = v
+			case "shmem-rss":
+				r.ShmemRSS = v
+			case "oom_score_adj":
+				r.OomScoreAdj = v
+				r.hasOomScoreAdj = true
+			}
+		}
+		if !r.hasOomScoreAdj {
+			r.OomScoreAdj, r.hasOomScoreAdj = r.oomScoreAdjs[r.Pid]
+		}
+		if r.Constraint == "" {
+			if strings.HasPrefix(msg, "Memory cgroup out of memory") {
+				r.Constraint = "memcg"
+			} else if strings.HasPrefix(msg, "Out of memory") {
+				r.Constraint = "none"
+			}
+		}
+		r.oomScoreAdjs = nil
+		return r
+	case taskRe.MatchString(msg):
+		m := taskRe.FindStringSubmatch(msg)
+		pid, _ := strconv.ParseInt(m[1], 10, 64)
+		adj, _ := strconv.ParseInt(m[2], 10, 64)
+		r.oomScoreAdjs[pid] = adj
+	case constraintRe.MatchString(msg):
+		r.Constraint = strings.ToLower(constraintRe.FindStringSubmatch(msg)[1])
+		if m := taskMemcgRe.FindStringSubmatch(msg); m != nil {
+			r.Cgroup = m[1]
+		}
+	case taskInRe.MatchString(msg):
+		r.Cgroup = taskInRe.FindStringSubmatch(msg)[1]
+	case killRe.MatchString(msg):
+		m := killRe.FindStringSubmatch(msg)
+		r.Score, _ = strconv.ParseInt(m[2], 10, 64)
+		if strings.HasPrefix(msg, "Memory cgroup out of memory") {
+			r.Constraint = "memcg"
+		} else if r.Constraint == "" {
+			r.Constraint = "none"
+		}
+	}
+	return nil
+}
+
+// syslogMessage returns the kernel message of a syslog line like
+// "Sep 14 12:13:49 host kernel: [98991.825192] <message>".
+func syslogMessage(line string) (string, bool) {
+	i := strings.Index(line, "kernel: ")
+	if i == -1 {
+		return "", false
+	}
+	msg := line[i+len("kernel: "):]
+	if strings.HasPrefix(msg, "[") {
+		if j := strings.Index(msg, "] "); j != -1 {
+			msg = msg[j+2:]
+		}
+	}
+	return msg, true
+}
+
+// kmsgMessage returns the message of a /dev/kmsg record like
+// "6,1022,3456789020,-;<message>". The continuation lines of the records,
+// starting with a space, have no message.
+func kmsgMessage(line string) (string, bool) {
+	if strings.HasPrefix(line, " ") {
+		return "", false
+	}
+	i := strings.Index(line, ";")
+	if i == -1 {
+		return "", false
+	}
+	return line[i+1:], true
+}
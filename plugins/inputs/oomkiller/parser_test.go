@@ -0,0 +1,112 @@
+package oomkiller
+
+import (
+	"bufio"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseFixture returns the reports of a fixture of the kernel log.
+func parseFixture(t *testing.T, path string, message func(string) (string, bool)) []*Report {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var parser Parser
+	var reports []*Report
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if msg, ok := message(scanner.Text()); ok {
+			if r := parser.Parse(msg); r != nil {
+				reports = append(reports, r)
+			}
+		}
+	}
+	require.NoError(t, scanner.Err())
+	return reports
+}
+
+func TestParseSyslog(t *testing.T) {
+	reports := parseFixture(t, "testdata/messages.log", syslogMessage)
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, &Report{
+		InvokedBy:      "oom-killer-sim",
+		Constraint:     "none",
+		Pid:            4602,
+		Process:        "oom-killer-sim",
+		Score:          818,
+		TotalVM:        5098100 * 1024,
+		AnonRSS:        1869024 * 1024,
+		FileRSS:        1164 * 1024,
+		OomScoreAdj:    100,
+		hasOomScoreAdj: true,
+	}, reports[0])
+	assert.Equal(t, "", reports[0].ContainerID())
+
+	assert.Equal(t, &Report{
+		InvokedBy:      "java",
+		Constraint:     "memcg",
+		Cgroup:         "/docker/4f2a8c7e6b1d3f5a9c0e2b4d6f8a1c3e5b7d9f0a2c4e6b8d0f1a3c5e7b9d2f4a",
+		Pid:            2245,
+		Process:        "java",
+		Score:          998,
+		TotalVM:        3738044 * 1024,
+		AnonRSS:        521380 * 1024,
+		FileRSS:        1064 * 1024,
+		hasOomScoreAdj: true,
+	}, reports[1])
+	assert.Equal(t, "4f2a8c7e6b1d3f5a9c0e2b4d6f8a1c3e5b7d9f0a2c4e6b8d0f1a3c5e7b9d2f4a",
+		reports[1].ContainerID())
+}
+
+func TestParseKmsg(t *testing.T) {
+	reports := parseFixture(t, "testdata/kmsg.txt", kmsgMessage)
+	require.Len(t, reports, 2)
+
+	assert.Equal(t, &Report{
+		InvokedBy:      "stress",
+		Constraint:     "memcg",
+		Cgroup:         "/kubepods/burstable/pod5c0f8a3e-8a1b-4c5d-9e7f-0a1b2c3d4e5f/b9b8c1e1d6a8f0e2d4c6b8a0f1e3d5c7b9a1f0e2d4c6b8a0f1e3d5c7b9a1f0e2",
+		Pid:            3112,
+		Process:        "stress",
+		TotalVM:        266036 * 1024,
+		AnonRSS:        261200 * 1024,
+		FileRSS:        1560 * 1024,
+		OomScoreAdj:    500,
+		hasOomScoreAdj: true,
+	}, reports[0])
+	assert.Equal(t, "b9b8c1e1d6a8f0e2d4c6b8a0f1e3d5c7b9a1f0e2d4c6b8a0f1e3d5c7b9a1f0e2",
+		reports[0].ContainerID())
+
+	assert.Equal(t, &Report{
+		InvokedBy:      "Web Content",
+		Constraint:     "none",
+		Cgroup:         "/user.slice/user-1000.slice/session-2.scope",
+		Pid:            5123,
+		Process:        "Web Content",
+		TotalVM:        3249380 * 1024,
+		AnonRSS:        1604936 * 1024,
+		OomScoreAdj:    300,
+		hasOomScoreAdj: true,
+	}, reports[1])
+	assert.Equal(t, "", reports[1].ContainerID())
+}
+
+func TestParseWithoutBeginning(t *testing.T) {
+	var parser Parser
+	r := parser.Parse("Killed process 4602 (oom-killer-sim) total-vm:5098100kB, anon-rss:1869024kB, file-rss:1164kB")
+	require.NotNil(t, r)
+	assert.Equal(t, "", r.InvokedBy)
+	assert.Equal(t, int64(4602), r.Pid)
+	assert.Equal(t, "oom-killer-sim", r.Process)
+	assert.False(t, r.hasOomScoreAdj)
+}
+
+func TestMesosContainerID(t *testing.T) {
+	r := &Report{Cgroup: "/mesos/0f3a9b1c-2d4e-4f60-8a1b-2c3d4e5f6a7b"}
+	assert.Equal(t, "0f3a9b1c-2d4e-4f60-8a1b-2c3d4e5f6a7b", r.ContainerID())
+}
@@ -61,35 +61,30 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 		for _, m := range mf.Metric {
 			// reading tags
 			tags := makeLabels(m)
-			// reading fields
-			fields := make(map[string]interface{})
-			if mf.GetType() == dto.MetricType_SUMMARY {
-				// summary metric
-				fields = makeQuantiles(m)
-				fields["count"] = float64(m.GetSummary().GetSampleCount())
-				fields["sum"] = float64(m.GetSummary().GetSampleSum())
-			} else if mf.GetType() == dto.MetricType_HISTOGRAM {
-				// historgram metric
-				fields = makeBuckets(m)
-				fields["count"] = float64(m.GetHistogram().GetSampleCount())
-				fields["sum"] = float64(m.GetHistogram().GetSampleSum())
-
+			var t time.Time
+			if m.TimestampMs != nil && *m.TimestampMs > 0 {
+				t = time.Unix(0, *m.TimestampMs*1000000)
 			} else {
-				// standard metric
-				fields = getNameAndValue(m)
+				t = time.Now()
 			}
-			// converting to telegraf metric
-			if len(fields) > 0 {
-				var t time.Time
-				if m.TimestampMs != nil && *m.TimestampMs > 0 {
-					t = time.Unix(0, *m.TimestampMs*1000000)
-				} else {
-					t = time.Now()
-				}
-				metric, err := metric.New(metricName, tags, fields, t)
-				if err == nil {
-					metrics = append(metrics, metric)
+
+			var point telegraf.Metric
+			var err error
+			switch mf.GetType() {
+			case dto.MetricType_SUMMARY:
+				point, err = makeSummary(metricName, tags, m, t)
+			case dto.MetricType_HISTOGRAM:
+				point, err = makeHistogram(metricName, tags, m, t)
+			default:
+				// standard metric
+				fields, mType := getNameAndValue(m)
+				if len(fields) == 0 {
+					continue
 				}
+				point, err = metric.New(metricName, tags, fields, t, mType)
+			}
+			if err == nil {
+				metrics = append(metrics, point)
 			}
 		}
 	}
@@ -97,24 +92,40 @@ func Parse(buf []byte, header http.Header) ([]telegraf.Metric, error) {
 	return metrics, err
 }
 
-// Get Quantiles from summary metric
-func makeQuantiles(m *dto.Metric) map[string]interface{} {
-	fields := make(map[string]interface{})
+// makeSummary returns the Summary metric of a summary, its NaN quantiles
+// being left out.
+func makeSummary(name string, tags map[string]string, m *dto.Metric, t time.Time) (telegraf.Metric, error) {
+	var quantiles []metric.Quantile
 	for _, q := range m.GetSummary().Quantile {
 		if !math.IsNaN(q.GetValue()) {
-			fields[fmt.Sprint(q.GetQuantile())] = float64(q.GetValue())
+			quantiles = append(quantiles, metric.Quantile{
+				Quantile: q.GetQuantile(),
+				Value:    q.GetValue(),
+			})
 		}
 	}
-	return fields
+	return metric.NewSummary(name, tags, quantiles,
+		m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), t)
 }
 
-// Get Buckets  from histogram metric
-func makeBuckets(m *dto.Metric) map[string]interface{} {
-	fields := make(map[string]interface{})
+// makeHistogram returns the Histogram metric of a histogram. The +Inf
+// bucket, implicit in the protobuf format, is always added.
+func makeHistogram(name string, tags map[string]string, m *dto.Metric, t time.Time) (telegraf.Metric, error) {
+	var buckets []metric.Bucket
+	hasInf := false
 	for _, b := range m.GetHistogram().Bucket {
-		fields[fmt.Sprint(b.GetUpperBound())] = float64(b.GetCumulativeCount())
+		buckets = append(buckets, metric.Bucket{
+			UpperBound: b.GetUpperBound(),
+			Count:      b.GetCumulativeCount(),
+		})
+		hasInf = hasInf || math.IsInf(b.GetUpperBound(), 1)
+	}
+	count := m.GetHistogram().GetSampleCount()
+	if !hasInf {
+		buckets = append(buckets, metric.Bucket{UpperBound: math.Inf(1), Count: count})
 	}
-	return fields
+	return metric.NewHistogram(name, tags, buckets,
+		count, m.GetHistogram().GetSampleSum(), t)
 }
 
 // Get labels from metric
@@ -126,21 +137,23 @@ func makeLabels(m *dto.Metric) map[string]string {
 	return result
 }
 
-// Get name and value from metric
-func getNameAndValue(m *dto.Metric) map[string]interface{} {
+// Get name, value and type from metric
+func getNameAndValue(m *dto.Metric) (map[string]interface{}, telegraf.ValueType) {
 	fields := make(map[string]interface{})
 	if m.Gauge != nil {
 		if !math.IsNaN(m.GetGauge().GetValue()) {
 			fields["gauge"] = float64(m.GetGauge().GetValue())
 		}
+		return fields, telegraf.Gauge
 	} else if m.Counter != nil {
-		if !math.IsNaN(m.GetGauge().GetValue()) {
+		if !math.IsNaN(m.GetCounter().GetValue()) {
 			fields["counter"] = float64(m.GetCounter().GetValue())
 		}
+		return fields, telegraf.Counter
 	} else if m.Untyped != nil {
-		if !math.IsNaN(m.GetGauge().GetValue()) {
+		if !math.IsNaN(m.GetUntyped().GetValue()) {
 			fields["value"] = float64(m.GetUntyped().GetValue())
 		}
 	}
-	return fields
+	return fields, telegraf.Untyped
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -115,6 +117,7 @@ func TestParseValidPrometheus(t *testing.T) {
 		"dockerVersion": "1.8.2",
 		"kernelVersion": "3.10.0-229.20.1.el7.x86_64",
 	}, metrics[0].Tags())
+	assert.Equal(t, telegraf.Gauge, metrics[0].Type())
 
 	// Counter value
 	metrics, err = Parse([]byte(validUniqueCounter), http.Header{})
@@ -125,6 +128,7 @@ func TestParseValidPrometheus(t *testing.T) {
 		"counter": float64(0),
 	}, metrics[0].Fields())
 	assert.Equal(t, map[string]string{}, metrics[0].Tags())
+	assert.Equal(t, telegraf.Counter, metrics[0].Type())
 
 	// Summary data
 	//SetDefaultTags(map[string]string{})
@@ -140,6 +144,7 @@ func TestParseValidPrometheus(t *testing.T) {
 		"sum":   1.8909097205e+07,
 	}, metrics[0].Fields())
 	assert.Equal(t, map[string]string{"handler": "prometheus"}, metrics[0].Tags())
+	assert.Equal(t, telegraf.Summary, metrics[0].Type())
 
 	// histogram data
 	metrics, err = Parse([]byte(validUniqueHistogram), http.Header{})
@@ -161,5 +166,6 @@ func TestParseValidPrometheus(t *testing.T) {
 	assert.Equal(t,
 		map[string]string{"verb": "POST", "resource": "bindings"},
 		metrics[0].Tags())
+	assert.Equal(t, telegraf.Histogram, metrics[0].Type())
 
 }
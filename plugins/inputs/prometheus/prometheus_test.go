@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -49,4 +50,10 @@ func TestPrometheusGeneratesMetrics(t *testing.T) {
 	assert.True(t, acc.HasFloatField("test_metric", "value"))
 	assert.True(t, acc.HasTimestamp("test_metric", time.Unix(1490802350, 0)))
 
+	m, ok := acc.Get("go_gc_duration_seconds")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Summary, m.Type)
+	m, ok = acc.Get("go_goroutines")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Gauge, m.Type)
 }
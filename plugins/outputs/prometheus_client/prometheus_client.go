@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"regexp"
 	"sync"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -133,6 +135,9 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 			mType = prometheus.CounterValue
 		case telegraf.Gauge:
 			mType = prometheus.GaugeValue
+		case telegraf.Summary, telegraf.Histogram:
+			p.addStructured(key, l, point)
+			continue
 		default:
 			mType = prometheus.UntypedValue
 		}
@@ -183,6 +188,62 @@ func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// addStructured adds a Summary or Histogram metric as a single Prometheus
+// summary or histogram named after the measurement.
+func (p *PrometheusClient) addStructured(
+	key string,
+	l prometheus.Labels,
+	point telegraf.Metric,
+) {
+	desc := prometheus.NewDesc(key, "Telegraf collected metric", nil, l)
+	var metric prometheus.Metric
+	var err error
+	if point.Type() == telegraf.Summary {
+		metric, err = summaryMetric(desc, point)
+	} else {
+		metric, err = histogramMetric(desc, point)
+	}
+	if err != nil {
+		log.Printf("E! Error creating prometheus metric, "+
+			"key: %s, labels: %v,\nerr: %s\n",
+			key, l, err.Error())
+		return
+	}
+
+	p.metrics[desc.String()] = &MetricWithExpiration{
+		Metric:     metric,
+		Expiration: time.Now().Add(p.ExpirationInterval.Duration),
+	}
+}
+
+func summaryMetric(desc *prometheus.Desc, point telegraf.Metric) (prometheus.Metric, error) {
+	quantiles, count, sum, err := metric.GetSummary(point)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[float64]float64, len(quantiles))
+	for _, q := range quantiles {
+		values[q.Quantile] = q.Value
+	}
+	return prometheus.NewConstSummary(desc, count, sum, values)
+}
+
+// histogramMetric returns the Prometheus histogram of a Histogram metric,
+// whose +Inf bucket is given by the count of the histogram.
+func histogramMetric(desc *prometheus.Desc, point telegraf.Metric) (prometheus.Metric, error) {
+	buckets, count, sum, err := metric.GetHistogram(point)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[float64]uint64, len(buckets))
+	for _, b := range buckets {
+		if !math.IsInf(b.UpperBound, 1) {
+			values[b.UpperBound] = b.Count
+		}
+	}
+	return prometheus.NewConstHistogram(desc, count, sum, values)
+}
+
 func init() {
 	outputs.Add("prometheus_client", func() telegraf.Output {
 		return &PrometheusClient{
@@ -1,6 +1,7 @@
 package prometheus_client
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/inputs/prometheus"
 	"github.com/influxdata/telegraf/testutil"
+	dto "github.com/prometheus/client_model/go"
 )
 
 var pTesting *PrometheusClient
@@ -147,6 +149,41 @@ func TestPrometheusExpireOldMetrics(t *testing.T) {
 	assert.Equal(t, 1, len(pClient.metrics))
 }
 
+func TestPrometheusRoundTripSummaryHistogram(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	pClient, p, err := setupPrometheus()
+	require.NoError(t, err)
+	defer pClient.Stop()
+
+	now := time.Now()
+	s, _ := metric.NewSummary("rpc_duration_seconds", nil,
+		[]metric.Quantile{{Quantile: 0.5, Value: 0.2}}, 10, 3, now)
+	h, _ := metric.NewHistogram("rpc_size_bytes", nil,
+		[]metric.Bucket{{UpperBound: 100, Count: 6}, {UpperBound: math.Inf(1), Count: 10}},
+		10, 1200, now)
+	require.NoError(t, pClient.Write([]telegraf.Metric{s, h}))
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Gather(&acc))
+
+	m, ok := acc.Get("rpc_duration_seconds")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Summary, m.Type)
+	assert.Equal(t, map[string]interface{}{
+		"0.5": 0.2, "count": float64(10), "sum": float64(3),
+	}, m.Fields)
+
+	m, ok = acc.Get("rpc_size_bytes")
+	require.True(t, ok)
+	assert.Equal(t, telegraf.Histogram, m.Type)
+	assert.Equal(t, map[string]interface{}{
+		"100": float64(6), "+Inf": float64(10), "count": float64(10), "sum": float64(1200),
+	}, m.Fields)
+}
+
 func setupPrometheus() (*PrometheusClient, *prometheus.Prometheus, error) {
 	if pTesting == nil {
 		pTesting = &PrometheusClient{Listen: "localhost:9127"}
@@ -166,3 +203,47 @@ func setupPrometheus() (*PrometheusClient, *prometheus.Prometheus, error) {
 
 	return pTesting, p, nil
 }
+
+func TestPrometheusWriteSummaryHistogram(t *testing.T) {
+	pClient := &PrometheusClient{
+		metrics: make(map[string]*MetricWithExpiration),
+	}
+
+	now := time.Now()
+	s, _ := metric.NewSummary("rpc_duration_seconds",
+		map[string]string{"service": "api"},
+		[]metric.Quantile{{Quantile: 0.5, Value: 0.2}, {Quantile: 0.99, Value: 0.9}},
+		10, 3, now)
+	h, _ := metric.NewHistogram("rpc_size_bytes",
+		map[string]string{"service": "api"},
+		[]metric.Bucket{{UpperBound: 100, Count: 6}, {UpperBound: math.Inf(1), Count: 10}},
+		10, 1200, now)
+	require.NoError(t, pClient.Write([]telegraf.Metric{s, h}))
+
+	require.Len(t, pClient.metrics, 2)
+	var summary *dto.Summary
+	var histogram *dto.Histogram
+	for _, m := range pClient.metrics {
+		var pb dto.Metric
+		require.NoError(t, m.Metric.Write(&pb))
+		if pb.Summary != nil {
+			summary = pb.Summary
+		}
+		if pb.Histogram != nil {
+			histogram = pb.Histogram
+		}
+	}
+
+	require.NotNil(t, summary)
+	assert.Equal(t, uint64(10), summary.GetSampleCount())
+	assert.Equal(t, float64(3), summary.GetSampleSum())
+	require.Len(t, summary.Quantile, 2)
+	assert.Equal(t, 0.99, summary.Quantile[1].GetQuantile())
+	assert.Equal(t, 0.9, summary.Quantile[1].GetValue())
+
+	require.NotNil(t, histogram)
+	assert.Equal(t, uint64(10), histogram.GetSampleCount())
+	require.Len(t, histogram.Bucket, 1)
+	assert.Equal(t, float64(100), histogram.Bucket[0].GetUpperBound())
+	assert.Equal(t, uint64(6), histogram.Bucket[0].GetCumulativeCount())
+}
@@ -1,5 +1,9 @@
 package all
 
 import (
+	_ "github.com/influxdata/telegraf/plugins/processors/converter"
+	_ "github.com/influxdata/telegraf/plugins/processors/dedup"
+	_ "github.com/influxdata/telegraf/plugins/processors/enrich"
 	_ "github.com/influxdata/telegraf/plugins/processors/printer"
+	_ "github.com/influxdata/telegraf/plugins/processors/transform"
 )
@@ -0,0 +1,271 @@
+package converter
+
+import (
+	"log"
+	"math"
+	"strconv"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// Converter converts the types of fields, and moves values between tags and
+// fields.
+type Converter struct {
+	Tags   conversion `toml:"tags"`
+	Fields conversion `toml:"fields"`
+
+	configured bool
+	tags       filters
+	fields     filters
+
+	// ConversionErrors counts the values which could not be converted.
+	ConversionErrors selfstat.Stat
+}
+
+// conversion lists the keys, globs supported, converted to each type.
+type conversion struct {
+	Tag     []string `toml:"tag"`
+	String  []string `toml:"string"`
+	Integer []string `toml:"integer"`
+	Float   []string `toml:"float"`
+	Boolean []string `toml:"boolean"`
+}
+
+type filters struct {
+	tag     filter.Filter
+	string  filter.Filter
+	integer filter.Filter
+	float   filter.Filter
+	boolean filter.Filter
+}
+
+var sampleConfig = `
+  ## Tags to convert to fields of the given type. The tags are removed.
+  ## Globs supported.
+  [processors.converter.tags]
+    # string = []
+    # integer = []
+    # float = []
+    # boolean = []
+
+  ## Fields to convert to the given type, or to tags. Fields which cannot be
+  ## converted are left unchanged and counted in the conversion_errors field
+  ## of the internal_converter metric. Globs supported.
+  [processors.converter.fields]
+    # tag = []
+    # string = []
+    # integer = []
+    # float = []
+    # boolean = []
+`
+
+func (c *Converter) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Converter) Description() string {
+	return "Convert the types of fields, and tags to fields or fields to tags."
+}
+
+// configure compiles the filters of the conversions. Invalid filters are
+// logged and ignored.
+func (c *Converter) configure() {
+	c.configured = true
+	c.ConversionErrors = selfstat.Register("converter", "conversion_errors",
+		map[string]string{})
+
+	if len(c.Tags.Tag) > 0 {
+		log.Printf("E! converter: tags cannot be converted to tags, ignoring %v",
+			c.Tags.Tag)
+		c.Tags.Tag = nil
+	}
+	c.tags = c.Tags.compile("tags")
+	c.fields = c.Fields.compile("fields")
+}
+
+func (cv *conversion) compile(section string) filters {
+	compile := func(typ string, keys []string) filter.Filter {
+		f, err := filter.Compile(keys)
+		if err != nil {
+			log.Printf("E! converter: invalid %s.%s %v: %s", section, typ, keys, err)
+		}
+		return f
+	}
+	return filters{
+		tag:     compile("tag", cv.Tag),
+		string:  compile("string", cv.String),
+		integer: compile("integer", cv.Integer),
+		float:   compile("float", cv.Float),
+		boolean: compile("boolean", cv.Boolean),
+	}
+}
+
+func matches(f filter.Filter, key string) bool {
+	return f != nil && f.Match(key)
+}
+
+func (c *Converter) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !c.configured {
+		c.configure()
+	}
+	for _, m := range in {
+		c.convertTags(m)
+		c.convertFields(m)
+	}
+	return in
+}
+
+func (c *Converter) convertTags(m telegraf.Metric) {
+	for k, v := range m.Tags() {
+		var value interface{}
+		var ok bool
+		switch {
+		case matches(c.tags.string, k):
+			value, ok = v, true
+		case matches(c.tags.integer, k):
+			value, ok = toInteger(v)
+		case matches(c.tags.float, k):
+			value, ok = toFloat(v)
+		case matches(c.tags.boolean, k):
+			value, ok = toBoolean(v)
+		default:
+			continue
+		}
+		if !ok {
+			c.failed("tag", k, v)
+			continue
+		}
+		m.RemoveTag(k)
+		metric.SetField(m, k, value)
+	}
+}
+
+func (c *Converter) convertFields(m telegraf.Metric) {
+	for k, v := range m.Fields() {
+		var value interface{}
+		var ok bool
+		switch {
+		case matches(c.fields.tag, k):
+			s, ok := toString(v)
+			if !ok {
+				c.failed("field", k, v)
+				continue
+			}
+			m.AddTag(k, s)
+			// the last field of the metric is copied rather than moved
+			m.RemoveField(k)
+			continue
+		case matches(c.fields.string, k):
+			value, ok = toString(v)
+		case matches(c.fields.integer, k):
+			value, ok = toInteger(v)
+		case matches(c.fields.float, k):
+			value, ok = toFloat(v)
+		case matches(c.fields.boolean, k):
+			value, ok = toBoolean(v)
+		default:
+			continue
+		}
+		if !ok {
+			c.failed("field", k, v)
+			continue
+		}
+		if value != v {
+			metric.SetField(m, k, value)
+		}
+	}
+}
+
+func (c *Converter) failed(kind, key string, value interface{}) {
+	c.ConversionErrors.Incr(1)
+	log.Printf("D! converter: cannot convert %s %q value %#v", kind, key, value)
+}
+
+func toString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// toInteger converts a value to an integer, truncating floats. Floats out of
+// the range of integers cannot be converted.
+func toInteger(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return v, true
+	case float64:
+		return floatToInteger(v)
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		if i, err := strconv.ParseInt(v, 0, 64); err == nil {
+			return i, true
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return floatToInteger(f)
+		}
+	}
+	return 0, false
+}
+
+func floatToInteger(f float64) (int64, bool) {
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toBoolean converts a value to a boolean, numbers being true when non zero.
+func toBoolean(v interface{}) (bool, bool) {
+	switch v := v.(type) {
+	case bool:
+		return v, true
+	case int64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case string:
+		b, err := strconv.ParseBool(v)
+		return b, err == nil
+	}
+	return false, false
+}
+
+func init() {
+	processors.Add("converter", func() telegraf.Processor {
+		return &Converter{}
+	})
+}
@@ -0,0 +1,97 @@
+package converter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() telegraf.Metric {
+	m, _ := metric.New("aurora",
+		map[string]string{
+			"host":    "scheduler-1",
+			"port":    "8081",
+			"leader":  "true",
+			"weight":  "0.5",
+			"version": "0.17",
+		},
+		map[string]interface{}{
+			"jvm_uptime":     "3600",
+			"tasks_pending":  float64(12.9),
+			"framework_id":   int64(42),
+			"elected":        int64(1),
+			"cpu_usage":      int64(3),
+			"scheduler_mode": "active",
+		},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func apply(t *testing.T, c *Converter) telegraf.Metric {
+	out := c.Apply(newMetric())
+	require.Len(t, out, 1)
+	return out[0]
+}
+
+func TestConvertFields(t *testing.T) {
+	m := apply(t, &Converter{
+		Fields: conversion{
+			Tag:     []string{"framework_id"},
+			String:  []string{"cpu_usage"},
+			Integer: []string{"jvm_*", "tasks_pending"},
+			Boolean: []string{"elected"},
+		},
+	})
+	assert.Equal(t, "42", m.Tags()["framework_id"])
+	assert.Equal(t, map[string]interface{}{
+		"jvm_uptime":     int64(3600),
+		"tasks_pending":  int64(12),
+		"elected":        true,
+		"cpu_usage":      "3",
+		"scheduler_mode": "active",
+	}, m.Fields())
+}
+
+func TestConvertTags(t *testing.T) {
+	m := apply(t, &Converter{
+		Tags: conversion{
+			String:  []string{"version"},
+			Integer: []string{"port"},
+			Float:   []string{"weight"},
+			Boolean: []string{"leader"},
+		},
+	})
+	assert.Equal(t, map[string]string{"host": "scheduler-1"}, m.Tags())
+	fields := m.Fields()
+	assert.Equal(t, "0.17", fields["version"])
+	assert.Equal(t, int64(8081), fields["port"])
+	assert.Equal(t, float64(0.5), fields["weight"])
+	assert.Equal(t, true, fields["leader"])
+}
+
+func TestConversionErrors(t *testing.T) {
+	c := &Converter{
+		Tags: conversion{
+			Integer: []string{"host"},
+		},
+		Fields: conversion{
+			Float: []string{"scheduler_mode", "tasks_pending"},
+		},
+	}
+	m := apply(t, c)
+	errors := c.ConversionErrors.Get()
+	assert.True(t, errors >= 2)
+
+	// values which cannot be converted are left unchanged
+	assert.Equal(t, "scheduler-1", m.Tags()["host"])
+	assert.Equal(t, newMetric().Fields(), m.Fields())
+
+	apply(t, c)
+	assert.Equal(t, errors+2, c.ConversionErrors.Get())
+}
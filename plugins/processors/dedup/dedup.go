@@ -0,0 +1,85 @@
+package dedup
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// Dedup drops the metrics of a series whose fields did not change since the
+// series was last emitted, until the dedup interval has elapsed.
+type Dedup struct {
+	DedupInterval internal.Duration `toml:"dedup_interval"`
+
+	// cache holds the last emitted fields of each series.
+	cache       map[uint64]*emission
+	lastCleanup time.Time
+}
+
+type emission struct {
+	fields map[string]interface{}
+	time   time.Time
+}
+
+func NewDedup() *Dedup {
+	return &Dedup{
+		DedupInterval: internal.Duration{Duration: 10 * time.Minute},
+		cache:         make(map[uint64]*emission),
+		lastCleanup:   time.Now(),
+	}
+}
+
+var sampleConfig = `
+  ## The maximum time a series with unchanged fields is suppressed. Its
+  ## metrics are emitted again once this interval has elapsed.
+  dedup_interval = "10m"
+`
+
+func (d *Dedup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Dedup) Description() string {
+	return "Drop the metrics of series whose fields are unchanged."
+}
+
+func (d *Dedup) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	d.cleanup()
+
+	out := make([]telegraf.Metric, 0, len(in))
+	for _, m := range in {
+		id := m.HashID()
+		fields := m.Fields()
+		last, ok := d.cache[id]
+		if ok && m.Time().Sub(last.time) < d.DedupInterval.Duration &&
+			!m.Time().Before(last.time) && reflect.DeepEqual(fields, last.fields) {
+			continue
+		}
+		d.cache[id] = &emission{fields: fields, time: m.Time()}
+		out = append(out, m)
+	}
+	return out
+}
+
+// cleanup forgets the series which were not emitted within the dedup
+// interval, their next metrics are emitted anyway.
+func (d *Dedup) cleanup() {
+	if time.Since(d.lastCleanup) < d.DedupInterval.Duration {
+		return
+	}
+	d.lastCleanup = time.Now()
+	for id, e := range d.cache {
+		if time.Since(e.time) >= d.DedupInterval.Duration {
+			delete(d.cache, id)
+		}
+	}
+}
+
+func init() {
+	processors.Add("dedup", func() telegraf.Processor {
+		return NewDedup()
+	})
+}
@@ -0,0 +1,63 @@
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Now()
+
+func newMetric(host string, seconds int, value int64) telegraf.Metric {
+	m, _ := metric.New("kernel",
+		map[string]string{"host": host},
+		map[string]interface{}{"boot_time": int64(1500000000), "processes_forked": value},
+		start.Add(time.Duration(seconds)*time.Second),
+	)
+	return m
+}
+
+func TestDedup(t *testing.T) {
+	d := NewDedup()
+	d.DedupInterval.Duration = time.Minute
+
+	tests := []struct {
+		metric  telegraf.Metric
+		emitted bool
+	}{
+		{newMetric("a", 0, 1), true},
+		{newMetric("b", 0, 1), true},
+		// unchanged
+		{newMetric("a", 10, 1), false},
+		// changed
+		{newMetric("a", 20, 2), true},
+		{newMetric("a", 30, 2), false},
+		// the dedup interval elapsed since the last emission
+		{newMetric("a", 80, 2), true},
+		{newMetric("b", 80, 1), true},
+	}
+	for i, tt := range tests {
+		out := d.Apply(tt.metric)
+		if tt.emitted {
+			assert.Len(t, out, 1, "metric %d", i)
+		} else {
+			assert.Empty(t, out, "metric %d", i)
+		}
+	}
+}
+
+func TestDedupCleanup(t *testing.T) {
+	d := NewDedup()
+	d.DedupInterval.Duration = time.Minute
+	d.Apply(newMetric("a", -120, 1))
+	d.Apply(newMetric("b", 0, 1))
+	assert.Len(t, d.cache, 2)
+
+	d.lastCleanup = start.Add(-time.Hour)
+	d.Apply()
+	assert.Len(t, d.cache, 1)
+}
@@ -0,0 +1,250 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/processors"
+
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Enrich adds tags to the metrics by looking up the value of one of their
+// tags in a table loaded from a local file.
+type Enrich struct {
+	File           string
+	Format         string
+	Key            string
+	Tags           []string
+	Overwrite      bool
+	ReloadInterval internal.Duration `toml:"reload_interval"`
+
+	// table maps the values of the key tag to the tags added.
+	table     map[string]map[string]string
+	signature string
+	lastCheck time.Time
+}
+
+func NewEnrich() *Enrich {
+	return &Enrich{
+		ReloadInterval: internal.Duration{Duration: time.Minute},
+	}
+}
+
+var sampleConfig = `
+  ## The file of the lookup table, in csv, json or toml format.
+  file = "/etc/telegraf/owners.csv"
+
+  ## The format of the file, by default from its extension.
+  # format = "csv"
+
+  ## The tag whose value is looked up in the table.
+  key = "host"
+
+  ## The tags added from the table, all of them by default.
+  # tags = ["team", "service"]
+
+  ## If true, the tags of the table replace the existing tags of the metrics.
+  # overwrite = false
+
+  ## How often the file is checked for modifications, and reloaded.
+  # reload_interval = "1m"
+`
+
+func (e *Enrich) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Enrich) Description() string {
+	return "Add tags to metrics from a lookup table in a local file."
+}
+
+func (e *Enrich) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if time.Since(e.lastCheck) >= e.ReloadInterval.Duration {
+		e.lastCheck = time.Now()
+		e.reload()
+	}
+
+	for _, m := range in {
+		value, ok := m.Tags()[e.Key]
+		if !ok {
+			continue
+		}
+		for k, v := range e.table[value] {
+			if e.Overwrite || !m.HasTag(k) {
+				m.AddTag(k, v)
+			}
+		}
+	}
+	return in
+}
+
+// reload loads the table again if the file changed since it was loaded. The
+// previous table is kept if the file cannot be loaded.
+func (e *Enrich) reload() {
+	info, err := os.Stat(e.File)
+	if err != nil {
+		log.Printf("E! enrich: %s", err)
+		return
+	}
+	signature := fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+	if signature == e.signature {
+		return
+	}
+
+	table, err := e.load()
+	if err != nil {
+		log.Printf("E! enrich: cannot load %s: %s", e.File, err)
+		return
+	}
+	if e.table != nil {
+		log.Printf("I! enrich: reloaded %s", e.File)
+	}
+	e.table = e.filter(table)
+	e.signature = signature
+}
+
+func (e *Enrich) load() (map[string]map[string]string, error) {
+	data, err := ioutil.ReadFile(e.File)
+	if err != nil {
+		return nil, err
+	}
+
+	format := e.Format
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(e.File), ".")
+	}
+	switch format {
+	case "csv":
+		return parseCSV(data)
+	case "json":
+		return parseJSON(data)
+	case "toml":
+		return parseTOML(data)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// filter removes the tags of the table which are not configured.
+func (e *Enrich) filter(table map[string]map[string]string) map[string]map[string]string {
+	if len(e.Tags) == 0 {
+		return table
+	}
+	keep := make(map[string]bool, len(e.Tags))
+	for _, k := range e.Tags {
+		keep[k] = true
+	}
+	for _, tags := range table {
+		for k := range tags {
+			if !keep[k] {
+				delete(tags, k)
+			}
+		}
+	}
+	return table
+}
+
+// parseCSV parses a table whose first column holds the values of the key
+// tag, and whose header holds the names of the tags of the other columns.
+// Empty cells are not added.
+func parseCSV(data []byte) (map[string]map[string]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no header")
+	}
+
+	header := records[0]
+	table := make(map[string]map[string]string, len(records)-1)
+	for _, record := range records[1:] {
+		tags := make(map[string]string, len(record)-1)
+		for i := 1; i < len(record); i++ {
+			if record[i] != "" {
+				tags[header[i]] = record[i]
+			}
+		}
+		table[record[0]] = tags
+	}
+	return table, nil
+}
+
+// parseJSON parses an object mapping the values of the key tag to objects
+// of tags. Numbers and booleans are formatted as strings.
+func parseJSON(data []byte) (map[string]map[string]string, error) {
+	var values map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+
+	table := make(map[string]map[string]string, len(values))
+	for key, v := range values {
+		tags := make(map[string]string, len(v))
+		for k, tv := range v {
+			switch tv := tv.(type) {
+			case string:
+				tags[k] = tv
+			case float64, bool:
+				tags[k] = fmt.Sprint(tv)
+			default:
+				return nil, fmt.Errorf("%s: invalid value of tag %s", key, k)
+			}
+		}
+		table[key] = tags
+	}
+	return table, nil
+}
+
+// parseTOML parses a table of tables, named after the values of the key tag.
+func parseTOML(data []byte) (map[string]map[string]string, error) {
+	tbl, err := toml.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	table := make(map[string]map[string]string, len(tbl.Fields))
+	for key, val := range tbl.Fields {
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("%s: not a table", key)
+		}
+		tags := make(map[string]string, len(subTable.Fields))
+		for k, node := range subTable.Fields {
+			kv, ok := node.(*ast.KeyValue)
+			if !ok {
+				return nil, fmt.Errorf("%s: invalid value of tag %s", key, k)
+			}
+			switch v := kv.Value.(type) {
+			case *ast.String:
+				tags[k] = v.Value
+			case *ast.Integer, *ast.Float, *ast.Boolean:
+				tags[k] = v.Source()
+			default:
+				return nil, fmt.Errorf("%s: invalid value of tag %s", key, k)
+			}
+		}
+		table[key] = tags
+	}
+	return table, nil
+}
+
+func init() {
+	processors.Add("enrich", func() telegraf.Processor {
+		return NewEnrich()
+	})
+}
@@ -0,0 +1,125 @@
+package enrich
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var tables = map[string]string{
+	"csv": `# ownership of the hosts
+host,team,service,datacenter
+web-01,frontend,shop,eu-west
+db-01,storage,,eu-west
+`,
+	"json": `{
+  "web-01": {"team": "frontend", "service": "shop", "datacenter": "eu-west"},
+  "db-01": {"team": "storage", "datacenter": "eu-west"}
+}`,
+	"toml": `
+[web-01]
+  team = "frontend"
+  service = "shop"
+  datacenter = "eu-west"
+
+[db-01]
+  team = "storage"
+  datacenter = "eu-west"
+`,
+}
+
+func newMetric(host string) telegraf.Metric {
+	m, _ := metric.New("cpu",
+		map[string]string{"host": host, "team": "unknown"},
+		map[string]interface{}{"usage_idle": float64(99)},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func writeTable(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestEnrich(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enrich")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for format, content := range tables {
+		e := NewEnrich()
+		e.File = writeTable(t, dir, "owners."+format, content)
+		e.Key = "host"
+		e.Overwrite = true
+
+		out := e.Apply(newMetric("web-01"), newMetric("db-01"), newMetric("app-01"))
+		assert.Equal(t, map[string]string{
+			"host":       "web-01",
+			"team":       "frontend",
+			"service":    "shop",
+			"datacenter": "eu-west",
+		}, out[0].Tags(), format)
+		assert.Equal(t, map[string]string{
+			"host":       "db-01",
+			"team":       "storage",
+			"datacenter": "eu-west",
+		}, out[1].Tags(), format)
+		assert.Equal(t, map[string]string{
+			"host": "app-01",
+			"team": "unknown",
+		}, out[2].Tags(), format)
+	}
+}
+
+func TestEnrichTags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enrich")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := NewEnrich()
+	e.File = writeTable(t, dir, "owners.txt", tables["csv"])
+	e.Format = "csv"
+	e.Key = "host"
+	e.Tags = []string{"team", "service"}
+
+	out := e.Apply(newMetric("web-01"))
+	// existing tags are not overwritten
+	assert.Equal(t, map[string]string{
+		"host":    "web-01",
+		"team":    "unknown",
+		"service": "shop",
+	}, out[0].Tags())
+}
+
+func TestEnrichReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "enrich")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	e := NewEnrich()
+	e.File = writeTable(t, dir, "owners.csv", tables["csv"])
+	e.Key = "host"
+	e.ReloadInterval.Duration = 0
+
+	out := e.Apply(newMetric("web-01"))
+	assert.Equal(t, "shop", out[0].Tags()["service"])
+
+	writeTable(t, dir, "owners.csv", "host,service\nweb-01,checkout\n")
+	out = e.Apply(newMetric("web-01"))
+	assert.Equal(t, "checkout", out[0].Tags()["service"])
+
+	// the previous table is kept when the file is invalid
+	writeTable(t, dir, "owners.csv", "host,service\nweb-01\n")
+	out = e.Apply(newMetric("web-01"))
+	assert.Equal(t, "checkout", out[0].Tags()["service"])
+}
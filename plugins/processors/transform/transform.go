@@ -0,0 +1,341 @@
+package transform
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+// Transform applies a list of operations to the names, tags and fields of
+// the metrics, in order.
+type Transform struct {
+	Operations []operation `toml:"operation"`
+
+	configured bool
+}
+
+type operation struct {
+	Op          string `toml:"op"`
+	Target      string `toml:"target"`
+	Key         string `toml:"key"`
+	Dest        string `toml:"dest"`
+	Pattern     string `toml:"pattern"`
+	Replacement string `toml:"replacement"`
+	Cutset      string `toml:"cutset"`
+	Prefix      string `toml:"prefix"`
+	Suffix      string `toml:"suffix"`
+
+	valid bool
+	keys  filter.Filter
+	re    *regexp.Regexp
+}
+
+var sampleConfig = `
+  ## The operations, applied in order to the metrics. An operation applies to
+  ## the target given, one of:
+  ##   measurement: the name of the metrics
+  ##   tag, field:  the values of the tags or of the string fields
+  ##   tag_key, field_key: the names of the tags or of the fields
+  ## "key" selects the measurements, tags or fields the operation applies to,
+  ## globs supported. It selects them all when empty.
+
+  ## Rename the measurement, tags or fields matching key to dest.
+  # [[processors.transform.operation]]
+  #   op = "rename"
+  #   target = "tag"
+  #   key = "hostname"
+  #   dest = "host"
+
+  ## Replace the matches of a regular expression, with $1 or ${name}
+  ## referring to the capture groups. For tag and field values, the result
+  ## can be written to the dest tag or field, keeping the original; it is
+  ## only written when the expression matches.
+  # [[processors.transform.operation]]
+  #   op = "replace"
+  #   target = "tag"
+  #   key = "path"
+  #   pattern = "^/api/(\\w+)/.*$"
+  #   replacement = "${1}"
+  #   dest = "endpoint"
+
+  ## Convert to lowercase or uppercase, writing to dest if set.
+  # [[processors.transform.operation]]
+  #   op = "lowercase"
+  #   target = "field_key"
+
+  ## Trim the characters of cutset, whitespaces by default, from both ends
+  ## with "trim", or from one end with "trim_left" and "trim_right". Remove a
+  ## prefix or a suffix with "trim_prefix" and "trim_suffix".
+  # [[processors.transform.operation]]
+  #   op = "trim_prefix"
+  #   target = "measurement"
+  #   prefix = "win_"
+
+  ## Move the tags matching key to fields with "tag_to_field", or the fields
+  ## matching key to tags with "field_to_tag", renaming them to dest if set.
+  ## These operations have no target.
+  # [[processors.transform.operation]]
+  #   op = "field_to_tag"
+  #   key = "status_code"
+`
+
+func (t *Transform) SampleConfig() string {
+	return sampleConfig
+}
+
+func (t *Transform) Description() string {
+	return "Rename and transform the names, tags and fields of metrics."
+}
+
+var stringOps = map[string]bool{
+	"replace":     true,
+	"lowercase":   true,
+	"uppercase":   true,
+	"trim":        true,
+	"trim_left":   true,
+	"trim_right":  true,
+	"trim_prefix": true,
+	"trim_suffix": true,
+}
+
+var targets = map[string]bool{
+	"measurement": true,
+	"tag":         true,
+	"field":       true,
+	"tag_key":     true,
+	"field_key":   true,
+}
+
+// configure validates the operations and compiles their keys and patterns.
+// Invalid operations are logged and skipped.
+func (t *Transform) configure() {
+	t.configured = true
+	for i := range t.Operations {
+		op := &t.Operations[i]
+		if err := op.compile(); err != nil {
+			log.Printf("E! transform: skipping operation %d (%s): %s",
+				i+1, op.Op, err)
+			continue
+		}
+		op.valid = true
+	}
+}
+
+func (op *operation) compile() error {
+	switch {
+	case op.Op == "rename":
+		if op.Target != "measurement" && op.Target != "tag" && op.Target != "field" {
+			return fmt.Errorf("invalid target %q", op.Target)
+		}
+		if op.Dest == "" {
+			return fmt.Errorf("no dest")
+		}
+	case op.Op == "tag_to_field" || op.Op == "field_to_tag":
+		if op.Target != "" {
+			return fmt.Errorf("no target expected")
+		}
+	case stringOps[op.Op]:
+		if !targets[op.Target] {
+			return fmt.Errorf("invalid target %q", op.Target)
+		}
+		if op.Dest != "" && op.Target != "tag" && op.Target != "field" {
+			return fmt.Errorf("dest is only supported by tag and field targets")
+		}
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+
+	if op.Op == "replace" {
+		var err error
+		if op.re, err = regexp.Compile(op.Pattern); err != nil {
+			return err
+		}
+	}
+
+	if op.Key != "" {
+		var err error
+		if op.keys, err = filter.Compile([]string{op.Key}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Transform) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	if !t.configured {
+		t.configure()
+	}
+	for _, m := range in {
+		for i := range t.Operations {
+			if t.Operations[i].valid {
+				t.Operations[i].apply(m)
+			}
+		}
+	}
+	return in
+}
+
+func (op *operation) matches(key string) bool {
+	return op.keys == nil || op.keys.Match(key)
+}
+
+// dest returns the name the result of the operation on key is written to.
+func (op *operation) dest(key string) string {
+	if op.Dest != "" {
+		return op.Dest
+	}
+	return key
+}
+
+func (op *operation) apply(m telegraf.Metric) {
+	switch op.Op {
+	case "tag_to_field":
+		for k, v := range m.Tags() {
+			if op.matches(k) {
+				m.RemoveTag(k)
+				metric.SetField(m, op.dest(k), v)
+			}
+		}
+		return
+	case "field_to_tag":
+		for k, v := range m.Fields() {
+			if !op.matches(k) {
+				continue
+			}
+			if s, ok := format(v); ok {
+				m.AddTag(op.dest(k), s)
+				// the last field of the metric is copied rather than moved
+				m.RemoveField(k)
+			}
+		}
+		return
+	}
+
+	switch op.Target {
+	case "measurement":
+		if op.matches(m.Name()) {
+			if name, ok := op.transform(m.Name()); ok {
+				m.SetName(name)
+			}
+		}
+	case "tag":
+		for k, v := range m.Tags() {
+			if !op.matches(k) {
+				continue
+			}
+			if op.Op == "rename" {
+				m.RemoveTag(k)
+				m.AddTag(op.Dest, v)
+			} else if s, ok := op.transform(v); ok {
+				m.AddTag(op.dest(k), s)
+			}
+		}
+	case "field":
+		for k, v := range m.Fields() {
+			if !op.matches(k) {
+				continue
+			}
+			if op.Op == "rename" {
+				if k != op.Dest {
+					metric.SetField(m, op.Dest, v)
+					m.RemoveField(k)
+				}
+				continue
+			}
+			if str, ok := v.(string); ok {
+				if s, ok := op.transform(str); ok {
+					metric.SetField(m, op.dest(k), s)
+				}
+			}
+		}
+	case "tag_key":
+		for k, v := range m.Tags() {
+			if !op.matches(k) {
+				continue
+			}
+			if key, ok := op.transform(k); ok && key != k {
+				m.RemoveTag(k)
+				m.AddTag(key, v)
+			}
+		}
+	case "field_key":
+		for k, v := range m.Fields() {
+			if !op.matches(k) {
+				continue
+			}
+			if key, ok := op.transform(k); ok && key != k {
+				metric.SetField(m, key, v)
+				m.RemoveField(k)
+			}
+		}
+	}
+}
+
+// transform returns the result of a string operation, or false when a
+// regular expression does not match.
+func (op *operation) transform(s string) (string, bool) {
+	switch op.Op {
+	case "rename":
+		return op.Dest, true
+	case "replace":
+		if !op.re.MatchString(s) {
+			return "", false
+		}
+		return op.re.ReplaceAllString(s, op.Replacement), true
+	case "lowercase":
+		return strings.ToLower(s), true
+	case "uppercase":
+		return strings.ToUpper(s), true
+	case "trim":
+		if op.Cutset == "" {
+			return strings.TrimSpace(s), true
+		}
+		return strings.Trim(s, op.Cutset), true
+	case "trim_left":
+		if op.Cutset == "" {
+			return strings.TrimLeftFunc(s, unicode.IsSpace), true
+		}
+		return strings.TrimLeft(s, op.Cutset), true
+	case "trim_right":
+		if op.Cutset == "" {
+			return strings.TrimRightFunc(s, unicode.IsSpace), true
+		}
+		return strings.TrimRight(s, op.Cutset), true
+	case "trim_prefix":
+		return strings.TrimPrefix(s, op.Prefix), true
+	case "trim_suffix":
+		return strings.TrimSuffix(s, op.Suffix), true
+	}
+	return s, true
+}
+
+// format returns the value of a field as a tag value.
+func format(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+func init() {
+	processors.Add("transform", func() telegraf.Processor {
+		return &Transform{}
+	})
+}
@@ -0,0 +1,137 @@
+package transform
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric() telegraf.Metric {
+	m, _ := metric.New("win_http",
+		map[string]string{
+			"hostname": "Web-01",
+			"path":     "/api/users/42",
+			"method":   " get ",
+		},
+		map[string]interface{}{
+			"Response_Time": float64(0.25),
+			"status_code":   int64(200),
+			"message":       "OK",
+		},
+		time.Unix(0, 0),
+	)
+	return m
+}
+
+func apply(t *testing.T, ops ...operation) telegraf.Metric {
+	tr := &Transform{Operations: ops}
+	out := tr.Apply(newMetric())
+	require.Len(t, out, 1)
+	return out[0]
+}
+
+func TestRename(t *testing.T) {
+	m := apply(t,
+		operation{Op: "rename", Target: "measurement", Key: "win_*", Dest: "http"},
+		operation{Op: "rename", Target: "tag", Key: "hostname", Dest: "host"},
+		operation{Op: "rename", Target: "field", Key: "message", Dest: "msg"},
+	)
+	assert.Equal(t, "http", m.Name())
+	assert.Equal(t, map[string]string{
+		"host":   "Web-01",
+		"path":   "/api/users/42",
+		"method": " get ",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Response_Time": float64(0.25),
+		"status_code":   int64(200),
+		"msg":           "OK",
+	}, m.Fields())
+}
+
+func TestReplace(t *testing.T) {
+	m := apply(t,
+		operation{Op: "replace", Target: "tag", Key: "path",
+			Pattern: `^/api/(\w+)/.*$`, Replacement: "${1}", Dest: "endpoint"},
+		// no match, nothing is written
+		operation{Op: "replace", Target: "tag", Key: "hostname",
+			Pattern: `^db-(\d+)$`, Replacement: "$1", Dest: "db"},
+		operation{Op: "replace", Target: "measurement",
+			Pattern: `^win_`, Replacement: ""},
+		operation{Op: "replace", Target: "field_key",
+			Pattern: `_code$`, Replacement: ""},
+	)
+	assert.Equal(t, "http", m.Name())
+	assert.Equal(t, map[string]string{
+		"hostname": "Web-01",
+		"path":     "/api/users/42",
+		"endpoint": "users",
+		"method":   " get ",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Response_Time": float64(0.25),
+		"status":        int64(200),
+		"message":       "OK",
+	}, m.Fields())
+}
+
+func TestStrings(t *testing.T) {
+	m := apply(t,
+		operation{Op: "lowercase", Target: "tag", Key: "hostname"},
+		operation{Op: "trim", Target: "tag", Key: "method"},
+		operation{Op: "uppercase", Target: "tag", Key: "method"},
+		operation{Op: "lowercase", Target: "field_key"},
+		operation{Op: "uppercase", Target: "field", Dest: "message_upper"},
+		operation{Op: "trim_prefix", Target: "measurement", Prefix: "win_"},
+	)
+	assert.Equal(t, "http", m.Name())
+	assert.Equal(t, map[string]string{
+		"hostname": "web-01",
+		"path":     "/api/users/42",
+		"method":   "GET",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"response_time": float64(0.25),
+		"status_code":   int64(200),
+		"message":       "OK",
+		"message_upper": "OK",
+	}, m.Fields())
+}
+
+func TestMove(t *testing.T) {
+	m := apply(t,
+		operation{Op: "field_to_tag", Key: "status_code", Dest: "status"},
+		operation{Op: "tag_to_field", Key: "path"},
+	)
+	assert.Equal(t, map[string]string{
+		"hostname": "Web-01",
+		"method":   " get ",
+		"status":   "200",
+	}, m.Tags())
+	assert.Equal(t, map[string]interface{}{
+		"Response_Time": float64(0.25),
+		"message":       "OK",
+		"path":          "/api/users/42",
+	}, m.Fields())
+}
+
+func TestInvalidOperations(t *testing.T) {
+	m := apply(t,
+		operation{Op: "rename", Target: "tag_key", Dest: "x"},
+		operation{Op: "rename", Target: "tag"},
+		operation{Op: "lowercase", Target: "tags"},
+		operation{Op: "lowercase", Target: "measurement", Dest: "x"},
+		operation{Op: "replace", Target: "tag", Pattern: "("},
+		operation{Op: "tag_to_field", Target: "tag"},
+		operation{Op: "reverse", Target: "tag"},
+	)
+	expected := newMetric()
+	assert.Equal(t, expected.Name(), m.Name())
+	assert.Equal(t, expected.Tags(), m.Tags())
+	assert.Equal(t, expected.Fields(), m.Fields())
+}
@@ -2,6 +2,7 @@ package influx
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"testing"
 	"time"
@@ -70,3 +71,20 @@ func TestSerializeMetricString(t *testing.T) {
 	expS := []string{fmt.Sprintf("cpu,cpu=cpu0 usage_idle=\"foobar\" %d", now.UnixNano())}
 	assert.Equal(t, expS, mS)
 }
+
+// The structure of summaries and histograms is kept by their fields, their
+// type is not part of the line protocol.
+func TestSerializeHistogram(t *testing.T) {
+	now := time.Now()
+	m, err := metric.NewHistogram("rpc_size_bytes", nil,
+		[]metric.Bucket{{UpperBound: 100, Count: 6}, {UpperBound: math.Inf(1), Count: 10}},
+		10, 1200, now)
+	assert.NoError(t, err)
+
+	s := InfluxSerializer{}
+	buf, _ := s.Serialize(m)
+	metrics, err := metric.Parse(buf)
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, m.Fields(), metrics[0].Fields())
+}
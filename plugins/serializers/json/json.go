@@ -7,6 +7,14 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
+// valueTypes are the names of the metric types serialized. Only summaries
+// and histograms have a type, which tells their quantile or bucket fields
+// apart; the other metrics keep the output of older versions.
+var valueTypes = map[telegraf.ValueType]string{
+	telegraf.Summary:   "summary",
+	telegraf.Histogram: "histogram",
+}
+
 type JsonSerializer struct {
 	TimestampUnits time.Duration
 }
@@ -23,6 +31,9 @@ func (s *JsonSerializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 	m["fields"] = metric.Fields()
 	m["name"] = metric.Name()
 	m["timestamp"] = metric.UnixNano() / units_nanoseconds
+	if t, ok := valueTypes[metric.Type()]; ok {
+		m["type"] = t
+	}
 	serialized, err := ejson.Marshal(m)
 	if err != nil {
 		return []byte{}, err
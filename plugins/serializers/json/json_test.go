@@ -2,11 +2,13 @@ package json
 
 import (
 	"fmt"
+	"math"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/metric"
 )
 
@@ -108,3 +110,32 @@ func TestSerializeMetricWithEscapes(t *testing.T) {
 	expS := []byte(fmt.Sprintf(`{"fields":{"U,age=Idle":90},"name":"My CPU","tags":{"cpu tag":"cpu0"},"timestamp":%d}`, now.Unix()) + "\n")
 	assert.Equal(t, string(expS), string(buf))
 }
+
+func TestSerializeHistogram(t *testing.T) {
+	now := time.Now()
+	m, err := metric.NewHistogram("rpc_size_bytes", nil,
+		[]metric.Bucket{{UpperBound: 100, Count: 6}, {UpperBound: math.Inf(1), Count: 10}},
+		10, 1200, now)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []byte(fmt.Sprintf(`{"fields":{"+Inf":10,"100":6,"count":10,"sum":1200},"name":"rpc_size_bytes","tags":{},"timestamp":%d,"type":"histogram"}`, now.Unix()) + "\n")
+	assert.Equal(t, string(expS), string(buf))
+}
+
+func TestSerializeCounterHasNoType(t *testing.T) {
+	now := time.Now()
+	m, err := metric.New("cpu", nil,
+		map[string]interface{}{"usage_idle": float64(91.5)}, now, telegraf.Counter)
+	assert.NoError(t, err)
+
+	s := JsonSerializer{}
+	buf, err := s.Serialize(m)
+	assert.NoError(t, err)
+
+	expS := []byte(fmt.Sprintf(`{"fields":{"usage_idle":91.5},"name":"cpu","tags":{},"timestamp":%d}`, now.Unix()) + "\n")
+	assert.Equal(t, string(expS), string(buf))
+}
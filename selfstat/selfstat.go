@@ -89,6 +89,22 @@ func RegisterTiming(measurement, field string, tags map[string]string) Stat {
 
 // Metrics returns all registered stats as telegraf metrics.
 func Metrics() []telegraf.Metric {
+	return metrics(Stat.Get)
+}
+
+// Snapshot returns all registered stats as telegraf metrics, like Metrics,
+// except that it does not clear the averages of timing stats. It lets other
+// consumers than the internal input look at the stats.
+func Snapshot() []telegraf.Metric {
+	return metrics(func(s Stat) int64 {
+		if ts, ok := s.(*timingStat); ok {
+			return ts.peek()
+		}
+		return s.Get()
+	})
+}
+
+func metrics(get func(Stat) int64) []telegraf.Metric {
 	registry.mu.Lock()
 	now := time.Now()
 	metrics := make([]telegraf.Metric, len(registry.stats))
@@ -104,7 +120,7 @@ func Metrics() []telegraf.Metric {
 					tags = stat.Tags()
 					name = stat.Name()
 				}
-				fields[fieldname] = stat.Get()
+				fields[fieldname] = get(stat)
 				j++
 			}
 			metric, err := metric.New(name, tags, fields, now)
@@ -219,3 +219,18 @@ func TestRegisterMetricsAndVerify(t *testing.T) {
 		},
 	)
 }
+
+func TestSnapshotKeepsTimings(t *testing.T) {
+	testLock.Lock()
+	defer testCleanup()
+	s := RegisterTiming("test", "test_field1_ns", map[string]string{"test": "foo"})
+	s.Incr(10)
+	s.Incr(20)
+
+	m := Snapshot()
+	assert.Len(t, m, 1)
+	assert.Equal(t, map[string]interface{}{"test_field1_ns": int64(15)}, m[0].Fields())
+
+	// the average is still there for the internal input
+	assert.Equal(t, int64(15), s.Get())
+}
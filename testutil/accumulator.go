@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,6 +21,7 @@ type Metric struct {
 	Tags        map[string]string
 	Fields      map[string]interface{}
 	Time        time.Time
+	Type        telegraf.ValueType
 }
 
 func (p *Metric) String() string {
@@ -55,6 +57,16 @@ func (a *Accumulator) AddFields(
 	fields map[string]interface{},
 	tags map[string]string,
 	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Untyped, timestamp...)
+}
+
+func (a *Accumulator) addFields(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	tp telegraf.ValueType,
+	timestamp ...time.Time,
 ) {
 	a.Lock()
 	defer a.Unlock()
@@ -93,6 +105,7 @@ func (a *Accumulator) AddFields(
 		Fields:      fields,
 		Tags:        tags,
 		Time:        t,
+		Type:        tp,
 	}
 
 	a.Metrics = append(a.Metrics, p)
@@ -104,7 +117,7 @@ func (a *Accumulator) AddCounter(
 	tags map[string]string,
 	timestamp ...time.Time,
 ) {
-	a.AddFields(measurement, fields, tags, timestamp...)
+	a.addFields(measurement, fields, tags, telegraf.Counter, timestamp...)
 }
 
 func (a *Accumulator) AddGauge(
@@ -113,13 +126,77 @@ func (a *Accumulator) AddGauge(
 	tags map[string]string,
 	timestamp ...time.Time,
 ) {
-	a.AddFields(measurement, fields, tags, timestamp...)
+	a.addFields(measurement, fields, tags, telegraf.Gauge, timestamp...)
+}
+
+func (a *Accumulator) AddSummary(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Summary, timestamp...)
+}
+
+func (a *Accumulator) AddHistogram(
+	measurement string,
+	fields map[string]interface{},
+	tags map[string]string,
+	timestamp ...time.Time,
+) {
+	a.addFields(measurement, fields, tags, telegraf.Histogram, timestamp...)
 }
 
 func (a *Accumulator) AddMetrics(metrics []telegraf.Metric) {
 	for _, m := range metrics {
+		a.addFields(m.Name(), m.Fields(), m.Tags(), m.Type(), m.Time())
+	}
+}
+
+// WithTracking returns a TrackingAccumulator adding its metrics to a,
+// every metric group is reported as delivered as soon as it is added.
+func (a *Accumulator) WithTracking(maxTracked int) telegraf.TrackingAccumulator {
+	return &TrackingAccumulator{
+		Accumulator: a,
+		delivered:   make(chan telegraf.DeliveryInfo, maxTracked),
+	}
+}
+
+// TrackingAccumulator is a mocked out TrackingAccumulator.
+type TrackingAccumulator struct {
+	*Accumulator
+	delivered chan telegraf.DeliveryInfo
+}
+
+func (a *TrackingAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	tracked, id := metric.WithGroupTracking(group, func(info telegraf.DeliveryInfo) {
+		a.delivered <- info
+	})
+	for _, m := range tracked {
 		a.AddFields(m.Name(), m.Fields(), m.Tags(), m.Time())
+		m.Accept()
 	}
+	return id
+}
+
+func (a *TrackingAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return a.delivered
+}
+
+// UndeliveredAccumulator is a mocked out TrackingAccumulator whose metric
+// groups are never delivered.
+type UndeliveredAccumulator struct {
+	*Accumulator
+}
+
+func (a *UndeliveredAccumulator) AddTrackingMetricGroup(group []telegraf.Metric) telegraf.TrackingID {
+	_, id := metric.WithGroupTracking(group, func(telegraf.DeliveryInfo) {})
+	a.AddMetrics(group)
+	return id
+}
+
+func (a *UndeliveredAccumulator) Delivered() <-chan telegraf.DeliveryInfo {
+	return nil
 }
 
 // AddError appends the given error to Accumulator.Errors.